@@ -6,56 +6,164 @@ package main
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"io/fs"
 	"io/ioutil"
-	"net/http"
+	"net/mail"
 	"os"
 	"strings"
 	"time"
+	"unicode"
 
 	_ "embed"
 
 	bopsdk "github.com/bopmatic/sdk/golang"
+	"github.com/go-openapi/runtime"
+	"github.com/go-openapi/strfmt"
+	"golang.org/x/term"
+	"gopkg.in/yaml.v2"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
 )
 
+var errNoApiKey = errors.New("No Bopmatic api key is configured; please run 'bopmatic config' to set one up")
+
 func getApiKey() (string, error) {
 	keyPath, err := getConfigApiKeyPath()
 	if err != nil {
 		return "", err
 	}
 	apiKey, err := ioutil.ReadFile(keyPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		// not yet migrated to the default profile
+		keyPath, _ = getLegacyApiKeyPath()
+		apiKey, err = ioutil.ReadFile(keyPath)
+	}
 	if err != nil {
 		return "", err
 	}
+	if strings.TrimSpace(string(apiKey)) == "" {
+		return "", fmt.Errorf("%v is empty", keyPath)
+	}
+	registerSecret(string(apiKey))
 
 	return string(apiKey), nil
 }
 
+// getRequiredApiKey is getApiKey() for operations which can't proceed
+// without one; a missing key is reported as errNoApiKey while any other
+// failure to read it is returned as is
+func getRequiredApiKey() (string, error) {
+	apiKey, err := getApiKey()
+	if errors.Is(err, fs.ErrNotExist) {
+		logDebug("could not read api key: %v", err)
+		return "", errNoApiKey
+	} else if err != nil {
+		return "", fmt.Errorf("Could not read your Bopmatic api key: %w", err)
+	}
+
+	return apiKey, nil
+}
+
 func getAuthSdkOpts() ([]bopsdk.DeployOption, error) {
 	opts := make([]bopsdk.DeployOption, 0)
 
-	httpClient := &http.Client{
-		Timeout: time.Second * 30,
-	}
+	httpClient := newSrHttpClient()
 	opts = append(opts, bopsdk.DeployOptHttpClient(httpClient))
 
-	apiKey, err := getApiKey()
+	// every ServiceRunner operation requires an api key so fail up front
+	// rather than letting the request fail deep within the sdk
+	apiKey, err := getRequiredApiKey()
 	if err != nil {
-		// treat as warning for now
-		fmt.Fprintf(os.Stderr,
-			"Warning: no api key is set; please run 'bopmatic config'\n")
-	} else {
-		opts = append(opts, bopsdk.DeployOptApiKey(apiKey))
+		return nil, err
 	}
+	opts = append(opts, bopsdk.DeployOptApiKey(apiKey))
 
 	return opts, nil
 }
 
-func login(ctx context.Context) (bopsdk.DeployOption, error) {
+// getSrAuthInfo authenticates requests made directly against ServiceRunner's
+// REST api for the operations the sdk does not yet wrap
+func getSrAuthInfo() (runtime.ClientAuthInfoWriter, error) {
+	apiKey, err := getRequiredApiKey()
+	if err != nil {
+		return nil, err
+	}
+
+	return runtime.ClientAuthInfoWriterFunc(
+		func(req runtime.ClientRequest, _ strfmt.Registry) error {
+			return req.SetHeaderParam("Authorization",
+				fmt.Sprintf("ApiKey %v", apiKey))
+		}), nil
+}
+
+// environment variable consulted by --stdin-password
+const PasswordEnvVar = "BOPMATIC_PASSWORD"
+
+// readStdinLine reads a single line from stdin a byte at a time so that
+// nothing beyond it is consumed from under later fmt.Scanf() prompts
+func readStdinLine() (string, error) {
+	var sb strings.Builder
+	buf := make([]byte, 1)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if n == 1 {
+			if buf[0] == '\n' {
+				break
+			}
+			sb.WriteByte(buf[0])
+		}
+		if errors.Is(err, io.EOF) {
+			if sb.Len() == 0 {
+				return "", io.ErrUnexpectedEOF
+			}
+			break
+		} else if err != nil {
+			return "", err
+		}
+	}
+
+	return strings.TrimRight(sb.String(), "\r"), nil
+}
+
+// readPassword reads the user's Bopmatic password. With stdinPassword it
+// comes from $BOPMATIC_PASSWORD or else the next line of stdin, for
+// automation; otherwise a terminal is read without echoing.
+func readPassword(stdinPassword bool) (string, error) {
+	if stdinPassword {
+		passwd, ok := os.LookupEnv(PasswordEnvVar)
+		if ok {
+			return passwd, nil
+		}
+		passwd, err := readStdinLine()
+		if err != nil {
+			return "", fmt.Errorf("Could not read password from stdin: %w", err)
+		}
+		return passwd, nil
+	}
+
+	fmt.Printf("         password: ")
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("stdin is not a terminal; use --stdin-password to read the password from stdin or $%v",
+			PasswordEnvVar)
+	}
+	passwd, err := term.ReadPassword(fd)
+	fmt.Printf("\n")
+	if err != nil {
+		return "", fmt.Errorf("Could not read password: %w", err)
+	}
+
+	return string(passwd), nil
+}
+
+func login(ctx context.Context, stdinPassword bool) (bopsdk.DeployOption, error) {
 	const clientId = "79qsr4af7jrrsm8f6lfi12aqlv"
 	const region = "us-east-2"
 
@@ -63,9 +171,11 @@ func login(ctx context.Context) (bopsdk.DeployOption, error) {
 	var username string
 	fmt.Scanf("%s", &username)
 	username = strings.TrimSpace(username)
-	fmt.Printf("         password: ")
-	var passwd string
-	fmt.Scanf("%s", &passwd)
+	passwd, err := readPassword(stdinPassword)
+	if err != nil {
+		return nil, err
+	}
+	registerSecret(passwd)
 
 	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
 	if err != nil {
@@ -87,6 +197,8 @@ func login(ctx context.Context) (bopsdk.DeployOption, error) {
 		return nil, err
 	}
 
+	registerSecret(*result.AuthenticationResult.AccessToken)
+
 	return bopsdk.DeployOptBearerToken(*result.AuthenticationResult.AccessToken), nil
 }
 
@@ -99,12 +211,10 @@ func getHostName() string {
 	return hostname
 }
 
-func getNewApiKey() (string, error) {
+func getNewApiKey(stdinPassword bool) (string, error) {
 	sdkOpts := make([]bopsdk.DeployOption, 0)
 
-	httpClient := &http.Client{
-		Timeout: time.Second * 30,
-	}
+	httpClient := newSrHttpClient()
 	sdkOpts = append(sdkOpts, bopsdk.DeployOptHttpClient(httpClient))
 
 	var sb strings.Builder
@@ -125,7 +235,7 @@ func getNewApiKey() (string, error) {
 	case "1":
 		return getKeyDataViaUser()
 	case "2":
-		bearerOpt, err := login(context.Background())
+		bearerOpt, err := login(context.Background(), stdinPassword)
 		if err != nil {
 			return "", err
 		}
@@ -142,7 +252,11 @@ func getNewApiKey() (string, error) {
 
 		return string(apiKeyResp.KeyData), nil
 	case "3":
-		return "", requestAccess()
+		err := requestAccess()
+		if err == nil {
+			err = fmt.Errorf("%v", requestAccessSubmittedMsg)
+		}
+		return "", err
 	default:
 	}
 
@@ -154,13 +268,63 @@ func getKeyDataViaUser() (string, error) {
 	fmt.Printf("Paste the key data you copied to the clipboard and press enter:	")
 	fmt.Scanf("%s", &keyData)
 	keyData = strings.TrimSpace(keyData)
-	if len(keyData) == 0 || keyData[len(keyData)-1] != '=' {
-		return "", fmt.Errorf("invalid key data")
+	err := validateApiKeyFormat(keyData)
+	if err != nil {
+		return "", fmt.Errorf("invalid key data: %w", err)
 	}
 
 	return keyData, nil
 }
 
+// api keys are base64 encoded; anything decoding to fewer bytes than this
+// was certainly truncated
+const apiKeyMinDecodedLen = 16
+
+// validateApiKeyFormat checks, without contacting ServiceRunner, that
+// keyData is structurally an api key and explains what's wrong if not
+func validateApiKeyFormat(keyData string) error {
+	if keyData == "" {
+		return fmt.Errorf("key data is empty")
+	}
+	if strings.HasPrefix(keyData, "ApiKey ") {
+		return fmt.Errorf("key data includes the 'ApiKey ' authorization prefix; paste only the key itself")
+	}
+	if strings.ContainsAny(keyData[:1]+keyData[len(keyData)-1:], "\"'`") {
+		return fmt.Errorf("key data is wrapped in quotes; paste it without them")
+	}
+	for idx, c := range keyData {
+		if unicode.IsSpace(c) {
+			return fmt.Errorf("key data contains whitespace at position %v; it may have been wrapped or split when copied",
+				idx+1)
+		}
+	}
+	if keyData[len(keyData)-1] != '=' {
+		return fmt.Errorf("key data should end with '=' but ends with '%c'; the end of the key may be missing",
+			keyData[len(keyData)-1])
+	}
+	if len(keyData)%4 != 0 {
+		return fmt.Errorf("key data is %v characters long which isn't a multiple of 4; characters may be missing",
+			len(keyData))
+	}
+	decoded, err := base64.StdEncoding.DecodeString(keyData)
+	if err != nil {
+		var corruptErr base64.CorruptInputError
+		if errors.As(err, &corruptErr) && int(corruptErr) < len(keyData) {
+			return fmt.Errorf("key data has an invalid character '%c' at position %v",
+				keyData[int64(corruptErr)], int64(corruptErr)+1)
+		}
+		return fmt.Errorf("key data is not valid base64: %w", err)
+	}
+	if len(decoded) < apiKeyMinDecodedLen {
+		return fmt.Errorf("key data is too short (%v bytes decoded); the key may be truncated",
+			len(decoded))
+	}
+
+	return nil
+}
+
+const requestAccessSubmittedMsg = "A request for an account on bopmatic.com was submitted on your behalf. A representative will respond to you shortly via email."
+
 func requestAccess() error {
 	type promptEntry struct {
 		key   string
@@ -189,14 +353,144 @@ func requestAccess() error {
 		fmt.Fprintf(os.Stderr, "%v: %v\n", p.key, *p.value)
 	}
 
-	httpClient := &http.Client{
-		Timeout: time.Second * 30,
-	}
-	err := bopsdk.RequestAccess(userName, firstName, lastName, email, "", "",
+	httpClient := newSrHttpClient()
+	return bopsdk.RequestAccess(userName, firstName, lastName, email, "", "",
 		bopsdk.DeployOptHttpClient(httpClient))
-	if err == nil {
-		err = fmt.Errorf("A request for an account on bopmatic.com was submitted on your behalf. A representative will respond to you shortly via email.")
+}
+
+// accessRequest is a single user's entry in a 'bopmatic request-access
+// --input-file' file
+type accessRequest struct {
+	FirstName          string `yaml:"first_name"`
+	LastName           string `yaml:"last_name"`
+	Email              string `yaml:"email"`
+	UserName           string `yaml:"username"`
+	ProgrammingLang    string `yaml:"programming_language"`
+	ProjectDescription string `yaml:"project_description"`
+}
+
+// validate reports every problem with req rather than just the first so
+// that an input file can be fixed in one pass
+func (req *accessRequest) validate() []string {
+	problems := make([]string, 0)
+	required := []struct {
+		key   string
+		value string
+	}{
+		{key: "first_name", value: req.FirstName},
+		{key: "last_name", value: req.LastName},
+		{key: "email", value: req.Email},
+		{key: "username", value: req.UserName},
+	}
+	for _, r := range required {
+		if strings.TrimSpace(r.value) == "" {
+			problems = append(problems, fmt.Sprintf("missing %v", r.key))
+		}
+	}
+	if req.Email != "" {
+		addr, err := mail.ParseAddress(req.Email)
+		if err != nil || addr.Address != req.Email {
+			problems = append(problems,
+				fmt.Sprintf("invalid email address %v", req.Email))
+		}
+	}
+	if strings.ContainsAny(req.UserName, " \t") {
+		problems = append(problems,
+			fmt.Sprintf("username %v may not contain whitespace", req.UserName))
+	}
+
+	return problems
+}
+
+// loadAccessRequests reads a yaml or json file containing either a single
+// access request or a list of them
+func loadAccessRequests(inputFile string) ([]accessRequest, error) {
+	inputData, err := ioutil.ReadFile(inputFile)
+	if err != nil {
+		return nil, fmt.Errorf("Could not read %v: %w", inputFile, err)
+	}
+
+	var shape interface{}
+	err = yaml.Unmarshal(inputData, &shape)
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse %v: %w", inputFile, err)
+	}
+	var reqs []accessRequest
+	if _, isList := shape.([]interface{}); isList {
+		err = yaml.UnmarshalStrict(inputData, &reqs)
+	} else {
+		var req accessRequest
+		err = yaml.UnmarshalStrict(inputData, &req)
+		reqs = []accessRequest{req}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse %v: %w", inputFile, err)
+	}
+	if len(reqs) == 0 {
+		return nil, fmt.Errorf("%v contains no access requests", inputFile)
+	}
+
+	return reqs, nil
+}
+
+// requestAccessMain requests Bopmatic accounts either interactively or, with
+// --input-file, for every user listed in a file
+func requestAccessMain(args []string) {
+	var inputFile string
+
+	f := flag.NewFlagSet("bopmatic request-access", flag.ExitOnError)
+	setGlobalFlags(f)
+	f.StringVar(&inputFile, "input-file", "",
+		"yaml or json file listing the users to request access for instead of prompting")
+	err := f.Parse(args)
+	if err != nil {
+		exitWithError(ExitUsage, "%v\n", err)
+	}
+
+	if inputFile == "" {
+		err = requestAccess()
+		if err != nil {
+			exitWithError(ExitServer, "%v\n", err)
+		}
+		fmt.Printf("%v\n", requestAccessSubmittedMsg)
+		return
+	}
+
+	reqs, err := loadAccessRequests(inputFile)
+	if err != nil {
+		exitWithError(ExitUsage, "%v\n", err)
+	}
+	invalid := false
+	for idx, req := range reqs {
+		for _, problem := range req.validate() {
+			logError("%v entry %v: %v", inputFile, idx+1, problem)
+			invalid = true
+		}
+	}
+	if invalid {
+		exitWithError(ExitUsage, "No access requests were submitted; please correct %v and retry\n",
+			inputFile)
 	}
 
-	return err
+	httpClient := newSrHttpClient()
+	failures := 0
+	for _, req := range reqs {
+		fmt.Printf("Requesting access for %v (%v)...", req.UserName, req.Email)
+		err = bopsdk.RequestAccess(req.UserName, req.FirstName, req.LastName,
+			req.Email, req.ProgrammingLang, req.ProjectDescription,
+			bopsdk.DeployOptHttpClient(httpClient))
+		if err != nil {
+			fmt.Printf("failed: %v\n", err)
+			failures++
+			continue
+		}
+		fmt.Printf("ok\n")
+	}
+
+	if failures > 0 {
+		exitWithError(ExitServer, "%v of %v access requests failed\n", failures,
+			len(reqs))
+	}
+	fmt.Printf("Submitted %v access request(s). A representative will respond to each user shortly via email.\n",
+		len(reqs))
 }
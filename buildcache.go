@@ -0,0 +1,228 @@
+/* Copyright © 2024 Bopmatic, LLC. All Rights Reserved.
+ *
+ * See LICENSE file at the root of this package for license terms
+ */
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	bopsdk "github.com/bopmatic/sdk/golang"
+)
+
+// buildCacheEntry records the package produced by the most recent build of
+// a project along with the hash of the project's files at that time
+type buildCacheEntry struct {
+	SourceHash string `json:"source_hash"`
+	PkgId      string `json:"pkg_id"`
+}
+
+// buildCache maps absolute project root directories to their most recent
+// build
+type buildCache map[string]buildCacheEntry
+
+func getBuildCachePath() (string, error) {
+	configPath, err := getConfigPath()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(configPath, "buildcache.json"), nil
+}
+
+func loadBuildCache() (buildCache, error) {
+	cache := make(buildCache)
+
+	cachePath, err := getBuildCachePath()
+	if err != nil {
+		return cache, err
+	}
+	cacheData, err := os.ReadFile(cachePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cache, nil
+	} else if err != nil {
+		return cache, fmt.Errorf("Could not read %v: %w", cachePath, err)
+	}
+
+	err = json.Unmarshal(cacheData, &cache)
+	if err != nil {
+		return make(buildCache), fmt.Errorf("Could not parse %v: %w",
+			cachePath, err)
+	}
+
+	return cache, nil
+}
+
+func saveBuildCache(cache buildCache) error {
+	cachePath, err := getBuildCachePath()
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(cachePath), 0700)
+	if err != nil {
+		return fmt.Errorf("Could not create config directory %v: %w",
+			filepath.Dir(cachePath), err)
+	}
+
+	cacheData, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = writeFileAtomic(cachePath, append(cacheData, '\n'), 0600)
+	if err != nil {
+		return fmt.Errorf("Could not write %v: %w", cachePath, err)
+	}
+
+	return nil
+}
+
+// updateBuildCache loads the build cache, applies update, and saves the
+// result while holding the config lock so that concurrent builds (e.g.
+// 'bopmatic package build --recursive') don't drop each other's entries
+func updateBuildCache(update func(cache buildCache)) error {
+	release, err := lockConfig()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	cache, err := loadBuildCache()
+	if err != nil {
+		logDebug("replacing build cache: %v", err)
+	}
+	update(cache)
+
+	return saveBuildCache(cache)
+}
+
+// hashProjectSources hashes the path and content of every file beneath
+// root, excluding the same directories --watch ignores, along with the build
+// image tag so that changing the pinned image forces a rebuild
+func hashProjectSources(root string) (string, error) {
+	var paths []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry,
+		err error) error {
+
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != root && watchExcludedDirs[d.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() {
+			paths = append(paths, path)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(paths)
+
+	hasher := sha256.New()
+	fmt.Fprintf(hasher, "image:%v\n", getBuildImageTag())
+	for _, path := range paths {
+		relPath, err := filepath.Rel(root, path)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(hasher, "file:%v\n", filepath.ToSlash(relPath))
+
+		file, err := os.Open(path)
+		if err != nil {
+			return "", err
+		}
+		_, err = io.Copy(hasher, file)
+		file.Close()
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+func getProjectRoot(proj *bopsdk.Project) (string, error) {
+	return filepath.Abs(proj.Desc.GetRoot())
+}
+
+// findCachedPackage returns the package built from the project's current
+// sources, or nil if the sources changed since the last build or its
+// package no longer exists
+func findCachedPackage(proj *bopsdk.Project) *bopsdk.Package {
+	root, err := getProjectRoot(proj)
+	if err != nil {
+		return nil
+	}
+	cache, err := loadBuildCache()
+	if err != nil {
+		logDebug("ignoring build cache: %v", err)
+		return nil
+	}
+	entry, ok := cache[root]
+	if !ok {
+		return nil
+	}
+	sourceHash, err := hashProjectSources(root)
+	if err != nil {
+		logDebug("could not hash %v: %v", root, err)
+		return nil
+	}
+	if sourceHash != entry.SourceHash {
+		return nil
+	}
+	pkg, err := proj.NewPackageExisting(entry.PkgId)
+	if err != nil {
+		logDebug("cached pkgId:%v is unusable: %v", entry.PkgId, err)
+		return nil
+	}
+
+	return pkg
+}
+
+// cacheBuiltPackage records pkg as the build of the project's current
+// sources. Hashing happens after the build so that build outputs written
+// into the project directory are part of the recorded state.
+func cacheBuiltPackage(proj *bopsdk.Project, pkg *bopsdk.Package) error {
+	root, err := getProjectRoot(proj)
+	if err != nil {
+		return err
+	}
+	sourceHash, err := hashProjectSources(root)
+	if err != nil {
+		return err
+	}
+
+	return updateBuildCache(func(cache buildCache) {
+		cache[root] = buildCacheEntry{
+			SourceHash: sourceHash,
+			PkgId:      pkg.Id,
+		}
+	})
+}
+
+// forgetCachedPackage removes the project's build cache entry so that the
+// next build isn't skipped
+func forgetCachedPackage(proj *bopsdk.Project) error {
+	root, err := getProjectRoot(proj)
+	if err != nil {
+		return err
+	}
+
+	return updateBuildCache(func(cache buildCache) {
+		delete(cache, root)
+	})
+}
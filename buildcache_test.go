@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestUpdateBuildCacheConcurrentBuilds(t *testing.T) {
+	useTempConfigDir(t)
+
+	const builds = 16
+	var wg sync.WaitGroup
+	errs := make([]error, builds)
+	for idx := 0; idx < builds; idx++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[idx] = updateBuildCache(func(cache buildCache) {
+				cache[fmt.Sprintf("/proj%02d", idx)] = buildCacheEntry{
+					PkgId: fmt.Sprintf("pkg%02d", idx),
+				}
+			})
+		}()
+	}
+	wg.Wait()
+	for idx, err := range errs {
+		if err != nil {
+			t.Fatalf("build %v: %v", idx, err)
+		}
+	}
+
+	// like --recursive's concurrent builds, every entry must survive
+	cache, err := loadBuildCache()
+	if err != nil {
+		t.Fatalf("loadBuildCache: %v", err)
+	}
+	if len(cache) != builds {
+		t.Fatalf("expected %v cache entries, got %v", builds, len(cache))
+	}
+	for idx := 0; idx < builds; idx++ {
+		entry := cache[fmt.Sprintf("/proj%02d", idx)]
+		if entry.PkgId != fmt.Sprintf("pkg%02d", idx) {
+			t.Errorf("expected pkg%02d for /proj%02d, got %+v", idx, idx, entry)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+/* Copyright © 2024 Bopmatic, LLC. All Rights Reserved.
+ *
+ * See LICENSE file at the root of this package for license terms
+ */
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	dockerClient "github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+
+	"github.com/bopmatic/sdk/golang/util"
+)
+
+const buildContainerRetries = 100
+
+// runBuildContainerCommand runs cmdAndArgs within getBuildImageName() the
+// same way util.RunContainerCommand does within util.BopmaticBuildImageName:
+// as the invoking user with the working directory & home directory bind
+// mounted. The sdk always uses the default tag, so build commands are run
+// here so that a pinned tag or --platform applies only to this invocation
+// rather than being retagged over the shared default image.
+// @todo drop once the sdk accepts an image to run
+func runBuildContainerCommand(ctx context.Context, cmdAndArgs []string,
+	stdOut io.Writer, stdErr io.Writer) error {
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		homeDir = ""
+	}
+
+	cli, err := dockerClient.NewClientWithOpts(dockerClient.FromEnv,
+		dockerClient.WithAPIVersionNegotiation())
+	if err != nil {
+		return fmt.Errorf(util.DockerInstallErrMsg, err)
+	}
+	defer cli.Close()
+
+	pwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("Could not get current working dir: %w", err)
+	}
+
+	hostConfig := &container.HostConfig{
+		AutoRemove: true,
+		Mounts: []mount.Mount{
+			{
+				Type:   mount.TypeBind,
+				Source: pwd,
+				Target: pwd,
+			},
+		},
+		Binds: []string{
+			"/etc/passwd:/etc/passwd",
+		},
+	}
+	if homeDir != "" && homeDir != pwd {
+		hostConfig.Mounts = append(hostConfig.Mounts, mount.Mount{
+			Type:   mount.TypeBind,
+			Source: homeDir,
+			Target: homeDir,
+		})
+	}
+
+	imageName := getBuildImageName()
+	logDebug("running %v in %v", cmdAndArgs, imageName)
+	containerConfig := &container.Config{
+		User:       fmt.Sprintf("%v:%v", os.Geteuid(), os.Getegid()),
+		Cmd:        cmdAndArgs,
+		Image:      imageName,
+		WorkingDir: pwd,
+	}
+
+	// like the sdk, retry due to occasional spurious 'container not found'
+	var retErr error
+	for retries := buildContainerRetries; retries > 0; retries-- {
+		retErr = nil
+
+		resp, err := cli.ContainerCreate(ctx, containerConfig, hostConfig,
+			nil, nil, "")
+		if err != nil {
+			return fmt.Errorf("Failed to create container: %w", err)
+		}
+		err = cli.ContainerStart(ctx, resp.ID, container.StartOptions{})
+		if err != nil {
+			return fmt.Errorf("Failed to start container: %w", err)
+		}
+
+		logOutput, err := cli.ContainerLogs(ctx, resp.ID,
+			container.LogsOptions{
+				ShowStdout: true,
+				ShowStderr: true,
+				Follow:     true,
+			})
+		if err != nil {
+			retErr = fmt.Errorf("Failed to get container output: %w", err)
+			time.Sleep(10 * time.Millisecond)
+			continue
+		}
+		_, _ = stdcopy.StdCopy(stdOut, stdErr, logOutput)
+		logOutput.Close()
+
+		statusCh, errCh := cli.ContainerWait(ctx, resp.ID,
+			container.WaitConditionRemoved)
+		select {
+		case err := <-errCh:
+			if err != nil {
+				if dockerClient.IsErrNotFound(err) {
+					retErr = fmt.Errorf("Container run failed/ not found: %w",
+						err)
+					time.Sleep(10 * time.Millisecond)
+					continue
+				}
+
+				return fmt.Errorf("Container run failed: %w", err)
+			}
+		case status := <-statusCh:
+			if status.StatusCode != 0 {
+				return fmt.Errorf("%v failed with status:%v", cmdAndArgs[0],
+					status.StatusCode)
+			}
+		}
+
+		break
+	}
+
+	return retErr
+}
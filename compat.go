@@ -0,0 +1,141 @@
+/* Copyright © 2024 Bopmatic, LLC. All Rights Reserved.
+ *
+ * See LICENSE file at the root of this package for license terms
+ */
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/bopmatic/sdk/golang/util"
+)
+
+// ServerApiVersionUnknown is reported in place of ServiceRunner's api
+// version, which it doesn't currently expose
+// @todo query the server's api version once the sdk provides an endpoint
+const ServerApiVersionUnknown = "unknown"
+
+// compatOutput is the result of 'bopmatic version --check-compat'
+type compatOutput struct {
+	CliVersion        string   `json:"cliVersion"`
+	BuildImageTag     string   `json:"buildImageTag"`
+	ExpectedImageTag  string   `json:"expectedImageTag"`
+	BuildImagePresent bool     `json:"buildImagePresent"`
+	ApiEndpoint       string   `json:"apiEndpoint"`
+	ServerApiVersion  string   `json:"serverApiVersion"`
+	Compatible        bool     `json:"compatible"`
+	Problems          []string `json:"problems,omitempty"`
+	Warnings          []string `json:"warnings,omitempty"`
+}
+
+func versionMain(args []string) {
+	var checkCompat bool
+
+	f := flag.NewFlagSet("bopmatic version", flag.ExitOnError)
+	setGlobalFlags(f)
+	f.BoolVar(&checkCompat, "check-compat", false,
+		"Check that the CLI, build image, and Bopmatic ServiceRunner versions are compatible")
+	err := f.Parse(args)
+	if err != nil {
+		exitWithError(ExitUsage, "%v\n", err)
+	}
+
+	if !checkCompat {
+		fmt.Printf("bopmatic-cli-%v\n", versionText)
+		return
+	}
+
+	compat := checkCompatibility()
+	if outputFormat != OutputText {
+		err = printStructured(&compat)
+		if err != nil {
+			exitWithError(ExitFailure, "%v\n", err)
+		}
+	} else {
+		printCompatibility(&compat)
+	}
+	if !compat.Compatible {
+		exit(ExitFailure)
+	}
+}
+
+// checkCompatibility compares the CLI version, the build image it runs, and
+// ServiceRunner. Problems are combinations known to fail; warnings are skew
+// which may cause failures.
+func checkCompatibility() compatOutput {
+	compat := compatOutput{
+		CliVersion:       versionText,
+		BuildImageTag:    getBuildImageTag(),
+		ExpectedImageTag: util.BopmaticImageTag,
+		ApiEndpoint:      getApiEndpointString(),
+		ServerApiVersion: ServerApiVersionUnknown,
+	}
+
+	if versionText != DevVersionText {
+		latestVer, err := getLatestVersion()
+		if err != nil {
+			compat.Warnings = append(compat.Warnings,
+				fmt.Sprintf("could not determine the latest CLI version: %v", err))
+		} else if latestVer != versionText {
+			compat.Warnings = append(compat.Warnings,
+				fmt.Sprintf("CLI %v is older than the latest release %v; run 'bopmatic upgrade'",
+					versionText, latestVer))
+		}
+	}
+
+	haveBuildImg, err := hasBuildImage()
+	if err != nil {
+		compat.Problems = append(compat.Problems,
+			fmt.Sprintf("could not inspect local docker images: %v", err))
+	} else if !haveBuildImg {
+		compat.Problems = append(compat.Problems,
+			fmt.Sprintf("build image %v:%v is not installed; run 'bopmatic upgrade container'",
+				util.BopmaticImageRepo, compat.BuildImageTag))
+	}
+	compat.BuildImagePresent = haveBuildImg
+
+	if compat.BuildImageTag != util.BopmaticImageTag {
+		if isPinnedBuildImageStale(compat.BuildImageTag) {
+			compat.Problems = append(compat.Problems,
+				fmt.Sprintf("build image is pinned to %v but this CLI requires %v; unpin via 'bopmatic upgrade container --tag %v'",
+					compat.BuildImageTag, util.BopmaticImageTag,
+					util.BopmaticImageTag))
+		}
+	} else if haveBuildImg {
+		needUpgrade, err := util.DoesLocalImageNeedUpdate(util.BopmaticImageRepo,
+			compat.BuildImageTag)
+		if err == nil && needUpgrade {
+			compat.Warnings = append(compat.Warnings,
+				"a newer build image is available; run 'bopmatic upgrade container'")
+		}
+	}
+
+	compat.Compatible = len(compat.Problems) == 0
+
+	return compat
+}
+
+func printCompatibility(compat *compatOutput) {
+	present := "not installed"
+	if compat.BuildImagePresent {
+		present = "installed"
+	}
+
+	fmt.Printf("CLI version:        bopmatic-cli-%v\n", compat.CliVersion)
+	fmt.Printf("Build image:        %v:%v (%v; this CLI expects %v)\n",
+		util.BopmaticImageRepo, compat.BuildImageTag, present,
+		compat.ExpectedImageTag)
+	fmt.Printf("ServiceRunner:      %v (api version %v)\n", compat.ApiEndpoint,
+		compat.ServerApiVersion)
+
+	for _, warning := range compat.Warnings {
+		fmt.Printf("WARNING: %v\n", warning)
+	}
+	for _, problem := range compat.Problems {
+		fmt.Printf("INCOMPATIBLE: %v\n", problem)
+	}
+	if compat.Compatible {
+		fmt.Printf("Compatible\n")
+	}
+}
@@ -5,13 +5,25 @@
 package main
 
 import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"io/fs"
 	"io/ioutil"
+	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
 
 	_ "embed"
+
+	"github.com/bopmatic/sdk/golang/goswag"
+	"github.com/bopmatic/sdk/golang/goswag/service_runner"
+	"github.com/bopmatic/sdk/golang/models"
 )
 
 func getConfigPath() (string, error) {
@@ -24,36 +36,185 @@ func getConfigPath() (string, error) {
 }
 
 func getConfigApiKeyPath() (string, error) {
+	profilePath, err := getConfigProfilePath()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(profilePath, "apikey"), nil
+}
+
+// cliConfig holds persistent CLI settings stored in config.json under
+// getConfigPath(); the api key is intentionally kept separately
+type cliConfig struct {
+	// BuildImageTag pins the Bopmatic Build Image to a specific tag; empty
+	// means track util.BopmaticImageTag
+	BuildImageTag string `json:"build_image_tag,omitempty"`
+	// ApiEndpoint overrides the default ServiceRunner endpoint, e.g. to
+	// target staging
+	ApiEndpoint string `json:"api_endpoint,omitempty"`
+	// UpgradeChannel selects which CLI releases upgrades track; empty means
+	// UpgradeChannelStable
+	UpgradeChannel string `json:"upgrade_channel,omitempty"`
+	// DefaultProject is the project id used outside of a Bopmatic project
+	// directory when --projid isn't specified; ProjectIdEnvVar overrides it
+	DefaultProject string        `json:"default_project,omitempty"`
+	Logs           *logsConfig   `json:"logs,omitempty"`
+	Deploy         *deployConfig `json:"deploy,omitempty"`
+}
+
+type deployConfig struct {
+	// WaitDefault makes 'bopmatic package deploy' wait for the deployment to
+	// complete unless --detach is specified
+	WaitDefault bool `json:"wait_default,omitempty"`
+	// ProtectedEnvs lists environment ids which require --confirm-prod (or
+	// a typed confirmation) to deploy to; DefaultEnvName refers to the
+	// project's default (prod) environment
+	ProtectedEnvs []string `json:"protected_envs,omitempty"`
+}
+
+func (d *deployConfig) isEmpty() bool {
+	return !d.WaitDefault && len(d.ProtectedEnvs) == 0
+}
+
+type logsConfig struct {
+	// DefaultWindow is how far back 'bopmatic logs' looks when neither
+	// --starttime nor --window is specified (e.g. 12h or 7d); empty means
+	// DefaultTimeWindow
+	DefaultWindow string `json:"default_window,omitempty"`
+}
+
+func getConfigFilePath() (string, error) {
 	configPath, err := getConfigPath()
 	if err != nil {
 		return "", err
 	}
 
-	return filepath.Join(configPath, "apikey"), nil
+	return filepath.Join(configPath, "config.json"), nil
+}
+
+// loadConfig reads the persisted CLI settings; a missing config file yields
+// default settings
+func loadConfig() (*cliConfig, error) {
+	cfg := &cliConfig{}
+
+	configFilePath, err := getConfigFilePath()
+	if err != nil {
+		return cfg, err
+	}
+	configData, err := ioutil.ReadFile(configFilePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cfg, nil
+	} else if err != nil {
+		return cfg, fmt.Errorf("Could not read %v: %w", configFilePath, err)
+	}
+
+	err = json.Unmarshal(configData, cfg)
+	if err != nil {
+		return cfg, fmt.Errorf("Could not parse %v: %w", configFilePath, err)
+	}
+
+	return cfg, nil
+}
+
+// saveConfig replaces the persisted CLI settings with cfg; use
+// updateConfig() to change individual settings
+func saveConfig(cfg *cliConfig) error {
+	release, err := lockConfig()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return writeConfig(cfg)
+}
+
+// writeConfig atomically writes cfg to the config file; the caller must
+// hold the config lock
+func writeConfig(cfg *cliConfig) error {
+	configPath, err := getConfigPath()
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(configPath, 0700)
+	if err != nil {
+		return fmt.Errorf("Could not create config directory %v: %w",
+			configPath, err)
+	}
+	configFilePath, _ := getConfigFilePath()
+
+	configData, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = writeFileAtomic(configFilePath, append(configData, '\n'), 0600)
+	if err != nil {
+		return fmt.Errorf("Could not write %v: %w", configFilePath, err)
+	}
+
+	return nil
+}
+
+var configSubCommandTab = map[string]func(args []string){
+	"test":            configTestMain,
+	"endpoint":        configEndpointMain,
+	"log-window":      configLogWindowMain,
+	"deploy-wait":     configDeployWaitMain,
+	"protected-envs":  configProtectedEnvsMain,
+	"default-project": configDefaultProjectMain,
+	"export":          configExportMain,
+	"import":          configImportMain,
+	"migrate":         configMigrateMain,
+	"unset-key":       configUnsetKeyMain,
 }
 
 func configMain(args []string) {
+	if len(args) > 0 {
+		configSubCommand, ok := configSubCommandTab[args[0]]
+		if ok {
+			configSubCommand(args[1:])
+			return
+		}
+		if args[0] == "--validate-key" {
+			configValidateKeyMain(args[1:])
+			return
+		}
+	}
+
+	var stdinPassword bool
+	f := flag.NewFlagSet("bopmatic config", flag.ExitOnError)
+	setGlobalFlags(f)
+	f.BoolVar(&stdinPassword, "stdin-password", false,
+		fmt.Sprintf("When logging in, read your password from $%v or else stdin rather than prompting for it",
+			PasswordEnvVar))
+	err := f.Parse(args)
+	if err != nil {
+		exitWithError(ExitUsage, "%v\n", err)
+	}
+
 	configPath, err := getConfigPath()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
-		os.Exit(1)
+		exitWithError(ExitFailure, "%v\n", err)
 	}
 	err = os.MkdirAll(configPath, 0700)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Could not create config directory %v: %v\n",
+		exitWithError(ExitFailure, "Could not create config directory %v: %v\n",
 			configPath, err)
-		os.Exit(1)
 	}
 
 	haveExisting := true
 	apiKeyPath, _ := getConfigApiKeyPath()
+	err = os.MkdirAll(filepath.Dir(apiKeyPath), 0700)
+	if err != nil {
+		exitWithError(ExitFailure, "Could not create config directory %v: %v\n",
+			filepath.Dir(apiKeyPath), err)
+	}
 
 	_, err = os.Stat(apiKeyPath)
 	if os.IsNotExist(err) {
 		haveExisting = false
 	} else if err != nil {
-		fmt.Fprintf(os.Stderr, "Could not read %v: %v", apiKeyPath, err)
-		os.Exit(1)
+		exitWithError(ExitFailure, "Could not read %v: %v", apiKeyPath, err)
 	}
 
 	shouldReplace := "N"
@@ -68,19 +229,403 @@ func configMain(args []string) {
 	}
 	if len(shouldReplace) > 0 && shouldReplace[0] == 'Y' {
 		apiKeyVal := ""
-		apiKeyVal, err = getNewApiKey()
+		apiKeyVal, err = getNewApiKey(stdinPassword)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to create new api key: %v\n", err)
-			os.Exit(1)
+			exitWithError(ExitFailure, "Failed to create new api key: %v\n", err)
 		}
+		registerSecret(apiKeyVal)
 		_ = os.Remove(apiKeyPath)
 		err = ioutil.WriteFile(apiKeyPath, []byte(apiKeyVal), 0400)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Could not install %v: %v\n", apiKeyPath,
+			exitWithError(ExitFailure, "Could not install %v: %v\n", apiKeyPath,
 				err)
-			os.Exit(1)
 		}
 	}
 
 	upgradeBuildContainer([]string{})
 }
+
+// configTestOutput is the structured rendering of 'bopmatic config test'
+type configTestOutput struct {
+	Ok           bool   `json:"ok"`
+	Endpoint     string `json:"endpoint"`
+	ProjectCount int    `json:"projectCount"`
+	Error        string `json:"error,omitempty"`
+}
+
+// configTestMain verifies the configured api key by making a lightweight
+// authenticated request to ServiceRunner
+func configTestMain(args []string) {
+	f := flag.NewFlagSet("bopmatic config test", flag.ExitOnError)
+	setGlobalFlags(f)
+	err := f.Parse(args)
+	if err != nil {
+		exitWithError(ExitUsage, "%v\n", err)
+	}
+
+	testOut := configTestOutput{
+		Endpoint: getApiEndpointString(),
+	}
+	fail := func(code int, format string, a ...any) {
+		if outputFormat == OutputText {
+			fmt.Printf("failed\n")
+		} else {
+			testOut.Error = strings.TrimRight(fmt.Sprintf(format, a...), "\n")
+			_ = printStructured(&testOut)
+		}
+		exitWithError(code, format, a...)
+	}
+
+	authInfo, err := getSrAuthInfo()
+	if err != nil {
+		if outputFormat == OutputText {
+			exitWithError(ExitAuth, "%v\n", err)
+		}
+		fail(ExitAuth, "%v\n", err)
+	}
+
+	if outputFormat == OutputText {
+		fmt.Printf("Verifying api key with Bopmatic ServiceRunner...")
+	}
+	// call ServiceRunner directly rather than via bopsdk.ListProjects() so
+	// that failures aren't retried and the http status is preserved
+	httpClient := newSrHttpClient()
+	listProjectsParams := service_runner.NewListProjectsParams().
+		WithBody(struct{}{}).WithHTTPClient(httpClient)
+	client := goswag.NewHTTPClientWithConfig(nil,
+		goswag.DefaultTransportConfig())
+	resp, err := client.ServiceRunner.ListProjects(listProjectsParams,
+		authInfo)
+	if err != nil {
+		var statusErr *service_runner.ListProjectsDefault
+		var urlErr *url.Error
+		if errors.As(err, &statusErr) {
+			switch statusErr.Code() {
+			case http.StatusUnauthorized:
+				fail(ExitAuth, "Your api key was rejected; it may have been mistyped or revoked. Run 'bopmatic config' to install a new one.\n")
+			case http.StatusForbidden:
+				fail(ExitAuth, "Your api key is valid but lacks permission to list projects; please contact Bopmatic support.\n")
+			}
+			fail(ExitServer, "Bopmatic ServiceRunner returned an error (http %v): %v\n",
+				statusErr.Code(), err)
+		} else if errors.As(err, &urlErr) {
+			fail(ExitFailure, "Could not reach Bopmatic ServiceRunner; please check your network connection: %v\n",
+				err)
+		}
+		fail(ExitServer, "%v\n", err)
+	}
+	listReply := resp.GetPayload()
+	if listReply.Result != nil && listReply.Result.Status != nil &&
+		*listReply.Result.Status != models.ServiceRunnerStatusSTATUSOK {
+		fail(ExitServer, "ListProjects failure(%v): %v\n",
+			*listReply.Result.Status, listReply.Result.StatusDetail)
+	}
+
+	testOut.Ok = true
+	testOut.ProjectCount = len(listReply.Ids)
+	if outputFormat != OutputText {
+		err = printStructured(&testOut)
+		if err != nil {
+			exitWithError(ExitFailure, "%v\n", err)
+		}
+		return
+	}
+
+	fmt.Printf("ok\nYour api key is valid; %v project(s) are visible to it.\n",
+		testOut.ProjectCount)
+}
+
+// configExportVersion is bumped whenever configExport changes incompatibly
+const configExportVersion = 1
+
+// configExport is the portable file written by 'bopmatic config export'
+// and read by 'bopmatic config import'
+type configExport struct {
+	Version int        `json:"version"`
+	Config  *cliConfig `json:"config"`
+	// ApiKey is only present when exported with --include-secrets
+	ApiKey string `json:"api_key,omitempty"`
+}
+
+// configExportMain writes the CLI settings, and optionally the api key, to
+// a single file for restoring on another machine via 'bopmatic config
+// import'
+func configExportMain(args []string) {
+	var includeSecrets bool
+
+	f := flag.NewFlagSet("bopmatic config export", flag.ExitOnError)
+	setGlobalFlags(f)
+	f.BoolVar(&includeSecrets, "include-secrets", false,
+		"Also export your api key; anyone with the exported file can act as you")
+	err := f.Parse(args)
+	if err != nil {
+		exitWithError(ExitUsage, "%v\n", err)
+	}
+	if f.NArg() != 1 {
+		exitWithError(ExitUsage, "Usage: bopmatic config export [--include-secrets] <file>\n")
+	}
+	exportPath := f.Arg(0)
+
+	cfg, err := loadConfig()
+	if err != nil {
+		exitWithError(ExitFailure, "%v\n", err)
+	}
+	export := configExport{
+		Version: configExportVersion,
+		Config:  cfg,
+	}
+	if includeSecrets {
+		export.ApiKey, err = getApiKey()
+		if err != nil {
+			exitWithError(ExitAuth, "%v\n", err)
+		}
+	}
+
+	exportData, err := json.MarshalIndent(&export, "", "  ")
+	if err != nil {
+		exitWithError(ExitFailure, "%v\n", err)
+	}
+	err = ioutil.WriteFile(exportPath, append(exportData, '\n'), 0600)
+	if err != nil {
+		exitWithError(ExitFailure, "Could not write %v: %v\n", exportPath, err)
+	}
+
+	fmt.Printf("Exported Bopmatic configuration to %v\n", exportPath)
+	if includeSecrets {
+		logWarn("**********************************************************************")
+		logWarn("%v CONTAINS YOUR BOPMATIC API KEY. Anyone who obtains it can manage", exportPath)
+		logWarn("your projects as you. Transfer it securely, delete it once imported, and")
+		logWarn("never commit it to source control.")
+		logWarn("**********************************************************************")
+	}
+}
+
+// configImportMain restores settings written by 'bopmatic config export'
+// into getConfigPath()
+func configImportMain(args []string) {
+	f := flag.NewFlagSet("bopmatic config import", flag.ExitOnError)
+	setGlobalFlags(f)
+	err := f.Parse(args)
+	if err != nil {
+		exitWithError(ExitUsage, "%v\n", err)
+	}
+	if f.NArg() != 1 {
+		exitWithError(ExitUsage, "Usage: bopmatic config import <file>\n")
+	}
+	importPath := f.Arg(0)
+
+	importData, err := ioutil.ReadFile(importPath)
+	if err != nil {
+		exitWithError(ExitFailure, "Could not read %v: %v\n", importPath, err)
+	}
+	var export configExport
+	err = json.Unmarshal(importData, &export)
+	if err != nil {
+		exitWithError(ExitUsage, "Could not parse %v: %v\n", importPath, err)
+	}
+	if export.Version != configExportVersion {
+		exitWithError(ExitUsage, "%v has unsupported export version %v; expected %v. Please upgrade the Bopmatic CLI on one of the machines so that both match.\n",
+			importPath, export.Version, configExportVersion)
+	}
+	if export.Config == nil {
+		export.Config = &cliConfig{}
+	}
+	if export.Config.ApiEndpoint != "" {
+		_, err = parseApiEndpoint(export.Config.ApiEndpoint)
+		if err != nil {
+			exitWithError(ExitUsage, "%v\n", err)
+		}
+	}
+
+	err = saveConfig(export.Config)
+	if err != nil {
+		exitWithError(ExitFailure, "%v\n", err)
+	}
+	fmt.Printf("Imported Bopmatic configuration from %v\n", importPath)
+
+	if export.ApiKey == "" {
+		return
+	}
+	apiKeyPath, err := getConfigApiKeyPath()
+	if err != nil {
+		exitWithError(ExitFailure, "%v\n", err)
+	}
+	existingKey, err := getApiKey()
+	if err == nil && existingKey != export.ApiKey {
+		shouldReplace := "N"
+		fmt.Printf("Your %v is already installed; replace with the imported api key? (Y/N) [N]: ",
+			apiKeyPath)
+		fmt.Scanf("%s", &shouldReplace)
+		shouldReplace = strings.ToUpper(strings.TrimSpace(shouldReplace))
+		if len(shouldReplace) == 0 || shouldReplace[0] != 'Y' {
+			fmt.Printf("Kept your existing api key\n")
+			return
+		}
+	}
+	err = os.MkdirAll(filepath.Dir(apiKeyPath), 0700)
+	if err != nil {
+		exitWithError(ExitFailure, "Could not create config directory %v: %v\n",
+			filepath.Dir(apiKeyPath), err)
+	}
+	_ = os.Remove(apiKeyPath)
+	err = ioutil.WriteFile(apiKeyPath, []byte(export.ApiKey), 0400)
+	if err != nil {
+		exitWithError(ExitFailure, "Could not install %v: %v\n", apiKeyPath,
+			err)
+	}
+	fmt.Printf("Installed imported api key to %v\n", apiKeyPath)
+}
+
+// configDefaultProjectMain shows, sets, or clears the project id used
+// outside of a Bopmatic project directory
+func configDefaultProjectMain(args []string) {
+	f := flag.NewFlagSet("bopmatic config default-project", flag.ExitOnError)
+	setGlobalFlags(f)
+	err := f.Parse(args)
+	if err != nil {
+		exitWithError(ExitUsage, "%v\n", err)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		exitWithError(ExitFailure, "%v\n", err)
+	}
+
+	if f.NArg() == 0 {
+		if cfg.DefaultProject == "" {
+			fmt.Printf("No default project\n")
+		} else {
+			fmt.Printf("%v\n", cfg.DefaultProject)
+		}
+		if os.Getenv(ProjectIdEnvVar) != "" {
+			fmt.Printf("(overridden by %v=%v)\n", ProjectIdEnvVar,
+				os.Getenv(ProjectIdEnvVar))
+		}
+		return
+	}
+
+	projId := f.Arg(0)
+	if projId == "none" {
+		projId = ""
+	}
+	err = updateConfig(func(cfg *cliConfig) error {
+		cfg.DefaultProject = projId
+		return nil
+	})
+	if err != nil {
+		exitWithError(ExitFailure, "%v\n", err)
+	}
+
+	if projId == "" {
+		fmt.Printf("Default project cleared\n")
+	} else {
+		fmt.Printf("Default project set to %v\n", projId)
+	}
+}
+
+// configValidateKeyMain checks the format of pasted api key data without
+// contacting ServiceRunner
+func configValidateKeyMain(args []string) {
+	f := flag.NewFlagSet("bopmatic config --validate-key", flag.ExitOnError)
+	setGlobalFlags(f)
+	err := f.Parse(args)
+	if err != nil {
+		exitWithError(ExitUsage, "%v\n", err)
+	}
+
+	var keyData string
+	if f.NArg() > 0 {
+		keyData = f.Arg(0)
+	} else {
+		if stdinIsTerminal() {
+			fmt.Printf("Paste the key data to check and press enter: ")
+		}
+		keyData, err = bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && err != io.EOF {
+			exitWithError(ExitFailure, "Could not read key data: %v\n", err)
+		}
+	}
+	keyData = strings.TrimSpace(keyData)
+
+	err = validateApiKeyFormat(keyData)
+	if err != nil {
+		exitWithError(ExitFailure, "Invalid key data: %v\n", err)
+	}
+	fmt.Printf("Key data format looks valid; run 'bopmatic config test' after installing it to verify it with Bopmatic ServiceRunner\n")
+}
+
+// configUnsetKeyMain removes the stored api key after confirmation (or
+// --yes). Nothing else needs clearing since login tokens are never
+// persisted.
+func configUnsetKeyMain(args []string) {
+	var yes bool
+	f := flag.NewFlagSet("bopmatic config unset-key", flag.ExitOnError)
+	setGlobalFlags(f)
+	f.BoolVar(&yes, "yes", false, "Remove the api key without prompting")
+	err := f.Parse(args)
+	if err != nil {
+		exitWithError(ExitUsage, "%v\n", err)
+	}
+
+	apiKeyPath, err := getConfigApiKeyPath()
+	if err != nil {
+		exitWithError(ExitFailure, "%v\n", err)
+	}
+	legacyKeyPath, err := getLegacyApiKeyPath()
+	if err != nil {
+		exitWithError(ExitFailure, "%v\n", err)
+	}
+	keyPaths := make([]string, 0)
+	for _, keyPath := range []string{apiKeyPath, legacyKeyPath} {
+		_, err = os.Stat(keyPath)
+		if err == nil {
+			keyPaths = append(keyPaths, keyPath)
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			exitWithError(ExitFailure, "Could not read %v: %v\n", keyPath, err)
+		}
+	}
+	if len(keyPaths) == 0 {
+		fmt.Printf("No api key is stored for the %v profile\n", DefaultProfile)
+		return
+	}
+
+	if !yes && !confirm(fmt.Sprintf("Remove your Bopmatic api key (%v)?",
+		strings.Join(keyPaths, ", "))) {
+		fmt.Printf("Kept your api key\n")
+		return
+	}
+
+	for _, keyPath := range keyPaths {
+		err = removeSecretFile(keyPath)
+		if err != nil {
+			exitWithError(ExitFailure, "%v\n", err)
+		}
+		fmt.Printf("Removed %v\n", keyPath)
+	}
+	fmt.Printf("Run 'bopmatic config' to set up a new api key\n")
+}
+
+// removeSecretFile overwrites path's contents before removing it so that
+// the secret doesn't linger in the file's former blocks. Overwriting is
+// best effort; e.g. copy-on-write filesystems may retain the old data.
+func removeSecretFile(path string) error {
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("Could not read %v: %w", path, err)
+	}
+	// api keys are installed read-only
+	err = os.Chmod(path, 0600)
+	if err == nil {
+		err = os.WriteFile(path, make([]byte, fileInfo.Size()), 0600)
+	}
+	if err != nil {
+		logWarn("Could not overwrite %v before removing it: %v", path, err)
+	}
+
+	err = os.Remove(path)
+	if err != nil {
+		return fmt.Errorf("Could not remove %v: %w", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,144 @@
+/* Copyright © 2024 Bopmatic, LLC. All Rights Reserved.
+ *
+ * See LICENSE file at the root of this package for license terms
+ */
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// how long to wait for another bopmatic process to finish updating the
+	// config file
+	ConfigLockTimeout = 10 * time.Second
+	// config locks older than this were left behind by a crashed process;
+	// config updates take milliseconds
+	ConfigLockStaleAge = 30 * time.Second
+	configLockRetry    = 10 * time.Millisecond
+)
+
+func getConfigLockPath() (string, error) {
+	configFilePath, err := getConfigFilePath()
+	if err != nil {
+		return "", err
+	}
+
+	return configFilePath + ".lock", nil
+}
+
+// lockConfig takes the advisory lock serializing read-modify-write cycles
+// of the config file across bopmatic processes, waiting up to
+// ConfigLockTimeout for another process to release it. The returned release
+// func should be deferred.
+func lockConfig() (func(), error) {
+	lockPath, err := getConfigLockPath()
+	if err != nil {
+		return nil, err
+	}
+	err = os.MkdirAll(filepath.Dir(lockPath), 0700)
+	if err != nil {
+		return nil, fmt.Errorf("Could not create config directory %v: %w",
+			filepath.Dir(lockPath), err)
+	}
+
+	deadline := time.Now().Add(ConfigLockTimeout)
+	for {
+		lockFile, err := os.OpenFile(lockPath,
+			os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			_, _ = lockFile.WriteString(strconv.Itoa(os.Getpid()) + "\n")
+			lockFile.Close()
+			break
+		} else if !errors.Is(err, fs.ErrExist) {
+			return nil, fmt.Errorf("Could not create %v: %w", lockPath, err)
+		}
+
+		lockInfo, statErr := os.Stat(lockPath)
+		if statErr == nil &&
+			time.Since(lockInfo.ModTime()) > ConfigLockStaleAge {
+			logDebug("removing stale config lock %v from %v", lockPath,
+				lockInfo.ModTime())
+			_ = os.Remove(lockPath)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("Timed out waiting for another bopmatic command to finish updating its configuration; if none is running, remove %v",
+				lockPath)
+		}
+		time.Sleep(configLockRetry)
+	}
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			err := os.Remove(lockPath)
+			if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				logWarn("Could not remove config lock %v: %v", lockPath, err)
+			}
+		})
+	}
+
+	return release, nil
+}
+
+// writeFileAtomic replaces path with data such that readers see either the
+// previous or the new content but never a partial write
+func writeFileAtomic(path string, data []byte, perm fs.FileMode) error {
+	tmpFile, err := os.CreateTemp(filepath.Dir(path),
+		"."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmpFile.Name()
+	_, err = tmpFile.Write(data)
+	if err == nil {
+		err = tmpFile.Sync()
+	}
+	closeErr := tmpFile.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmpPath, perm)
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	return nil
+}
+
+// updateConfig loads the config file, applies update, and saves the
+// result while holding the config lock so that concurrent bopmatic
+// processes don't overwrite each other's settings. Nothing is saved if
+// update returns an error.
+func updateConfig(update func(cfg *cliConfig) error) error {
+	release, err := lockConfig()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	err = update(cfg)
+	if err != nil {
+		return err
+	}
+
+	return writeConfig(cfg)
+}
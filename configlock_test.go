@@ -0,0 +1,192 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// useTempConfigDir points getConfigPath() at an empty directory for the
+// duration of the test
+func useTempConfigDir(t *testing.T) string {
+	t.Setenv("HOME", t.TempDir())
+	configPath, err := getConfigPath()
+	if err != nil {
+		t.Fatalf("getConfigPath: %v", err)
+	}
+
+	return configPath
+}
+
+func TestUpdateConfigConcurrentWriters(t *testing.T) {
+	configPath := useTempConfigDir(t)
+
+	const writers = 16
+	var wg sync.WaitGroup
+	errs := make([]error, writers)
+	for idx := 0; idx < writers; idx++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[idx] = updateConfig(func(cfg *cliConfig) error {
+				if cfg.Deploy == nil {
+					cfg.Deploy = &deployConfig{}
+				}
+				cfg.Deploy.ProtectedEnvs = append(cfg.Deploy.ProtectedEnvs,
+					fmt.Sprintf("env%02d", idx))
+				return nil
+			})
+		}()
+	}
+	wg.Wait()
+	for idx, err := range errs {
+		if err != nil {
+			t.Fatalf("writer %v: %v", idx, err)
+		}
+	}
+
+	// every writer's update must survive; without the lock later writers
+	// overwrite earlier ones
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig: %v", err)
+	}
+	if cfg.Deploy == nil || len(cfg.Deploy.ProtectedEnvs) != writers {
+		t.Fatalf("expected %v protected envs, got %+v", writers, cfg.Deploy)
+	}
+	envs := append([]string{}, cfg.Deploy.ProtectedEnvs...)
+	sort.Strings(envs)
+	for idx, env := range envs {
+		if env != fmt.Sprintf("env%02d", idx) {
+			t.Errorf("expected env%02d, got %v", idx, env)
+		}
+	}
+
+	// neither the lock nor any temp file may be left behind
+	entries, err := os.ReadDir(configPath)
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "config.json" {
+		names := make([]string, 0)
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		t.Errorf("expected only config.json in %v, got %v", configPath,
+			names)
+	}
+}
+
+func TestSaveConfigReadersNeverSeePartialWrites(t *testing.T) {
+	useTempConfigDir(t)
+
+	err := saveConfig(&cliConfig{DefaultProject: "initial"})
+	if err != nil {
+		t.Fatalf("saveConfig: %v", err)
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(done)
+		for idx := 0; idx < 50; idx++ {
+			err := updateConfig(func(cfg *cliConfig) error {
+				cfg.DefaultProject = fmt.Sprintf("proj%v", idx)
+				cfg.Logs = &logsConfig{DefaultWindow: fmt.Sprintf("%vh", idx)}
+				return nil
+			})
+			if err != nil {
+				t.Errorf("updateConfig: %v", err)
+				return
+			}
+		}
+	}()
+
+	reads := 0
+	for reading := true; reading; reads++ {
+		select {
+		case <-done:
+			reading = false
+		default:
+		}
+		cfg, err := loadConfig()
+		if err != nil {
+			t.Fatalf("read %v: %v", reads, err)
+		}
+		if cfg.DefaultProject == "" {
+			t.Fatalf("read %v: config was empty", reads)
+		}
+	}
+	wg.Wait()
+}
+
+func TestUpdateConfigErrorSavesNothing(t *testing.T) {
+	useTempConfigDir(t)
+
+	err := saveConfig(&cliConfig{DefaultProject: "before"})
+	if err != nil {
+		t.Fatalf("saveConfig: %v", err)
+	}
+	errUpdate := errors.New("update failed")
+	err = updateConfig(func(cfg *cliConfig) error {
+		cfg.DefaultProject = "after"
+		return errUpdate
+	})
+	if !errors.Is(err, errUpdate) {
+		t.Fatalf("expected update failure, got %v", err)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig: %v", err)
+	}
+	if cfg.DefaultProject != "before" {
+		t.Errorf("expected DefaultProject before, got %v", cfg.DefaultProject)
+	}
+	lockPath, _ := getConfigLockPath()
+	_, err = os.Stat(lockPath)
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected %v to be released, got %v", lockPath, err)
+	}
+}
+
+func TestLockConfigRemovesStaleLock(t *testing.T) {
+	configPath := useTempConfigDir(t)
+
+	lockPath, _ := getConfigLockPath()
+	err := os.MkdirAll(filepath.Dir(lockPath), 0700)
+	if err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	err = os.WriteFile(lockPath, []byte("12345\n"), 0600)
+	if err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	staleTime := time.Now().Add(-2 * ConfigLockStaleAge)
+	err = os.Chtimes(lockPath, staleTime, staleTime)
+	if err != nil {
+		t.Fatalf("Chtimes: %v", err)
+	}
+
+	err = updateConfig(func(cfg *cliConfig) error {
+		cfg.DefaultProject = "unstuck"
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("updateConfig with stale lock in %v: %v", configPath, err)
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig: %v", err)
+	}
+	if cfg.DefaultProject != "unstuck" {
+		t.Errorf("expected DefaultProject unstuck, got %v", cfg.DefaultProject)
+	}
+}
@@ -0,0 +1,145 @@
+/* Copyright © 2024 Bopmatic, LLC. All Rights Reserved.
+ *
+ * See LICENSE file at the root of this package for license terms
+ */
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// per-profile settings (currently just the api key) live in
+// getConfigPath()/profiles/<profile>/
+const (
+	ProfilesSubdir = "profiles"
+	DefaultProfile = "default"
+)
+
+func getConfigProfilePath() (string, error) {
+	configPath, err := getConfigPath()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(configPath, ProfilesSubdir, DefaultProfile), nil
+}
+
+// getLegacyApiKeyPath returns where CLI versions which predate profiles
+// kept the api key
+func getLegacyApiKeyPath() (string, error) {
+	configPath, err := getConfigPath()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(configPath, "apikey"), nil
+}
+
+var errBothApiKeys = errors.New("both a legacy and a migrated api key exist")
+
+// migrateConfig moves a legacy api key into the default profile. The legacy
+// file is first copied to a timestamped backup directory and is only
+// removed once the migrated key reads back intact. It returns the backup
+// directory, or "" when there was nothing to migrate.
+func migrateConfig() (string, error) {
+	legacyKeyPath, err := getLegacyApiKeyPath()
+	if err != nil {
+		return "", err
+	}
+	keyData, err := os.ReadFile(legacyKeyPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	} else if err != nil {
+		return "", fmt.Errorf("Could not read %v: %w", legacyKeyPath, err)
+	}
+	apiKeyPath, err := getConfigApiKeyPath()
+	if err != nil {
+		return "", err
+	}
+	_, err = os.Stat(apiKeyPath)
+	if err == nil {
+		return "", fmt.Errorf("%w: %v and %v; remove whichever is stale",
+			errBothApiKeys, legacyKeyPath, apiKeyPath)
+	}
+
+	configPath, _ := getConfigPath()
+	backupPath := filepath.Join(configPath,
+		"backup-"+time.Now().UTC().Format("20060102T150405Z"))
+	err = os.MkdirAll(backupPath, 0700)
+	if err != nil {
+		return "", fmt.Errorf("Could not create %v: %w", backupPath, err)
+	}
+	err = os.WriteFile(filepath.Join(backupPath, "apikey"), keyData, 0400)
+	if err != nil {
+		return "", fmt.Errorf("Could not back up %v: %w", legacyKeyPath, err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(apiKeyPath), 0700)
+	if err != nil {
+		return "", fmt.Errorf("Could not create %v: %w",
+			filepath.Dir(apiKeyPath), err)
+	}
+	err = os.WriteFile(apiKeyPath, keyData, 0400)
+	if err != nil {
+		return "", fmt.Errorf("Could not write %v: %w", apiKeyPath, err)
+	}
+	migratedData, err := os.ReadFile(apiKeyPath)
+	if err != nil || !bytes.Equal(migratedData, keyData) {
+		_ = os.Remove(apiKeyPath)
+		return "", fmt.Errorf("%v did not read back intact; left %v in place",
+			apiKeyPath, legacyKeyPath)
+	}
+	err = os.Remove(legacyKeyPath)
+	if err != nil {
+		return "", fmt.Errorf("Could not remove %v: %w", legacyKeyPath, err)
+	}
+
+	return backupPath, nil
+}
+
+// migrateConfigOnStartup runs migrateConfig once a legacy layout is
+// detected; failures are only warned about since getApiKey still falls
+// back to the legacy api key
+func migrateConfigOnStartup() {
+	backupPath, err := migrateConfig()
+	if errors.Is(err, errBothApiKeys) {
+		// the migrated key is used; don't nag on every run
+		logDebug("%v", err)
+	} else if err != nil {
+		logWarn("Could not migrate Bopmatic configuration: %v; run 'bopmatic config migrate' to retry",
+			err)
+	} else if backupPath != "" {
+		apiKeyPath, _ := getConfigApiKeyPath()
+		logInfo("Migrated your api key to %v (backup in %v)", apiKeyPath,
+			backupPath)
+	}
+}
+
+// configMigrateMain explicitly migrates a legacy config layout
+func configMigrateMain(args []string) {
+	f := flag.NewFlagSet("bopmatic config migrate", flag.ExitOnError)
+	setGlobalFlags(f)
+	err := f.Parse(args)
+	if err != nil {
+		exitWithError(ExitUsage, "%v\n", err)
+	}
+
+	backupPath, err := migrateConfig()
+	if err != nil {
+		exitWithError(ExitFailure, "%v\n", err)
+	}
+	if backupPath == "" {
+		fmt.Printf("Your Bopmatic configuration is already up to date\n")
+		return
+	}
+	apiKeyPath, _ := getConfigApiKeyPath()
+	fmt.Printf("Migrated your api key to %v; the previous layout was backed up to %v\n",
+		apiKeyPath, backupPath)
+}
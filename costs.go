@@ -0,0 +1,44 @@
+/* Copyright © 2024 Bopmatic, LLC. All Rights Reserved.
+ *
+ * See LICENSE file at the root of this package for license terms
+ */
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	bopsdk "github.com/bopmatic/sdk/golang"
+)
+
+var errCostsUnavailable = errors.New("cost data unavailable; Bopmatic ServiceRunner does not currently report cost or usage data")
+
+// projCostsOutput is the --show-costs section of 'bopmatic project describe'
+type projCostsOutput struct {
+	StartTime string `json:"startTime"`
+	EndTime   string `json:"endTime"`
+}
+
+// getProjectCosts reports projId's estimated cost over the billing period
+// from startTime to endTime. ServiceRunner doesn't yet report cost data so
+// this currently always fails with errCostsUnavailable; since cost data is
+// informational, callers note its absence rather than failing.
+// @todo once ServiceRunner exposes cost data, fan out across each service,
+// database, and datastore via newFanOutGroup() and report a project total
+func getProjectCosts(projId string, startTime time.Time, endTime time.Time,
+	sdkOpts []bopsdk.DeployOption) (*projCostsOutput, error) {
+
+	logDebug("no cost data for projId:%v between %v and %v", projId,
+		startTime, endTime)
+
+	return nil, errCostsUnavailable
+}
+
+func printProjectCosts(costsOut *projCostsOutput, costsErr error) {
+	if costsErr != nil {
+		fmt.Printf("\tCosts: %v\n", costsErr)
+		return
+	}
+	fmt.Printf("\tCosts (%v - %v):\n", costsOut.StartTime, costsOut.EndTime)
+}
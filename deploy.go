@@ -5,9 +5,16 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
 	_ "embed"
 
@@ -31,51 +38,252 @@ func deployHelpMain(args []string) {
 func deployListMain(args []string) {
 	sdkOpts, err := getAuthSdkOpts()
 	if err != nil {
-		fmt.Fprintf(os.Stderr,
-			"Failed to get user creds; did you run bompatic config? err: %v\n",
-			err)
-		os.Exit(1)
+		exitWithError(ExitAuth, "%v\n", err)
 	}
 
 	type listOpts struct {
-		common commonOpts
+		common    commonOpts
+		porcelain bool
+		projIds   string
+		last      int
 	}
 
 	var opts listOpts
 
 	f := flag.NewFlagSet("bopmatic deploy list", flag.ExitOnError)
 	setCommonFlags(f, &opts.common)
+	setTimestampFlags(f)
+	f.StringVar(&opts.common.startTime, "since", "", "Alias for --starttime")
+	f.StringVar(&opts.common.endTime, "until", "", "Alias for --endtime")
+	f.BoolVar(&opts.porcelain, "porcelain", false,
+		"Print a stable, header-less, space separated format for scripts")
+	f.StringVar(&opts.projIds, "projids", "",
+		"Comma separated project ids whose deployments are listed together with a project column")
+	f.IntVar(&opts.last, "last", 0,
+		"Only list the N most recently created deployments along with their create time")
 
 	err = f.Parse(args)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
-		os.Exit(1)
+		exitWithError(ExitUsage, "%v\n", err)
 	}
-	if opts.common.projectId == "" {
-		proj, err := bopsdk.NewProject(opts.common.projectFilename)
-		if err == nil {
-			opts.common.projectId = proj.Desc.Id
+	checkPorcelain(opts.porcelain)
+	if opts.last < 0 {
+		exitWithError(ExitUsage, "--last cannot be negative\n")
+	}
+	// --projids always includes the project column, even for a single id,
+	// so that the output's shape doesn't depend on how many were given
+	multiProj := opts.projIds != ""
+	projIds := parseIdList(opts.projIds)
+	if multiProj {
+		if opts.common.projectId != "" {
+			exitWithError(ExitUsage, "--projids cannot be combined with --projid\n")
+		}
+		if len(projIds) == 0 {
+			exitWithError(ExitUsage, "--projids requires at least one project id\n")
+		}
+	} else {
+		if opts.common.projectId == "" {
+			opts.common.projectId, err = resolveProjectId(
+				opts.common.projFile())
+			if err != nil {
+				exitWithError(ExitUsage, "%v\n", err)
+			}
 		}
+		projIds = []string{opts.common.projectId}
 	}
 
-	fmt.Printf("Listing deployments for project %v...", opts.common.projectId)
+	// ordering by create time requires describing each deployment
+	if opts.common.startTime != "" || opts.common.endTime != "" ||
+		opts.last > 0 {
+		// without --starttime there's no lower bound
+		startTime, endTime, err := parseTimeWindowWithDefault(
+			opts.common.startTime, opts.common.endTime,
+			time.Since(time.UnixMilli(0)))
+		if err != nil {
+			exitWithError(ExitUsage, "%v\n", err)
+		}
+		listDeploymentsInWindow(projIds, multiProj, startTime, endTime,
+			opts.last, opts.porcelain, sdkOpts)
+		return
+	}
 
-	// @todo add envId
-	deployments, err := bopsdk.ListDeployments(opts.common.projectId, "",
-		sdkOpts...)
+	if !opts.porcelain {
+		fmt.Printf("Listing deployments for %v...", describeProjIds(projIds))
+	}
+
+	deployments, err := listProjectsDeployments(projIds, sdkOpts)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
-		os.Exit(1)
+		exitWithError(ExitServer, "%v\n", err)
 	}
 
-	if len(deployments) == 0 {
+	if opts.porcelain {
+		// <deployment id> [<project id> with --projids]
+		for _, deployment := range deployments {
+			if multiProj {
+				printPorcelain(deployment.deployId, deployment.projId)
+			} else {
+				printPorcelain(deployment.deployId)
+			}
+		}
+	} else if len(deployments) == 0 {
 		fmt.Printf("\nNo currently deployed packages\n")
+	} else if multiProj {
+		fmt.Printf("\n%-24v%v\n", "Project Id", "Deployment Id")
+		fmt.Printf("%-24v%v\n", "----------", "-------------")
+		for _, deployment := range deployments {
+			fmt.Printf("%-24v%v\n", deployment.projId, deployment.deployId)
+		}
 	} else {
 		fmt.Printf("\nDeploymentId\n")
 
-		for _, deployId := range deployments {
-			fmt.Printf("%v\n", deployId)
+		for _, deployment := range deployments {
+			fmt.Printf("%v\n", deployment.deployId)
+		}
+	}
+}
+
+// describeProjIds renders projIds for progress messages
+func describeProjIds(projIds []string) string {
+	if len(projIds) == 1 {
+		return "project " + projIds[0]
+	}
+
+	return "projects " + strings.Join(projIds, ", ")
+}
+
+// a deployment id along with the project it belongs to
+type projDeployment struct {
+	projId   string
+	deployId string
+}
+
+// listProjectsDeployments lists the deployments of each of projIds
+// concurrently; the result is ordered by project as given
+func listProjectsDeployments(projIds []string,
+	sdkOpts []bopsdk.DeployOption) ([]projDeployment, error) {
+
+	deployIdLists := make([][]string, len(projIds))
+	wg := newFanOutGroup()
+	for idx, projId := range projIds {
+		wg.Go(func() error {
+			var err error
+			// @todo add envId
+			deployIdLists[idx], err = bopsdk.ListDeployments(projId, "",
+				sdkOpts...)
+			if err != nil {
+				return fmt.Errorf("%v: %w", projId, err)
+			}
+			return nil
+		})
+	}
+	err := wg.Wait()
+	if err != nil {
+		return nil, err
+	}
+
+	deployments := make([]projDeployment, 0)
+	for idx, projId := range projIds {
+		for _, deployId := range deployIdLists[idx] {
+			deployments = append(deployments, projDeployment{
+				projId:   projId,
+				deployId: deployId,
+			})
+		}
+	}
+
+	return deployments, nil
+}
+
+// listDeploymentsInWindow prints the id and create time of each of projIds'
+// deployments created between startTime and endTime, oldest first; with
+// last > 0 only the last most recent of those are printed. multiProj adds a
+// project column.
+func listDeploymentsInWindow(projIds []string, multiProj bool,
+	startTime time.Time, endTime time.Time, last int, porcelain bool,
+	sdkOpts []bopsdk.DeployOption) {
+
+	if !porcelain {
+		if last > 0 {
+			fmt.Printf("Listing the %v most recent deployments for %v...",
+				last, describeProjIds(projIds))
+		} else {
+			fmt.Printf("Listing deployments for %v created between %v and %v...",
+				describeProjIds(projIds), startTime.UTC().Format(time.RFC3339),
+				endTime.UTC().Format(time.RFC3339))
+		}
+	}
+
+	deployments, err := listProjectsDeployments(projIds, sdkOpts)
+	if err != nil {
+		exitWithError(ExitServer, "%v\n", err)
+	}
+
+	deployDescList := make([]*pb.DeploymentDescription, len(deployments))
+	wg := newFanOutGroup()
+	for idx, deployment := range deployments {
+		wg.Go(func() error {
+			deployDesc, err := bopsdk.DescribeDeployment(deployment.deployId,
+				sdkOpts...)
+			if err != nil {
+				return fmt.Errorf("%v: %w", deployment.deployId, err)
+			}
+			deployDescList[idx] = deployDesc
+			return nil
+		})
+	}
+	err = wg.Wait()
+	if err != nil {
+		exitWithError(ExitServer, "%v\n", err)
+	}
+
+	inWindow := make([]*pb.DeploymentDescription, 0)
+	for _, deployDesc := range deployDescList {
+		created := time.UnixMilli(int64(deployDesc.CreateTime))
+		if created.Before(startTime) || created.After(endTime) {
+			continue
 		}
+		inWindow = append(inWindow, deployDesc)
+	}
+	sort.Slice(inWindow, func(i, j int) bool {
+		return inWindow[i].CreateTime < inWindow[j].CreateTime
+	})
+	if last > 0 && len(inWindow) > last {
+		inWindow = inWindow[len(inWindow)-last:]
+	}
+
+	if porcelain {
+		// <deployment id> <created (epoch msecs)> [<project id> with
+		// --projids]
+		for _, deployDesc := range inWindow {
+			if multiProj {
+				printPorcelain(deployDesc.Id, deployDesc.CreateTime,
+					deployDesc.Header.ProjId)
+			} else {
+				printPorcelain(deployDesc.Id, deployDesc.CreateTime)
+			}
+		}
+		return
+	}
+	if len(inWindow) == 0 {
+		fmt.Printf("\nNo deployments were created in this time window\n")
+		return
+	}
+	if multiProj {
+		fmt.Printf("\n%-24v%-24v%v\n", "Project Id", "Deployment Id",
+			"Created")
+		fmt.Printf("%-24v%-24v%v\n", "----------", "-------------",
+			"-------")
+		for _, deployDesc := range inWindow {
+			fmt.Printf("%-24v%-24v%v\n", deployDesc.Header.ProjId,
+				deployDesc.Id, formatTimestamp(deployDesc.CreateTime))
+		}
+		return
+	}
+	fmt.Printf("\n%-24v%v\n", "Deployment Id", "Created")
+	fmt.Printf("%-24v%v\n", "-------------", "-------")
+	for _, deployDesc := range inWindow {
+		fmt.Printf("%-24v%v\n", deployDesc.Id,
+			formatTimestamp(deployDesc.CreateTime))
 	}
 }
 
@@ -107,48 +315,194 @@ func deployMain(args []string) {
 func deployDescribeMain(args []string) {
 	sdkOpts, err := getAuthSdkOpts()
 	if err != nil {
-		fmt.Fprintf(os.Stderr,
-			"Failed to get user creds; did you run bompatic config? err: %v\n",
-			err)
-		os.Exit(1)
+		exitWithError(ExitAuth, "%v\n", err)
 	}
 
 	type describeOpts struct {
-		common commonOpts
+		common          commonOpts
+		failures        bool
+		pollUntilChange bool
+		fields          string
+		export          string
+		format          string
 	}
 
 	var opts describeOpts
 
 	f := flag.NewFlagSet("bopmatic deploy describe", flag.ExitOnError)
 	setCommonFlags(f, &opts.common)
+	f.BoolVar(&opts.failures, "failures", false,
+		"Only display failure details and suggested next steps")
+	setTimestampFlags(f)
+	f.BoolVar(&opts.pollUntilChange, "poll-until-change", false,
+		"Poll the deployment, printing only state transitions, until it completes")
+	f.BoolVar(&opts.pollUntilChange, "wait", false,
+		"Alias for --poll-until-change")
+	setDeployHookFlags(f)
+	f.StringVar(&opts.fields, "fields", "",
+		"Only print these comma separated fields (e.g. State,Detail) as key=value")
+	f.StringVar(&opts.export, "export", "",
+		"Also write the full deployment report to this file as json (or yaml with --output yaml)")
+	f.StringVar(&opts.format, "format", "",
+		"Alternate rendering; duration-table prints how long each deployment phase took")
 
 	err = f.Parse(args)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
-		os.Exit(1)
+		exitWithError(ExitUsage, "%v\n", err)
+	}
+	if opts.pollUntilChange && opts.failures {
+		exitWithError(ExitUsage, "--poll-until-change cannot be combined with --failures\n")
+	}
+	fields, err := parseFields(opts.fields, deployDescribeOutput{})
+	if err != nil {
+		exitWithError(ExitUsage, "%v\n", err)
+	}
+	if fields != nil && (opts.pollUntilChange || opts.failures) {
+		exitWithError(ExitUsage, "--fields cannot be combined with --poll-until-change or --failures\n")
+	}
+	if opts.export != "" && opts.pollUntilChange {
+		exitWithError(ExitUsage, "--export cannot be combined with --poll-until-change\n")
+	}
+	if opts.format != "" {
+		if opts.format != DeployFormatDurationTable {
+			exitWithError(ExitUsage, "Invalid --format %v; must be %v\n",
+				opts.format, DeployFormatDurationTable)
+		}
+		if fields != nil || opts.pollUntilChange || opts.failures ||
+			outputFormat != OutputText {
+			exitWithError(ExitUsage, "--format cannot be combined with --fields, --poll-until-change, --failures, or --output\n")
+		}
+	}
+	if deployCompletionHooks.isSet() && !opts.pollUntilChange {
+		exitWithError(ExitUsage, "--on-success and --on-failure require --wait (or --poll-until-change)\n")
 	}
 	if opts.common.deployId == "" {
-		fmt.Fprintf(os.Stderr, "Please specify deployment id with --deployid. If you don't know this, try 'bopmatic deployment list'\n")
-		os.Exit(1)
+		if !stdinIsTerminal() {
+			exitWithError(ExitUsage, "Please specify deployment id with --deployid. If you don't know this, try 'bopmatic deployment list'\n")
+		}
+		if opts.common.projectId == "" {
+			opts.common.projectId, err = resolveProjectId(opts.common.projFile())
+			if err != nil {
+				exitWithError(ExitUsage, "%v\n", err)
+			}
+		}
+		opts.common.deployId, err = pickDeployId(opts.common.projectId,
+			sdkOpts)
+		if err != nil {
+			exitPickError(err)
+		}
+	}
+	if opts.common.deployId == DeployIdLatest ||
+		opts.common.deployId == DeployIdActive {
+
+		opts.common.deployId = resolveDeployIdAlias(&opts.common, sdkOpts)
 	}
 
-	fmt.Printf("Describing deployId:%v...", opts.common.deployId)
+	if opts.pollUntilChange {
+		fmt.Printf("Watching deployId:%v for state changes...\n",
+			opts.common.deployId)
+		watchDeployment(opts.common.deployId, "deployment", sdkOpts, 0,
+			func(deployDesc *pb.DeploymentDescription) string {
+				return fmt.Sprintf("%v (%v)", deployDesc.State,
+					deployDesc.StateDetail)
+			})
+		fmt.Printf("Deployment %v succeeded\n", opts.common.deployId)
+		return
+	}
+
+	if fields == nil {
+		fmt.Printf("Describing deployId:%v...", opts.common.deployId)
+	}
 	deployDesc, err := bopsdk.DescribeDeployment(opts.common.deployId,
 		sdkOpts...)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
-		os.Exit(1)
+		exitWithError(ExitServer, "%v\n", err)
+	}
+
+	if opts.export != "" {
+		err = exportDeployReport(opts.export, deployDesc)
+		if err != nil {
+			exitWithError(ExitFailure, "%v\n", err)
+		}
+		// keep stdout clean for --fields and structured output
+		fmt.Fprintf(os.Stderr, "Wrote deployment report to %v\n", opts.export)
+	}
+
+	if fields != nil {
+		deployOut := newDeployDescribeOutput(deployDesc)
+		err = printFields(&deployOut, fields)
+		if err != nil {
+			exitWithError(ExitFailure, "%v\n", err)
+		}
+		if deployDesc.State == pb.DeploymentState_FAILED {
+			exit(ExitFailure)
+		}
+		return
 	}
 
-	fmt.Printf("\nDeployment Id:%v\n\tProject Id:%v\n\tPackage Id:%v\n\tEnvironment Id:%v\n\tType:%v\n\tInitiator:%v\n\tState:%v\n\tDetail:%v\n\tCreate Time:           %v\n\tValidation Start Time: %v\n\tBuild Start Time:      %v\n\tDeploy Start Time:     %v\n\tCompletion Time:       %v\n",
-		deployDesc.Id, deployDesc.Header.ProjId, deployDesc.Header.PkgId,
+	if opts.format == DeployFormatDurationTable {
+		fmt.Printf("\n")
+		printDeployDurationTable(deployDesc)
+		if deployDesc.State == pb.DeploymentState_FAILED {
+			exit(ExitFailure)
+		}
+		return
+	}
+
+	if opts.failures {
+		fmt.Printf("\n")
+		if deployDesc.State != pb.DeploymentState_FAILED {
+			fmt.Printf("Deployment %v has not failed; its current state is %v\n",
+				deployDesc.Id, deployDesc.State)
+			return
+		}
+		printDeployFailure(deployDesc)
+		exit(ExitFailure)
+	}
+
+	// enrich the opaque ids with the project name and package upload time;
+	// these are best effort so fall back to showing just the ids on failure
+	projIdStr := deployDesc.Header.ProjId
+	pkgIdStr := deployDesc.Header.PkgId
+	wg := newFanOutGroup()
+	wg.Go(func() error {
+		projDesc, err := bopsdk.DescribeProject(deployDesc.Header.ProjId,
+			sdkOpts...)
+		if err != nil {
+			logDebug("could not describe project %v: %v",
+				deployDesc.Header.ProjId, err)
+			return nil
+		}
+		projIdStr = fmt.Sprintf("%v (%v)", projDesc.Id, projDesc.Header.Name)
+		return nil
+	})
+	wg.Go(func() error {
+		pkgDesc, err := bopsdk.Describe(deployDesc.Header.PkgId, sdkOpts...)
+		if err != nil {
+			logDebug("could not describe package %v: %v",
+				deployDesc.Header.PkgId, err)
+			return nil
+		}
+		pkgIdStr = fmt.Sprintf("%v (uploaded %v)", pkgDesc.PackageId,
+			formatTimestamp(pkgDesc.UploadTime))
+		return nil
+	})
+	_ = wg.Wait()
+
+	noteStr := ""
+	if note := getDeployNote(deployDesc.Id); note != "" {
+		noteStr = fmt.Sprintf("\tNote:%v\n", note)
+	}
+
+	fmt.Printf("\nDeployment Id:%v\n\tProject Id:%v\n\tPackage Id:%v\n\tEnvironment Id:%v\n\tType:%v\n\tInitiator:%v\n%v\tState:%v\n\tDetail:%v\n\tCreate Time:           %v\n\tValidation Start Time: %v\n\tBuild Start Time:      %v\n\tDeploy Start Time:     %v\n\tCompletion Time:       %v\n",
+		deployDesc.Id, projIdStr, pkgIdStr,
 		deployDesc.Header.EnvId, deployDesc.Header.Type,
-		deployDesc.Header.Initiator, deployDesc.State, deployDesc.StateDetail,
-		unixTime2UtcStr(deployDesc.CreateTime),
-		unixTime2UtcStr(deployDesc.ValidationStartTime),
-		unixTime2UtcStr(deployDesc.BuildStartTime),
-		unixTime2UtcStr(deployDesc.DeployStartTime),
-		unixTime2UtcStr(deployDesc.EndTime))
+		deployDesc.Header.Initiator, noteStr, deployDesc.State, deployDesc.StateDetail,
+		formatTimestamp(deployDesc.CreateTime),
+		formatTimestamp(deployDesc.ValidationStartTime),
+		formatTimestamp(deployDesc.BuildStartTime),
+		formatTimestamp(deployDesc.DeployStartTime),
+		formatTimestamp(deployDesc.EndTime))
 
 	switch deployDesc.State {
 	case pb.DeploymentState_CREATED:
@@ -162,8 +516,308 @@ func deployDescribeMain(args []string) {
 	case pb.DeploymentState_SUCCESS:
 		fmt.Printf("\nBopmatic ServiceRunner has successully completed this deployment of your package\n")
 	case pb.DeploymentState_FAILED:
-		fallthrough
+		fmt.Printf("\n")
+		printDeployFailure(deployDesc)
+		exit(ExitFailure)
 	case pb.DeploymentState_UNKNOWN_DEPLOY_STATE:
 		fmt.Printf("\nAn error occurred within Bopmatic ServiceRunner and a support staff member needs to examine the situation.\n")
 	}
 }
+
+// deployDescribeOutput names the fields 'bopmatic deploy describe --fields'
+// can select
+type deployDescribeOutput struct {
+	Id                  string `json:"id"`
+	ProjectId           string `json:"projectId"`
+	PackageId           string `json:"packageId"`
+	EnvironmentId       string `json:"environmentId"`
+	Type                string `json:"type"`
+	Initiator           string `json:"initiator"`
+	Reason              string `json:"reason,omitempty"`
+	Note                string `json:"note,omitempty"`
+	State               string `json:"state"`
+	Detail              string `json:"detail"`
+	CreateTime          string `json:"createTime"`
+	ValidationStartTime string `json:"validationStartTime"`
+	BuildStartTime      string `json:"buildStartTime"`
+	DeployStartTime     string `json:"deployStartTime"`
+	EndTime             string `json:"endTime"`
+	// time spent in each phase; empty until the phase has completed
+	ValidationDuration string `json:"validationDuration,omitempty"`
+	BuildDuration      string `json:"buildDuration,omitempty"`
+	DeployDuration     string `json:"deployDuration,omitempty"`
+	TotalDuration      string `json:"totalDuration,omitempty"`
+}
+
+func newDeployDescribeOutput(
+	deployDesc *pb.DeploymentDescription) deployDescribeOutput {
+
+	return deployDescribeOutput{
+		Id:                  deployDesc.Id,
+		ProjectId:           deployDesc.Header.ProjId,
+		PackageId:           deployDesc.Header.PkgId,
+		EnvironmentId:       deployDesc.Header.EnvId,
+		Type:                deployDesc.Header.Type.String(),
+		Initiator:           deployDesc.Header.Initiator.String(),
+		Reason:              deployDesc.Header.Reason,
+		Note:                getDeployNote(deployDesc.Id),
+		State:               deployDesc.State.String(),
+		Detail:              deployDesc.StateDetail.String(),
+		CreateTime:          formatTimestamp(deployDesc.CreateTime),
+		ValidationStartTime: formatTimestamp(deployDesc.ValidationStartTime),
+		BuildStartTime:      formatTimestamp(deployDesc.BuildStartTime),
+		DeployStartTime:     formatTimestamp(deployDesc.DeployStartTime),
+		EndTime:             formatTimestamp(deployDesc.EndTime),
+		ValidationDuration: phaseDuration(deployDesc.ValidationStartTime,
+			deployDesc.BuildStartTime),
+		BuildDuration: phaseDuration(deployDesc.BuildStartTime,
+			deployDesc.DeployStartTime),
+		DeployDuration: phaseDuration(deployDesc.DeployStartTime,
+			deployDesc.EndTime),
+		TotalDuration: phaseDuration(deployDesc.CreateTime, deployDesc.EndTime),
+	}
+}
+
+// phaseDuration returns the time between two deployment timestamps
+// (milliseconds since the epoch), or "" if either hasn't happened
+func phaseDuration(startMsecs uint64, endMsecs uint64) string {
+	if startMsecs == 0 || endMsecs == 0 || endMsecs < startMsecs {
+		return ""
+	}
+
+	return (time.Duration(endMsecs-startMsecs) * time.Millisecond).Round(
+		time.Second).String()
+}
+
+// --format values accepted by 'bopmatic deploy describe'
+const DeployFormatDurationTable = "duration-table"
+
+// printDeployDurationTable prints how long each of deployDesc's phases took;
+// phases which haven't completed are shown as in progress or pending
+func printDeployDurationTable(deployDesc *pb.DeploymentDescription) {
+	phases := []struct {
+		name  string
+		start uint64
+		end   uint64
+	}{
+		{"Queued", deployDesc.CreateTime, deployDesc.ValidationStartTime},
+		{"Validation", deployDesc.ValidationStartTime, deployDesc.BuildStartTime},
+		{"Build", deployDesc.BuildStartTime, deployDesc.DeployStartTime},
+		{"Deploy", deployDesc.DeployStartTime, deployDesc.EndTime},
+	}
+
+	fmt.Printf("%-14v%v\n", "Phase", "Duration")
+	fmt.Printf("%-14v%v\n", "-----", "--------")
+	for _, phase := range phases {
+		end := phase.end
+		if end == 0 && isTerminalDeployState(deployDesc) {
+			// the phase in which the deployment failed
+			end = deployDesc.EndTime
+		}
+		duration := phaseDuration(phase.start, end)
+		if duration == "" && phase.start != 0 {
+			duration = "in progress"
+		} else if duration == "" {
+			duration = "-"
+		}
+		fmt.Printf("%-14v%v\n", phase.name, duration)
+	}
+	total := phaseDuration(deployDesc.CreateTime, deployDesc.EndTime)
+	if total == "" {
+		total = fmt.Sprintf("%v so far",
+			time.Since(unixTime2Local(deployDesc.CreateTime)).Round(time.Second))
+	}
+	fmt.Printf("%-14v%v\n", "-----", "--------")
+	fmt.Printf("%-14v%v\n", "Total", total)
+}
+
+// exportDeployReport writes deployDesc's full description to reportPath,
+// creating parent directories as needed
+func exportDeployReport(reportPath string,
+	deployDesc *pb.DeploymentDescription) error {
+
+	deployOut := newDeployDescribeOutput(deployDesc)
+	reportBuf, err := renderStructured(&deployOut, outputFormat)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(reportPath), 0755)
+	if err != nil {
+		return fmt.Errorf("Could not create %v: %w", filepath.Dir(reportPath),
+			err)
+	}
+	err = os.WriteFile(reportPath, reportBuf, 0644)
+	if err != nil {
+		return fmt.Errorf("Could not write %v: %w", reportPath, err)
+	}
+
+	return nil
+}
+
+const (
+	// --deployid aliases for the project's most recent and currently active
+	// deployments
+	DeployIdLatest = "latest"
+	DeployIdActive = "active"
+)
+
+// resolveDeployIdAlias maps DeployIdLatest or DeployIdActive to the
+// corresponding deployment id of the --projid (or current) project
+func resolveDeployIdAlias(opts *commonOpts,
+	sdkOpts []bopsdk.DeployOption) string {
+
+	var err error
+	if opts.projectId == "" {
+		opts.projectId, err = resolveProjectId(opts.projFile())
+		if err != nil {
+			exitWithError(ExitUsage, "%v\n", err)
+		}
+	}
+
+	var deployIds []string
+	if opts.deployId == DeployIdActive {
+		var projDesc *pb.ProjectDescription
+		projDesc, err = bopsdk.DescribeProject(opts.projectId, sdkOpts...)
+		if err == nil {
+			deployIds = projDesc.ActiveDeployIds
+		}
+	} else {
+		// @todo add envId
+		deployIds, err = bopsdk.ListDeployments(opts.projectId, "", sdkOpts...)
+	}
+	if err != nil {
+		exitWithError(ExitServer, "%v\n", err)
+	}
+	if len(deployIds) == 0 {
+		exitWithError(ExitNotFound, "Project %v has no %v deployment\n",
+			opts.projectId, opts.deployId)
+	}
+	if len(deployIds) == 1 {
+		return deployIds[0]
+	}
+
+	// deployment ids are opaque so describe each to find the newest
+	deployDescList := make([]*pb.DeploymentDescription, len(deployIds))
+	wg := newFanOutGroup()
+	for idx, deployId := range deployIds {
+		wg.Go(func() error {
+			var err error
+			deployDescList[idx], err = bopsdk.DescribeDeployment(deployId,
+				sdkOpts...)
+			return err
+		})
+	}
+	err = wg.Wait()
+	if err != nil {
+		exitWithError(ExitServer, "%v\n", err)
+	}
+	newest := deployDescList[0]
+	for _, deployDesc := range deployDescList[1:] {
+		if deployDesc.CreateTime > newest.CreateTime {
+			newest = deployDesc
+		}
+	}
+
+	return newest.Id
+}
+
+// waitForDeployment polls deployId until it completes, printing each state
+// change as the named operation progresses. It exits non-zero with failure
+// details when the deployment fails or the wait is interrupted.
+func waitForDeployment(deployId string, what string,
+	sdkOpts []bopsdk.DeployOption) {
+
+	watchDeployment(deployId, what, sdkOpts, 0,
+		func(deployDesc *pb.DeploymentDescription) string {
+			return fmt.Sprintf("%v %v", what, deployDesc.State)
+		})
+}
+
+// watchDeployment is waitForDeployment() with describeState determining
+// which polled changes are printed and, when non-zero, timeout bounding the
+// wait
+// @todo subscribe to deployment events rather than polling once
+// ServiceRunner offers a streaming endpoint; polling remains the fallback
+func watchDeployment(deployId string, what string,
+	sdkOpts []bopsdk.DeployOption, timeout time.Duration,
+	describeState func(deployDesc *pb.DeploymentDescription) string) {
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if timeout != 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	deployDesc, err := pollUntil(ctx, pollOptions{
+		interval:    DefaultPollInterval,
+		maxInterval: DefaultPollMaxInterval,
+		out:         os.Stdout,
+	}, func(ctx context.Context) (*pb.DeploymentDescription, error) {
+		return bopsdk.DescribeDeployment(deployId, sdkOpts...)
+	}, isTerminalDeployState, describeState)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) ||
+			errors.Is(err, errPollTimeout) {
+			exitWithError(ExitFailure, "Timed out after %v; check progress with:\n\t'bopmatic deploy describe --deployid %v'\n",
+				timeout, deployId)
+		} else if errors.Is(err, context.Canceled) {
+			exitWithError(ExitFailure, "Interrupted; %v continues in the background. Check progress with:\n\t'bopmatic deploy describe --deployid %v'\n",
+				what, deployId)
+		}
+		exitWithError(ExitServer, "%v\n", err)
+	}
+	if deployDesc.State != pb.DeploymentState_SUCCESS {
+		fmt.Printf("\n")
+		printDeployFailure(deployDesc)
+		err = runDeployCompletionHook(deployDesc)
+		if err != nil {
+			logError("%v", err)
+		}
+		exit(ExitFailure)
+	}
+	err = runDeployCompletionHook(deployDesc)
+	if err != nil {
+		exitWithError(ExitFailure, "%v %v succeeded but %v\n", what,
+			deployId, err)
+	}
+}
+
+// printDeployFailure explains why a deployment failed along with the next
+// steps a user can take to resolve it themselves
+func printDeployFailure(deployDesc *pb.DeploymentDescription) {
+	fmt.Printf("*** Deployment %v FAILED ***\n", deployDesc.Id)
+	fmt.Printf("\tFailure detail: %v\n", deployDesc.StateDetail)
+	if deployDesc.Header.Reason != "" {
+		fmt.Printf("\tDeployment reason: %v\n", deployDesc.Header.Reason)
+	}
+	if deployDesc.EndTime != 0 {
+		fmt.Printf("\tFailed at: %v\n", formatTimestamp(deployDesc.EndTime))
+	}
+
+	fmt.Printf("\n")
+	switch deployDesc.StateDetail {
+	case pb.DeploymentStateDetail_PKG_INVALID:
+		fmt.Printf("Bopmatic ServiceRunner rejected package %v during validation.\n",
+			deployDesc.Header.PkgId)
+	case pb.DeploymentStateDetail_BLD_INVALID:
+		fmt.Printf("Bopmatic ServiceRunner could not build infrastructure for package %v; this usually\nmeans your project's services, databases, or datastores are misconfigured.\n",
+			deployDesc.Header.PkgId)
+	default:
+		fmt.Printf("An error occurred within Bopmatic ServiceRunner and a support staff member needs to examine the situation.\n")
+	}
+
+	fmt.Printf("\nSuggested next steps:\n")
+	fmt.Printf("\t- Inspect the package: 'bopmatic package describe --pkgid %v'\n",
+		deployDesc.Header.PkgId)
+	fmt.Printf("\t- Check your service logs: 'bopmatic logs --projid %v --starttime \"%v\"'\n",
+		deployDesc.Header.ProjId,
+		unixTime2Utc(deployDesc.CreateTime).Format(time.RFC3339))
+	switch deployDesc.StateDetail {
+	case pb.DeploymentStateDetail_PKG_INVALID, pb.DeploymentStateDetail_BLD_INVALID:
+		fmt.Printf("\t- Fix your project, then rebuild & redeploy: 'bopmatic package build && bopmatic package deploy'\n")
+	default:
+		fmt.Printf("\t- Contact Bopmatic support and include deployment id %v\n",
+			deployDesc.Id)
+	}
+}
@@ -0,0 +1,131 @@
+/* Copyright © 2024 Bopmatic, LLC. All Rights Reserved.
+ *
+ * See LICENSE file at the root of this package for license terms
+ */
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/bopmatic/sdk/golang/pb"
+)
+
+// commands run once a waited upon deployment completes
+type deployHooks struct {
+	onSuccess string
+	onFailure string
+}
+
+// set by --on-success & --on-failure; run by watchDeployment()
+var deployCompletionHooks deployHooks
+
+// the variables runDeployCompletionHook sets in a hook's environment
+const deployHookEnvHelp = "BOPMATIC_DEPLOY_ID, BOPMATIC_DEPLOY_STATE, BOPMATIC_DEPLOY_STATE_DETAIL, BOPMATIC_DEPLOY_PROJECT_ID, & BOPMATIC_DEPLOY_PACKAGE_ID are set in its environment"
+
+func setDeployHookFlags(f *flag.FlagSet) {
+	f.StringVar(&deployCompletionHooks.onSuccess, "on-success", "",
+		"Command to run once the deployment succeeds; "+deployHookEnvHelp)
+	f.StringVar(&deployCompletionHooks.onFailure, "on-failure", "",
+		"Command to run if the deployment fails; "+deployHookEnvHelp)
+}
+
+func (hooks deployHooks) isSet() bool {
+	return hooks.onSuccess != "" || hooks.onFailure != ""
+}
+
+// splitHookCommand splits a hook command into argv the way a shell would
+// split words, honoring single & double quotes and backslash escapes, but
+// without any expansion, substitution, redirection, or command chaining;
+// the command is exec'd directly rather than via a shell
+func splitHookCommand(cmdStr string) ([]string, error) {
+	argv := make([]string, 0)
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+	for _, c := range cmdStr {
+		switch {
+		case escaped:
+			word.WriteRune(c)
+			escaped = false
+		case c == '\\' && quote != '\'':
+			escaped = true
+			inWord = true
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else {
+				word.WriteRune(c)
+			}
+		case c == '\'' || c == '"':
+			quote = c
+			inWord = true
+		case c == ' ' || c == '\t' || c == '\n':
+			if inWord {
+				argv = append(argv, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(c)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote in %v", quote, cmdStr)
+	} else if escaped {
+		return nil, fmt.Errorf("trailing backslash in %v", cmdStr)
+	}
+	if inWord {
+		argv = append(argv, word.String())
+	}
+	if len(argv) == 0 {
+		return nil, fmt.Errorf("empty command")
+	}
+
+	return argv, nil
+}
+
+// runDeployCompletionHook runs the --on-success or --on-failure hook which
+// corresponds to deployDesc's terminal state, if any; a hook which exits
+// non-zero is reported as an error including its exit code
+func runDeployCompletionHook(deployDesc *pb.DeploymentDescription) error {
+	hookName, cmdStr := "--on-success", deployCompletionHooks.onSuccess
+	if deployDesc.State != pb.DeploymentState_SUCCESS {
+		hookName, cmdStr = "--on-failure", deployCompletionHooks.onFailure
+	}
+	if cmdStr == "" {
+		return nil
+	}
+	argv, err := splitHookCommand(cmdStr)
+	if err != nil {
+		return fmt.Errorf("Invalid %v hook: %w", hookName, err)
+	}
+
+	fmt.Printf("Running %v hook: %v\n", hookName, cmdStr)
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(),
+		"BOPMATIC_DEPLOY_ID="+deployDesc.Id,
+		"BOPMATIC_DEPLOY_STATE="+deployDesc.State.String(),
+		"BOPMATIC_DEPLOY_STATE_DETAIL="+deployDesc.StateDetail.String(),
+		"BOPMATIC_DEPLOY_PROJECT_ID="+deployDesc.Header.ProjId,
+		"BOPMATIC_DEPLOY_PACKAGE_ID="+deployDesc.Header.PkgId)
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return fmt.Errorf("%v hook exited with status %v", hookName,
+			exitErr.ExitCode())
+	} else if err != nil {
+		return fmt.Errorf("Could not run %v hook: %w", hookName, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitHookCommand(t *testing.T) {
+	tests := []struct {
+		cmdStr   string
+		expected []string
+	}{
+		{"notify", []string{"notify"}},
+		{"  notify   --channel  ops ", []string{"notify", "--channel", "ops"}},
+		{"notify\t--channel\nops", []string{"notify", "--channel", "ops"}},
+		{`notify "deploy done"`, []string{"notify", "deploy done"}},
+		{`notify 'deploy done'`, []string{"notify", "deploy done"}},
+		{`notify pre"fix suf"fix`, []string{"notify", "prefix suffix"}},
+		{`notify "it's done"`, []string{"notify", "it's done"}},
+		{`notify 'say "hi"'`, []string{"notify", `say "hi"`}},
+		{`notify ""`, []string{"notify", ""}},
+		{`notify deploy\ done`, []string{"notify", "deploy done"}},
+		{`notify "a \"quoted\" word"`, []string{"notify", `a "quoted" word`}},
+		// backslashes are literal within single quotes
+		{`notify 'C:\path'`, []string{"notify", `C:\path`}},
+		// no expansion, substitution, or command chaining
+		{`notify $HOME; rm -rf /`, []string{"notify", "$HOME;", "rm", "-rf",
+			"/"}},
+	}
+
+	for _, test := range tests {
+		argv, err := splitHookCommand(test.cmdStr)
+		if err != nil {
+			t.Errorf("splitHookCommand(%q): unexpected error: %v", test.cmdStr,
+				err)
+			continue
+		}
+		if !reflect.DeepEqual(argv, test.expected) {
+			t.Errorf("splitHookCommand(%q): expected %q, got %q", test.cmdStr,
+				test.expected, argv)
+		}
+	}
+}
+
+func TestSplitHookCommandErrors(t *testing.T) {
+	for _, cmdStr := range []string{"", "   ", `notify "unterminated`,
+		`notify 'unterminated`, `notify trailing\`} {
+
+		_, err := splitHookCommand(cmdStr)
+		if err == nil {
+			t.Errorf("splitHookCommand(%q): expected an error", cmdStr)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+/* Copyright © 2024 Bopmatic, LLC. All Rights Reserved.
+ *
+ * See LICENSE file at the root of this package for license terms
+ */
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// deployNote is a human annotation attached to a deployment via
+// 'bopmatic package deploy --note'
+type deployNote struct {
+	Note    string    `json:"note"`
+	Created time.Time `json:"created"`
+}
+
+// deployNotes maps deploy ids to their note. ServiceRunner has no way to
+// accept a note at deploy time through the sdk, so notes are kept locally.
+// @todo send notes as the deployment header's reason once the sdk supports
+// setting it
+type deployNotes map[string]deployNote
+
+func getDeployNotesPath() (string, error) {
+	configPath, err := getConfigPath()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(configPath, "deploynotes.json"), nil
+}
+
+func loadDeployNotes() (deployNotes, error) {
+	notes := make(deployNotes)
+
+	notesPath, err := getDeployNotesPath()
+	if err != nil {
+		return notes, err
+	}
+	notesData, err := os.ReadFile(notesPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return notes, nil
+	} else if err != nil {
+		return notes, fmt.Errorf("Could not read %v: %w", notesPath, err)
+	}
+
+	err = json.Unmarshal(notesData, &notes)
+	if err != nil {
+		return make(deployNotes), fmt.Errorf("Could not parse %v: %w",
+			notesPath, err)
+	}
+
+	return notes, nil
+}
+
+func saveDeployNotes(notes deployNotes) error {
+	notesPath, err := getDeployNotesPath()
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(notesPath), 0700)
+	if err != nil {
+		return fmt.Errorf("Could not create config directory %v: %w",
+			filepath.Dir(notesPath), err)
+	}
+
+	notesData, err := json.MarshalIndent(notes, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = writeFileAtomic(notesPath, append(notesData, '\n'), 0600)
+	if err != nil {
+		return fmt.Errorf("Could not write %v: %w", notesPath, err)
+	}
+
+	return nil
+}
+
+// setDeployNote records note against deployId
+func setDeployNote(deployId string, note string) error {
+	return setDeployNotes([]string{deployId}, note)
+}
+
+// setDeployNotes records note against each of deployIds in a single write
+// while holding the config lock so that concurrent deploys don't drop each
+// other's notes
+func setDeployNotes(deployIds []string, note string) error {
+	release, err := lockConfig()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	notes, err := loadDeployNotes()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, deployId := range deployIds {
+		notes[deployId] = deployNote{
+			Note:    note,
+			Created: now,
+		}
+	}
+
+	return saveDeployNotes(notes)
+}
+
+// getDeployNote returns the note recorded against deployId, if any
+func getDeployNote(deployId string) string {
+	notes, err := loadDeployNotes()
+	if err != nil {
+		logDebug("ignoring deploy notes: %v", err)
+		return ""
+	}
+
+	return notes[deployId].Note
+}
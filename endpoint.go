@@ -0,0 +1,204 @@
+/* Copyright © 2024 Bopmatic, LLC. All Rights Reserved.
+ *
+ * See LICENSE file at the root of this package for license terms
+ */
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bopmatic/sdk/golang/goswag"
+)
+
+const ApiEndpointEnvVar = "BOPMATIC_API_ENDPOINT"
+
+// set via --api-endpoint; takes precedence over ApiEndpointEnvVar and the
+// config file
+var apiEndpointOverride = ""
+
+type apiEndpointFlag struct{}
+
+func (a *apiEndpointFlag) String() string {
+	return apiEndpointOverride
+}
+
+func (a *apiEndpointFlag) Set(val string) error {
+	_, err := parseApiEndpoint(val)
+	if err != nil {
+		return err
+	}
+	apiEndpointOverride = val
+
+	return nil
+}
+
+func parseApiEndpoint(endpoint string) (*url.URL, error) {
+	endpointUrl, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid api endpoint %v: %w", endpoint, err)
+	}
+	if endpointUrl.Scheme != "http" && endpointUrl.Scheme != "https" {
+		return nil, fmt.Errorf("Invalid api endpoint %v: must be an http:// or https:// url",
+			endpoint)
+	}
+	if endpointUrl.Host == "" {
+		return nil, fmt.Errorf("Invalid api endpoint %v: missing host",
+			endpoint)
+	}
+
+	return endpointUrl, nil
+}
+
+// getApiEndpoint returns the ServiceRunner endpoint to use in place of the
+// sdk's default, or nil when there is no override
+func getApiEndpoint() (*url.URL, error) {
+	endpoint := apiEndpointOverride
+	if endpoint == "" {
+		endpoint = os.Getenv(ApiEndpointEnvVar)
+	}
+	if endpoint == "" {
+		cfg, err := loadConfig()
+		if err != nil {
+			return nil, err
+		}
+		endpoint = cfg.ApiEndpoint
+	}
+	if endpoint == "" {
+		return nil, nil
+	}
+
+	return parseApiEndpoint(endpoint)
+}
+
+// getApiEndpointString returns the ServiceRunner endpoint requests are sent
+// to, whether overridden or the sdk's default
+func getApiEndpointString() string {
+	endpoint, err := getApiEndpoint()
+	if err == nil && endpoint != nil {
+		return endpoint.String()
+	}
+
+	return goswag.DefaultSchemes[0] + "://" + goswag.DefaultHost +
+		goswag.DefaultBasePath
+}
+
+// endpointRewriter redirects requests destined for the sdk's default
+// ServiceRunner endpoint to an alternate one
+type endpointRewriter struct {
+	endpoint *url.URL
+	base     http.RoundTripper
+}
+
+func (r *endpointRewriter) RoundTrip(req *http.Request) (*http.Response,
+	error) {
+
+	req = req.Clone(req.Context())
+	req.URL.Scheme = r.endpoint.Scheme
+	req.URL.Host = r.endpoint.Host
+	req.URL.Path = strings.TrimRight(r.endpoint.Path, "/") + req.URL.Path
+	req.Host = r.endpoint.Host
+
+	return r.base.RoundTrip(req)
+}
+
+// default timeout of each ServiceRunner request
+const DefaultSrHttpTimeout = 30 * time.Second
+
+// newSrHttpClient returns the http client used for all ServiceRunner
+// requests. The sdk doesn't support configuring its endpoint so an
+// override is applied by rewriting each request.
+// @todo replace with an sdk DeployOption once one exists
+func newSrHttpClient() *http.Client {
+	return newSrHttpClientWithTimeout(DefaultSrHttpTimeout)
+}
+
+// newSrHttpClientWithTimeout is newSrHttpClient for requests which need
+// longer than DefaultSrHttpTimeout, e.g. uploading a large package
+func newSrHttpClientWithTimeout(timeout time.Duration) *http.Client {
+	httpClient := &http.Client{
+		Timeout: timeout,
+	}
+
+	endpoint, err := getApiEndpoint()
+	if err != nil {
+		exitWithError(ExitUsage, "%v\n", err)
+	}
+	if endpoint != nil {
+		logDebug("using api endpoint %v", endpoint)
+		httpClient.Transport = &endpointRewriter{
+			endpoint: endpoint,
+			base:     http.DefaultTransport,
+		}
+	}
+
+	return httpClient
+}
+
+// isHttpTimeout reports whether err is the result of an http client
+// timeout. The sdk flattens the errors it returns into strings so the
+// message is checked as well.
+func isHttpTimeout(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	return errors.Is(err, context.DeadlineExceeded) ||
+		strings.Contains(err.Error(), "Client.Timeout exceeded")
+}
+
+// configEndpointMain shows, sets, or clears the persisted api endpoint
+func configEndpointMain(args []string) {
+	f := flag.NewFlagSet("bopmatic config endpoint", flag.ExitOnError)
+	setGlobalFlags(f)
+	err := f.Parse(args)
+	if err != nil {
+		exitWithError(ExitUsage, "%v\n", err)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		exitWithError(ExitFailure, "%v\n", err)
+	}
+
+	if f.NArg() == 0 {
+		if cfg.ApiEndpoint == "" {
+			fmt.Printf("Using the default api endpoint\n")
+		} else {
+			fmt.Printf("%v\n", cfg.ApiEndpoint)
+		}
+		return
+	}
+
+	endpoint := f.Arg(0)
+	if endpoint == "default" {
+		endpoint = ""
+	} else {
+		_, err = parseApiEndpoint(endpoint)
+		if err != nil {
+			exitWithError(ExitUsage, "%v\n", err)
+		}
+	}
+	err = updateConfig(func(cfg *cliConfig) error {
+		cfg.ApiEndpoint = endpoint
+		return nil
+	})
+	if err != nil {
+		exitWithError(ExitFailure, "%v\n", err)
+	}
+
+	if endpoint == "" {
+		fmt.Printf("Api endpoint reset to the default\n")
+	} else {
+		fmt.Printf("Api endpoint set to %v\n", endpoint)
+	}
+}
@@ -0,0 +1,255 @@
+/* Copyright © 2024 Bopmatic, LLC. All Rights Reserved.
+ *
+ * See LICENSE file at the root of this package for license terms
+ */
+package main
+
+import (
+	_ "embed"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/bopmatic/sdk/golang/goswag"
+	"github.com/bopmatic/sdk/golang/goswag/service_runner"
+	"github.com/bopmatic/sdk/golang/models"
+)
+
+var envSubCommandTab = map[string]func(args []string){
+	"list":     envListMain,
+	"describe": envDescribeMain,
+	"help":     envHelpMain,
+}
+
+//go:embed envHelp.txt
+var envHelpText string
+
+func envHelpMain(args []string) {
+	fmt.Printf(envHelpText)
+}
+
+func envMain(args []string) {
+	exitStatus := 0
+
+	envSubCommandName := "help"
+	if len(args) == 0 {
+		exitStatus = 1
+	} else {
+		envSubCommandName = args[0]
+	}
+
+	envSubCommand, ok := envSubCommandTab[envSubCommandName]
+	if !ok {
+		exitStatus = 1
+		envSubCommand = envHelpMain
+	}
+
+	if len(args) > 0 {
+		args = args[1:]
+	}
+
+	envSubCommand(args)
+
+	os.Exit(exitStatus)
+}
+
+type envOutput struct {
+	EnvId            string   `json:"envId"`
+	Name             string   `json:"name"`
+	DnsPrefix        string   `json:"dnsPrefix"`
+	CreateTime       string   `json:"createTime"`
+	ActiveDeployIds  []string `json:"activeDeployIds"`
+	PendingDeployIds []string `json:"pendingDeployIds"`
+}
+
+func newEnvOutput(envDesc *models.EnvironmentDescription) envOutput {
+	envOut := envOutput{
+		EnvId:            envDesc.ID,
+		ActiveDeployIds:  envDesc.ActiveDeployIds,
+		PendingDeployIds: envDesc.PendingDeployIds,
+	}
+	if envDesc.Header != nil {
+		envOut.Name = envDesc.Header.Name
+		envOut.DnsPrefix = envDesc.Header.DNSPrefix
+	}
+	createTime, err := strconv.ParseUint(envDesc.CreateTime, 10, 64)
+	if err == nil {
+		envOut.CreateTime = formatTimestamp(createTime)
+	}
+	if envOut.ActiveDeployIds == nil {
+		envOut.ActiveDeployIds = []string{}
+	}
+	if envOut.PendingDeployIds == nil {
+		envOut.PendingDeployIds = []string{}
+	}
+
+	return envOut
+}
+
+// listEnvironments returns the ids of every environment visible to the
+// configured api key
+// @todo move into the sdk alongside the other ServiceRunner wrappers
+func listEnvironments() ([]string, error) {
+	authInfo, err := getSrAuthInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	httpClient := newSrHttpClient()
+	listEnvsParams := service_runner.NewListEnvironmentsParams().
+		WithBody(struct{}{}).WithHTTPClient(httpClient)
+	client := goswag.NewHTTPClientWithConfig(nil,
+		goswag.DefaultTransportConfig())
+
+	resp, err := client.ServiceRunner.ListEnvironments(listEnvsParams,
+		authInfo)
+	if err != nil {
+		return nil, fmt.Errorf("Client/HTTP failure: %v", err)
+	}
+	listEnvsReply := resp.GetPayload()
+	if listEnvsReply.Result != nil &&
+		listEnvsReply.Result.Status != nil &&
+		*listEnvsReply.Result.Status != models.ServiceRunnerStatusSTATUSOK {
+		return nil, fmt.Errorf("ListEnvironments failure(%v): %v",
+			*listEnvsReply.Result.Status, listEnvsReply.Result.StatusDetail)
+	}
+
+	return listEnvsReply.Ids, nil
+}
+
+// describeEnvironment returns envId's name, dns prefix, and deployments
+// @todo move into the sdk alongside the other ServiceRunner wrappers
+func describeEnvironment(envId string) (*models.EnvironmentDescription,
+	error) {
+
+	authInfo, err := getSrAuthInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	describeEnvReq := &models.DescribeEnvironmentRequest{
+		ID: envId,
+	}
+	httpClient := newSrHttpClient()
+	describeEnvParams := service_runner.NewDescribeEnvironmentParams().
+		WithBody(describeEnvReq).WithHTTPClient(httpClient)
+	client := goswag.NewHTTPClientWithConfig(nil,
+		goswag.DefaultTransportConfig())
+
+	resp, err := client.ServiceRunner.DescribeEnvironment(describeEnvParams,
+		authInfo)
+	if err != nil {
+		return nil, fmt.Errorf("Client/HTTP failure: %v", err)
+	}
+	describeEnvReply := resp.GetPayload()
+	if describeEnvReply.Result != nil &&
+		describeEnvReply.Result.Status != nil &&
+		*describeEnvReply.Result.Status != models.ServiceRunnerStatusSTATUSOK {
+		return nil, fmt.Errorf("DescribeEnvironment failure(%v): %v",
+			*describeEnvReply.Result.Status,
+			describeEnvReply.Result.StatusDetail)
+	}
+	if describeEnvReply.Desc == nil {
+		return nil, fmt.Errorf("Environment %v was not found", envId)
+	}
+
+	return describeEnvReply.Desc, nil
+}
+
+func envListMain(args []string) {
+	f := flag.NewFlagSet("bopmatic env list", flag.ExitOnError)
+	setGlobalFlags(f)
+	err := f.Parse(args)
+	if err != nil {
+		exitWithError(ExitUsage, "%v\n", err)
+	}
+
+	if outputFormat == OutputText {
+		fmt.Printf("Listing environments...")
+	}
+	envIds, err := listEnvironments()
+	if err != nil {
+		exitWithError(ExitServer, "%v\n", err)
+	}
+
+	envList := make([]envOutput, len(envIds))
+	wg := newFanOutGroup()
+	for idx, envId := range envIds {
+		wg.Go(func() error {
+			envDesc, err := describeEnvironment(envId)
+			if err != nil {
+				return fmt.Errorf("%v: %w", envId, err)
+			}
+			envList[idx] = newEnvOutput(envDesc)
+			return nil
+		})
+	}
+	err = wg.Wait()
+	if err != nil {
+		exitWithError(ExitServer, "%v\n", err)
+	}
+
+	if outputFormat != OutputText {
+		err = printStructured(envList)
+		if err != nil {
+			exitWithError(ExitFailure, "Failed to render environments: %v\n",
+				err)
+		}
+		return
+	}
+
+	if len(envList) == 0 {
+		fmt.Printf("\nNo environments exist\n")
+		return
+	}
+	fmt.Printf("\n%-24v%-16v%v\n", "Environment Id", "Name",
+		"Active Deployments")
+	fmt.Printf("%-24v%-16v%v\n", "--------------", "----",
+		"------------------")
+	for _, envOut := range envList {
+		fmt.Printf("%-24v%-16v%v\n", envOut.EnvId, envOut.Name,
+			strings.Join(envOut.ActiveDeployIds, ","))
+	}
+}
+
+func envDescribeMain(args []string) {
+	var envId string
+
+	f := flag.NewFlagSet("bopmatic env describe", flag.ExitOnError)
+	setGlobalFlags(f)
+	f.StringVar(&envId, "envid", "", "Bopmatic environment id")
+	err := f.Parse(args)
+	if err != nil {
+		exitWithError(ExitUsage, "%v\n", err)
+	}
+	if envId == "" {
+		exitWithError(ExitUsage, "Please specify the environment to describe with --envid; 'bopmatic env list' lists them\n")
+	}
+
+	if outputFormat == OutputText {
+		fmt.Printf("Describing envId:%v...", envId)
+	}
+	envDesc, err := describeEnvironment(envId)
+	if err != nil {
+		exitWithError(ExitServer, "%v\n", err)
+	}
+	envOut := newEnvOutput(envDesc)
+
+	if outputFormat != OutputText {
+		err = printStructured(&envOut)
+		if err != nil {
+			exitWithError(ExitFailure, "Failed to render environment: %v\n",
+				err)
+		}
+		return
+	}
+
+	fmt.Printf("\nEnvironment %v:\n\tName: %v\n\tDnsPrefix: %v\n\tCreated: %v\n",
+		envOut.EnvId, envOut.Name, envOut.DnsPrefix, envOut.CreateTime)
+	fmt.Printf("\tActiveDeployments: %v\n",
+		strings.Join(envOut.ActiveDeployIds, ", "))
+	fmt.Printf("\tPendingDeployments: %v\n",
+		strings.Join(envOut.PendingDeployIds, ", "))
+}
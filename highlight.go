@@ -0,0 +1,122 @@
+/* Copyright © 2024 Bopmatic, LLC. All Rights Reserved.
+ *
+ * See LICENSE file at the root of this package for license terms
+ */
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+const NoColorEnvVar = "NO_COLOR"
+
+// ANSI colors assigned to successive --highlight patterns
+var highlightColors = []string{
+	"\x1b[1;33m", // bold yellow
+	"\x1b[1;36m", // bold cyan
+	"\x1b[1;35m", // bold magenta
+	"\x1b[1;32m", // bold green
+	"\x1b[1;31m", // bold red
+	"\x1b[1;34m", // bold blue
+}
+
+const colorReset = "\x1b[0m"
+
+// highlighter colorizes the substrings of log lines which match any of its
+// patterns
+type highlighter struct {
+	patterns []*regexp.Regexp
+}
+
+// set by 'bopmatic logs --highlight'; nil when highlighting is disabled
+var logHighlighter *highlighter
+
+// stdoutIsTerminal reports whether stdout is attached to a terminal
+func stdoutIsTerminal() bool {
+	stat, err := os.Stdout.Stat()
+	if err != nil {
+		return false
+	}
+
+	return stat.Mode()&os.ModeCharDevice != 0
+}
+
+// colorEnabled reports whether output may include ANSI colors: stdout must
+// be a terminal and neither --no-color nor NO_COLOR may be set
+func colorEnabled(noColor bool) bool {
+	if noColor {
+		return false
+	}
+	_, isSet := os.LookupEnv(NoColorEnvVar)
+	if isSet {
+		return false
+	}
+
+	return stdoutIsTerminal()
+}
+
+// newHighlighter compiles patterns; it returns nil when there is nothing to
+// highlight or color is disabled, in which case lines are printed as is
+func newHighlighter(patterns []string, noColor bool) (*highlighter, error) {
+	hl := &highlighter{}
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid --highlight %v: %w", pattern, err)
+		}
+		hl.patterns = append(hl.patterns, re)
+	}
+	if len(hl.patterns) == 0 || !colorEnabled(noColor) {
+		return nil, nil
+	}
+
+	return hl, nil
+}
+
+// apply returns line with each match wrapped in its pattern's color; where
+// matches overlap the earlier --highlight pattern wins
+func (hl *highlighter) apply(line string) string {
+	if hl == nil {
+		return line
+	}
+
+	// pattern index + 1 covering each byte of line; 0 is uncolored
+	owners := make([]int, len(line))
+	matched := false
+	for idx, re := range hl.patterns {
+		for _, loc := range re.FindAllStringIndex(line, -1) {
+			for pos := loc[0]; pos < loc[1]; pos++ {
+				if owners[pos] == 0 {
+					owners[pos] = idx + 1
+					matched = true
+				}
+			}
+		}
+	}
+	if !matched {
+		return line
+	}
+
+	var sb strings.Builder
+	current := 0
+	for pos := 0; pos < len(line); pos++ {
+		if owners[pos] != current {
+			if current != 0 {
+				sb.WriteString(colorReset)
+			}
+			if owners[pos] != 0 {
+				sb.WriteString(highlightColors[(owners[pos]-1)%len(highlightColors)])
+			}
+			current = owners[pos]
+		}
+		sb.WriteByte(line[pos])
+	}
+	if current != 0 {
+		sb.WriteString(colorReset)
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,168 @@
+/* Copyright © 2024 Bopmatic, LLC. All Rights Reserved.
+ *
+ * See LICENSE file at the root of this package for license terms
+ */
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// ANSI colors for the parts of a --json-pretty log line
+const (
+	jsonKeyColor     = "\x1b[36m" // cyan
+	jsonStringColor  = "\x1b[32m" // green
+	jsonLiteralColor = "\x1b[33m" // yellow: numbers, true, false & null
+)
+
+// fields which, when present in a json log line, are summarized ahead of the
+// indented object; the first of each list found is used
+var (
+	jsonLevelKeys = []string{"level", "lvl", "severity"}
+	jsonMsgKeys   = []string{"msg", "message"}
+)
+
+// colors for well known --json-pretty log levels
+var jsonLevelColors = map[string]string{
+	"debug":   "\x1b[34m",   // blue
+	"info":    "\x1b[32m",   // green
+	"warn":    "\x1b[1;33m", // bold yellow
+	"warning": "\x1b[1;33m",
+	"error":   "\x1b[1;31m", // bold red
+	"fatal":   "\x1b[1;31m",
+	"panic":   "\x1b[1;31m",
+}
+
+// jsonPrettifier re-indents log lines whose message is a json object
+type jsonPrettifier struct {
+	color bool
+}
+
+// set by 'bopmatic logs --json-pretty'; nil when disabled
+var logJsonPrettifier *jsonPrettifier
+
+func newJsonPrettifier(noColor bool) *jsonPrettifier {
+	return &jsonPrettifier{
+		color: colorEnabled(noColor),
+	}
+}
+
+// formatLogLine applies --json-pretty and then --highlight to a log line
+func formatLogLine(line string) string {
+	return logHighlighter.apply(logJsonPrettifier.apply(line))
+}
+
+// apply returns line with a json object message re-indented beneath a
+// '<time>: <LEVEL> <msg>' summary; lines whose message isn't a json object
+// are returned unchanged
+func (jp *jsonPrettifier) apply(line string) string {
+	if jp == nil {
+		return line
+	}
+
+	// each line is formatted by the sdk as '<time>: <message>'
+	prefix, msg, found := strings.Cut(line, ": ")
+	if !found {
+		prefix, msg = "", line
+	}
+	msg = strings.TrimSpace(msg)
+	if !strings.HasPrefix(msg, "{") || !json.Valid([]byte(msg)) {
+		return line
+	}
+	var fields map[string]any
+	err := json.Unmarshal([]byte(msg), &fields)
+	if err != nil {
+		return line
+	}
+	var indented bytes.Buffer
+	err = json.Indent(&indented, []byte(msg), "    ", "  ")
+	if err != nil {
+		return line
+	}
+
+	var sb strings.Builder
+	if prefix != "" {
+		sb.WriteString(prefix)
+		sb.WriteString(":")
+	}
+	level := firstStringField(fields, jsonLevelKeys)
+	if level != "" {
+		sb.WriteString(" ")
+		sb.WriteString(jp.colorize(jsonLevelColors[strings.ToLower(level)],
+			strings.ToUpper(level)))
+	}
+	logMsg := firstStringField(fields, jsonMsgKeys)
+	if logMsg != "" {
+		sb.WriteString(" ")
+		sb.WriteString(logMsg)
+	}
+	sb.WriteString("\n    ")
+	sb.WriteString(jp.highlightJson(indented.String()))
+
+	return strings.TrimLeft(sb.String(), " ")
+}
+
+func firstStringField(fields map[string]any, keys []string) string {
+	for _, key := range keys {
+		val, ok := fields[key].(string)
+		if ok {
+			return val
+		}
+	}
+
+	return ""
+}
+
+func (jp *jsonPrettifier) colorize(color string, text string) string {
+	if !jp.color || color == "" {
+		return text
+	}
+
+	return color + text + colorReset
+}
+
+// highlightJson colors the keys, strings, and literals of indented, which
+// must be valid json
+func (jp *jsonPrettifier) highlightJson(indented string) string {
+	if !jp.color {
+		return indented
+	}
+
+	var sb strings.Builder
+	for pos := 0; pos < len(indented); {
+		c := indented[pos]
+		switch {
+		case c == '"':
+			end := pos + 1
+			for end < len(indented) && indented[end] != '"' {
+				if indented[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			end++
+			color := jsonStringColor
+			if strings.HasPrefix(strings.TrimLeft(indented[end:], " "), ":") {
+				color = jsonKeyColor
+			}
+			sb.WriteString(jp.colorize(color, indented[pos:end]))
+			pos = end
+		case c == '-' || (c >= '0' && c <= '9') || c == 't' || c == 'f' ||
+			c == 'n':
+			end := pos
+			for end < len(indented) &&
+				!strings.ContainsRune(",]} \n", rune(indented[end])) {
+				end++
+			}
+			sb.WriteString(jp.colorize(jsonLiteralColor, indented[pos:end]))
+			pos = end
+		default:
+			sb.WriteByte(c)
+			pos++
+		}
+	}
+
+	return sb.String()
+}
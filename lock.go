@@ -0,0 +1,112 @@
+/* Copyright © 2024 Bopmatic, LLC. All Rights Reserved.
+ *
+ * See LICENSE file at the root of this package for license terms
+ */
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// set via --force-unlock on mutating commands
+var forceUnlock = false
+
+func setLockFlags(f *flag.FlagSet) {
+	f.BoolVar(&forceUnlock, "force-unlock", false,
+		"Remove a stale project lock left behind by an interrupted bopmatic operation")
+}
+
+// projectLock is the content of a project's lockfile; it is informational
+// only and lets a contended operation report who holds the lock
+type projectLock struct {
+	Pid     int       `json:"pid"`
+	Command string    `json:"command"`
+	Started time.Time `json:"started"`
+}
+
+func getProjectLockPath(projId string) (string, error) {
+	configPath, err := getConfigPath()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(configPath, "locks", projId+".lock"), nil
+}
+
+// acquireProjectLock takes the advisory lock serializing mutating operations
+// on projId. The returned release func should be deferred; the lock is also
+// released when the CLI exits via exitWithError().
+func acquireProjectLock(projId string) (func(), error) {
+	lockPath, err := getProjectLockPath(projId)
+	if err != nil {
+		return nil, err
+	}
+	err = os.MkdirAll(filepath.Dir(lockPath), 0700)
+	if err != nil {
+		return nil, fmt.Errorf("Could not create lock directory %v: %w",
+			filepath.Dir(lockPath), err)
+	}
+
+	if forceUnlock {
+		err = os.Remove(lockPath)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("Could not remove %v: %w", lockPath, err)
+		}
+	}
+
+	lockFile, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY,
+		0600)
+	if errors.Is(err, fs.ErrExist) {
+		holder := ""
+		var lock projectLock
+		lockData, readErr := os.ReadFile(lockPath)
+		if readErr == nil && json.Unmarshal(lockData, &lock) == nil {
+			holder = fmt.Sprintf(" ('%v' pid %v started %v)", lock.Command,
+				lock.Pid, lock.Started.Local().Format(time.RFC1123))
+		}
+		return nil, fmt.Errorf("Another bopmatic operation is in progress for projId:%v%v. If it is no longer running, retry with --force-unlock",
+			projId, holder)
+	} else if err != nil {
+		return nil, fmt.Errorf("Could not create %v: %w", lockPath, err)
+	}
+	lock := projectLock{
+		Pid:     os.Getpid(),
+		Command: "bopmatic " + strings.Join(os.Args[1:], " "),
+		Started: time.Now(),
+	}
+	_ = json.NewEncoder(lockFile).Encode(&lock)
+	lockFile.Close()
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			err := os.Remove(lockPath)
+			if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				logWarn("Could not remove project lock %v: %v", lockPath, err)
+			}
+		})
+	}
+	atExit(release)
+
+	return release, nil
+}
+
+// lockProjectOrExit is acquireProjectLock() for commands which can't
+// proceed without the lock
+func lockProjectOrExit(projId string) func() {
+	release, err := acquireProjectLock(projId)
+	if err != nil {
+		exitWithError(ExitFailure, "%v\n", err)
+	}
+
+	return release
+}
@@ -0,0 +1,69 @@
+/* Copyright © 2024 Bopmatic, LLC. All Rights Reserved.
+ *
+ * See LICENSE file at the root of this package for license terms
+ */
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	LogLevelError = iota
+	LogLevelWarn
+	LogLevelInfo
+	LogLevelDebug
+)
+
+var logLevelNames = []string{"error", "warn", "info", "debug"}
+
+var logLevel = LogLevelWarn
+
+type logLevelFlag struct{}
+
+func (l *logLevelFlag) String() string {
+	return logLevelNames[logLevel]
+}
+
+func (l *logLevelFlag) Set(val string) error {
+	for level, name := range logLevelNames {
+		if strings.EqualFold(val, name) {
+			logLevel = level
+			return nil
+		}
+	}
+
+	return fmt.Errorf("invalid log level %v; must be one of %v", val,
+		logLevelNames)
+}
+
+func logEnabled(level int) bool {
+	return level <= logLevel
+}
+
+func logAt(level int, prefix string, format string, a ...any) {
+	if !logEnabled(level) {
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "%v%v\n", prefix,
+		maskSecrets(strings.TrimRight(fmt.Sprintf(format, a...), "\n")))
+}
+
+func logError(format string, a ...any) {
+	logAt(LogLevelError, "", format, a...)
+}
+
+func logWarn(format string, a ...any) {
+	logAt(LogLevelWarn, "*WARN*: ", format, a...)
+}
+
+func logInfo(format string, a ...any) {
+	logAt(LogLevelInfo, "", format, a...)
+}
+
+func logDebug(format string, a ...any) {
+	logAt(LogLevelDebug, "*DEBUG*: ", format, a...)
+}
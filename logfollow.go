@@ -0,0 +1,176 @@
+/* Copyright © 2024 Bopmatic, LLC. All Rights Reserved.
+ *
+ * See LICENSE file at the root of this package for license terms
+ */
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+
+	bopsdk "github.com/bopmatic/sdk/golang"
+)
+
+// how often 'bopmatic logs --follow' polls ServiceRunner for new log lines
+const logFollowInterval = DefaultPollInterval
+
+// svcLogKey identifies a printed log line. Lines without a timestamp take
+// the time of the line before them, and repeats of the same line at the
+// same time are told apart by their order.
+type svcLogKey struct {
+	unixNano   int64
+	line       string
+	occurrence int
+}
+
+// svcLogCursor tracks how far a followed service's logs have been printed.
+// Each poll re-requests logs from the last printed timestamp so that lines
+// which arrive late for that timestamp aren't missed; lines already printed
+// at that timestamp are skipped.
+type svcLogCursor struct {
+	svcName string
+	start   time.Time
+	// lines at start which have already been printed
+	seen map[svcLogKey]bool
+}
+
+// advance returns the lines in logLines which haven't been printed yet and
+// moves the cursor past them
+func (cursor *svcLogCursor) advance(logLines []svcLogLine) []svcLogLine {
+	newLines := make([]svcLogLine, 0, len(logLines))
+	lineTime := cursor.start
+	occurrences := make(map[svcLogKey]int)
+	for _, logLine := range logLines {
+		if !logLine.time.IsZero() {
+			lineTime = logLine.time
+		}
+		if lineTime.After(cursor.start) {
+			cursor.start = lineTime
+		}
+		key := svcLogKey{unixNano: lineTime.UnixNano(), line: logLine.line}
+		key.occurrence = occurrences[key]
+		occurrences[svcLogKey{unixNano: key.unixNano, line: key.line}]++
+		if cursor.seen[key] {
+			continue
+		}
+		cursor.seen[key] = true
+		newLines = append(newLines, logLine)
+	}
+
+	// the next poll starts at cursor.start so only lines at that time can be
+	// retrieved again
+	startNano := cursor.start.UnixNano()
+	for key := range cursor.seen {
+		if key.unixNano != startNano {
+			delete(cursor.seen, key)
+		}
+	}
+
+	return newLines
+}
+
+// followLogs prints svcNames' logs from startTime onward and continues to
+// print new lines as they're emitted until interrupted. With --output json
+// each line is written as a newline-delimited json object (see logSinkEntry)
+// and flushed immediately so that downstream consumers see it in real time.
+func followLogs(projId string, svcNames []string, startTime time.Time,
+	prefixSvcName bool, sdkOpts []bopsdk.DeployOption) error {
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	out := bufio.NewWriter(os.Stdout)
+	enc := json.NewEncoder(out)
+	printLine := func(logLine svcLogLine) error {
+		var err error
+		if outputFormat == OutputJson {
+			err = enc.Encode(newLogSinkEntry(logLine))
+		} else if prefixSvcName {
+			_, err = fmt.Fprintf(out, "[%v] %v\n", logLine.svcName,
+				formatLogLine(logLine.line))
+		} else {
+			_, err = fmt.Fprintf(out, "%v\n", formatLogLine(logLine.line))
+		}
+		if err != nil {
+			return err
+		}
+
+		return out.Flush()
+	}
+
+	cursors := make([]*svcLogCursor, 0, len(svcNames))
+	for _, svcName := range svcNames {
+		cursors = append(cursors, &svcLogCursor{
+			svcName: svcName,
+			start:   startTime,
+			seen:    make(map[svcLogKey]bool),
+		})
+	}
+
+	ticker := time.NewTicker(logFollowInterval)
+	defer ticker.Stop()
+	for {
+		endTime := time.Now().UTC()
+		for _, cursor := range cursors {
+			logLines, err := fetchCompleteSvcLogLines(projId, cursor.svcName,
+				cursor.start, endTime, sdkOpts)
+			if err != nil {
+				logWarn("%v; retrying in %v", err, logFollowInterval)
+				continue
+			}
+			for _, logLine := range cursor.advance(logLines) {
+				err = printLine(logLine)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// fetchCompleteSvcLogLines retrieves a single service's logs like
+// fetchSvcLogLines but discards a trailing partial line; the cursor isn't
+// advanced past it so it is retrieved whole on the next poll
+func fetchCompleteSvcLogLines(projId string, svcName string,
+	startTime time.Time, endTime time.Time,
+	sdkOpts []bopsdk.DeployOption) ([]svcLogLine, error) {
+
+	var logBuf bytes.Buffer
+	svcSdkOpts := append(append([]bopsdk.DeployOption{}, sdkOpts...),
+		bopsdk.DeployOptOutput(&logBuf))
+	// @todo specify environment id
+	err := bopsdk.GetLogs(projId, "", svcName, startTime, endTime,
+		svcSdkOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", svcName, err)
+	}
+
+	logLines := make([]svcLogLine, 0)
+	reader := bufio.NewReader(&logBuf)
+	for {
+		line, err := reader.ReadString('\n')
+		if err == io.EOF {
+			if line != "" {
+				logDebug("%v: deferring partial log line to next poll", svcName)
+			}
+			break
+		}
+		logLines = append(logLines, parseSvcLogLine(svcName,
+			line[:len(line)-1]))
+	}
+
+	return logLines, nil
+}
@@ -5,18 +5,23 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"errors"
 	"flag"
 	"fmt"
-	"io/fs"
+	"io"
 	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	_ "embed"
 
-	"github.com/araddon/dateparse"
-
 	bopsdk "github.com/bopmatic/sdk/golang"
+	"github.com/bopmatic/sdk/golang/pb"
 )
 
 //go:embed logsHelp.txt
@@ -25,44 +30,156 @@ var logsHelpText string
 func logsMain(args []string) {
 	sdkOpts, err := getAuthSdkOpts()
 	if err != nil {
-		fmt.Fprintf(os.Stderr,
-			"Failed to get user creds; did you run bompatic config? err: %v\n",
-			err)
-		os.Exit(1)
+		exitWithError(ExitAuth, "%v\n", err)
 	}
 
 	type logsOpts struct {
-		common commonOpts
+		common      commonOpts
+		byEndpoint  bool
+		allServices bool
+		mergeSort   bool
+		sink        string
+		count       bool
+		window      string
+		raw         bool
+		status      bool
+		follow      bool
+		highlight   stringListFlag
+		noColor     bool
+		jsonPretty  bool
+		services    stringListFlag
+		fromDeploy  string
 	}
 
 	var opts logsOpts
 
 	f := flag.NewFlagSet("bopmatic logs", flag.ExitOnError)
 	setCommonFlags(f, &opts.common)
+	f.BoolVar(&opts.byEndpoint, "by-endpoint", false,
+		"Group log lines by the RPC endpoint that emitted them")
+	f.BoolVar(&opts.allServices, "all-services", false,
+		"Retrieve logs from every service in the project")
+	f.Var(&opts.services, "service",
+		"Retrieve logs from the named service; may be repeated to select several services")
+	f.BoolVar(&opts.mergeSort, "merge-sort", false,
+		"With --all-services, merge all services' logs into one stream ordered by time")
+	f.StringVar(&opts.sink, "sink", "",
+		"Deliver logs as newline-delimited json to a file:// or http(s):// destination instead of stdout")
+	f.StringVar(&opts.window, "window", "",
+		"How far back from --endtime to retrieve logs (e.g. 6h or 7d) when --starttime isn't specified")
+	f.BoolVar(&opts.count, "count", false,
+		"Report the number of log lines instead of printing them")
+	f.BoolVar(&opts.status, "status", false,
+		"Describe each service (endpoints, databases, datastores) before retrieving its logs")
+	f.BoolVar(&opts.raw, "raw", false,
+		"Print logs exactly as returned by Bopmatic ServiceRunner, ignoring all formatting flags")
+	f.BoolVar(&opts.follow, "follow", false,
+		"Continue printing new log lines as they're emitted until interrupted")
+	f.Var(&opts.highlight, "highlight",
+		"Colorize substrings matching this regular expression; may be repeated")
+	f.BoolVar(&opts.noColor, "no-color", false,
+		"Don't colorize --highlight matches or --json-pretty output")
+	f.BoolVar(&opts.jsonPretty, "json-pretty", false,
+		"Re-indent log lines whose message is a json object, leading with its level & msg")
+	f.StringVar(&opts.fromDeploy, "from-deploy", "",
+		"Only retrieve logs emitted since this deployment (or 'latest' or 'active') completed")
 	err = f.Parse(args)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
-		fmt.Fprintf(os.Stderr, "%v\n", logsHelpText)
-		os.Exit(1)
+		exitWithError(ExitUsage, "%v\n%v\n", err, logsHelpText)
+	}
+	if opts.raw {
+		ignored := make([]string, 0)
+		if opts.byEndpoint {
+			ignored = append(ignored, "--by-endpoint")
+			opts.byEndpoint = false
+		}
+		if opts.mergeSort {
+			ignored = append(ignored, "--merge-sort")
+			opts.mergeSort = false
+		}
+		if opts.sink != "" {
+			ignored = append(ignored, "--sink")
+			opts.sink = ""
+		}
+		if opts.count {
+			ignored = append(ignored, "--count")
+			opts.count = false
+		}
+		if len(opts.highlight) > 0 {
+			ignored = append(ignored, "--highlight")
+			opts.highlight = nil
+		}
+		if opts.jsonPretty {
+			ignored = append(ignored, "--json-pretty")
+			opts.jsonPretty = false
+		}
+		if len(ignored) > 0 {
+			logWarn("--raw overrides %v; ignoring", strings.Join(ignored, ", "))
+		}
+	}
+	logHighlighter, err = newHighlighter(opts.highlight, opts.noColor)
+	if err != nil {
+		exitWithError(ExitUsage, "%v\n", err)
+	}
+	if opts.jsonPretty {
+		logJsonPrettifier = newJsonPrettifier(opts.noColor)
+	}
+	if opts.follow {
+		if opts.common.endTime != "" {
+			exitWithError(ExitUsage, "--follow cannot be combined with --endtime\n")
+		}
+		if opts.byEndpoint || opts.mergeSort || opts.sink != "" ||
+			opts.count || opts.raw {
+			exitWithError(ExitUsage, "--follow cannot be combined with --by-endpoint, --merge-sort, --sink, --count, or --raw\n")
+		}
+		if outputFormat == OutputYaml {
+			exitWithError(ExitUsage, "--follow supports --output text or json\n")
+		}
+	}
+	if opts.fromDeploy != "" &&
+		(opts.common.startTime != "" || opts.window != "") {
+		exitWithError(ExitUsage, "--from-deploy cannot be combined with --starttime or --window\n")
+	}
+	if len(opts.services) > 0 &&
+		(opts.allServices || opts.common.serviceName != "") {
+		exitWithError(ExitUsage, "--service cannot be combined with --svcname or --all-services\n")
+	}
+	// several services' logs are fetched concurrently and prefixed with
+	// their service's name
+	multiSvc := opts.allServices || len(opts.services) > 0
+	if multiSvc && opts.byEndpoint {
+		exitWithError(ExitUsage, "--by-endpoint cannot be combined with --all-services or --service\n")
+	}
+	if opts.sink != "" && opts.byEndpoint {
+		exitWithError(ExitUsage, "--by-endpoint cannot be combined with --sink\n")
+	}
+	if opts.mergeSort && !multiSvc {
+		exitWithError(ExitUsage, "--merge-sort requires --all-services or --service\n")
+	}
+	if opts.count && (opts.byEndpoint || opts.mergeSort || opts.sink != "") {
+		exitWithError(ExitUsage, "--count cannot be combined with --by-endpoint, --merge-sort, or --sink\n")
 	}
 
 	projId := opts.common.projectId
 	var proj *bopsdk.Project
 	if projId == "" {
-		proj, err = bopsdk.NewProject(opts.common.projectFilename)
-		if err != nil {
-			if errors.Is(err, fs.ErrNotExist) {
-				fmt.Fprintf(os.Stderr, "Please specify --projid or run from within a Bopmatic project directory.\n")
-				fmt.Fprintf(os.Stderr, "%v\n", logsHelpText)
-			} else {
-				fmt.Fprintf(os.Stderr, "%v\n", err)
+		proj, err = loadProject(opts.common.projFile())
+		if err == nil {
+			projId = proj.Desc.Id
+		} else if errors.Is(err, errNoProject) {
+			projId = getDefaultProjectId()
+			if projId == "" {
+				if outputFormat != OutputJson {
+					fmt.Fprintf(os.Stderr, "%v\n", logsHelpText)
+				}
+				exitWithError(ExitUsage, "%v\n", err)
 			}
-			os.Exit(1)
+		} else {
+			exitWithError(ExitFailure, "%v\n", err)
 		}
-		projId = proj.Desc.Id
 	}
 	svcName := opts.common.serviceName
-	if svcName == "" {
+	if svcName == "" && !multiSvc {
 		if proj != nil {
 			if len(proj.Desc.Services) == 1 {
 				svcName = proj.Desc.Services[0].Name
@@ -72,49 +189,650 @@ func logsMain(args []string) {
 					svcList = append(svcList, svc.Name)
 				}
 
-				fmt.Fprintf(os.Stderr, "Please specify --svcname. Project %v currently has %v services: %v\n",
+				exitWithError(ExitUsage, "Please specify --svcname. Project %v currently has %v services: %v\n",
 					projId, len(svcList), svcList)
-				os.Exit(1)
 			}
 		} else {
-			fmt.Fprintf(os.Stderr, "Please specify --svcname.")
-			os.Exit(1)
+			exitWithError(ExitUsage, "Please specify --svcname.")
 		}
 	}
 
-	var startTime, endTime time.Time
-	if opts.common.endTime == "" {
-		endTime = time.Now().UTC()
-	} else {
-		endTime, err = dateparse.ParseAny(opts.common.endTime)
+	window, err := getLogWindow(opts.window)
+	if err != nil {
+		exitWithError(ExitUsage, "%v\n", err)
+	}
+	startTime, endTime, err := parseTimeWindowWithDefault(
+		opts.common.startTime, opts.common.endTime, window)
+	if err != nil {
+		exitWithError(ExitUsage, "%v\n", err)
+	}
+	if opts.fromDeploy != "" {
+		deployId := opts.fromDeploy
+		if deployId == DeployIdLatest || deployId == DeployIdActive {
+			opts.common.projectId = projId
+			opts.common.deployId = deployId
+			deployId = resolveDeployIdAlias(&opts.common, sdkOpts)
+		}
+		startTime, err = getDeployCompletionTime(deployId, sdkOpts)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Could not parse end time(%v): %v\n",
-				opts.common.endTime, err)
-			os.Exit(1)
+			exitWithError(ExitFailure, "%v\n", err)
+		}
+		if !endTime.After(startTime) {
+			exitWithError(ExitUsage, "--endtime %v is before deployment %v completed at %v\n",
+				endTime, deployId, startTime)
 		}
 	}
 
-	const DefaultLogWindow = 48 * time.Hour
-	if opts.common.startTime == "" {
-		startTime = endTime.Add(-DefaultLogWindow)
-	} else {
-		startTime, err = dateparse.ParseAny(opts.common.startTime)
+	svcNames := []string{svcName}
+	if multiSvc {
+		svcNames = make([]string, 0)
+		if proj != nil {
+			for _, svc := range proj.Desc.Services {
+				svcNames = append(svcNames, svc.Name)
+			}
+		} else {
+			// @todo specify environment id
+			svcDescList, err := bopsdk.DescribeAllServices(projId, "",
+				sdkOpts...)
+			if err != nil {
+				exitWithError(ExitServer, "%v\n", err)
+			}
+			for _, svcDesc := range svcDescList {
+				svcNames = append(svcNames, svcDesc.Desc.SvcHeader.ServiceName)
+			}
+		}
+	}
+	if len(opts.services) > 0 {
+		svcNames, err = selectServices(svcNames, opts.services)
+		if err != nil {
+			exitWithError(ExitUsage, "%v\n", err)
+		}
+	}
+
+	if opts.status {
+		printServiceStatus(projId, svcNames, sdkOpts)
+	}
+
+	if opts.follow {
+		err = followLogs(projId, svcNames, startTime, multiSvc,
+			sdkOpts)
+		if err != nil {
+			exitWithError(ExitFailure, "%v\n", err)
+		}
+		return
+	}
+
+	if opts.raw {
+		err = printRawLogs(projId, svcNames, startTime, endTime, sdkOpts)
+		if err != nil {
+			exitWithError(ExitServer, "%v\n", err)
+		}
+		return
+	}
+
+	if opts.count {
+		err = countLogs(projId, svcNames, startTime, endTime,
+			multiSvc, sdkOpts)
+		if err != nil {
+			exitWithError(ExitServer, "%v\n", err)
+		}
+		return
+	}
+
+	if opts.sink != "" {
+		sinkLogs(opts.sink, projId, svcNames, startTime, endTime, sdkOpts)
+		return
+	}
+
+	if multiSvc {
+		err = printAllServicesLogs(projId, svcNames, startTime, endTime,
+			opts.mergeSort, sdkOpts)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Could not parse start time(%v): %v\n",
-				opts.common.startTime, err)
-			os.Exit(1)
+			exitWithError(ExitServer, "%v\n", err)
 		}
+		return
 	}
-	if !endTime.After(startTime) {
-		fmt.Fprintf(os.Stderr, "End time(%v) <= start time(%v). Please specify an end time that occurs later than start time.\n",
-			endTime, startTime)
-		os.Exit(1)
+
+	if !opts.byEndpoint &&
+		(logHighlighter != nil || logJsonPrettifier != nil) {
+		logLines, err := fetchSvcLogLines(projId, svcName, startTime, endTime,
+			sdkOpts)
+		if err != nil {
+			exitWithError(ExitServer, "%v\n", err)
+		}
+		for _, logLine := range logLines {
+			fmt.Printf("%v\n", formatLogLine(logLine.line))
+		}
+		return
+	}
+
+	if !opts.byEndpoint {
+		// @todo specify environment id
+		err = bopsdk.GetLogs(projId, "", svcName, startTime, endTime, sdkOpts...)
+		if err != nil {
+			exitWithError(ExitServer, "%v\n", err)
+		}
+		return
 	}
 
+	var logBuf bytes.Buffer
+	sdkOpts = append(sdkOpts, bopsdk.DeployOptOutput(&logBuf))
 	// @todo specify environment id
 	err = bopsdk.GetLogs(projId, "", svcName, startTime, endTime, sdkOpts...)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
-		os.Exit(1)
+		exitWithError(ExitServer, "%v\n", err)
+	}
+
+	err = printLogsByEndpoint(&logBuf)
+	if err != nil {
+		exitWithError(ExitFailure, "%v\n", err)
+	}
+}
+
+// getDeployCompletionTime returns when deployId completed; it's an error
+// if the deployment is still in progress
+func getDeployCompletionTime(deployId string,
+	sdkOpts []bopsdk.DeployOption) (time.Time, error) {
+
+	deployDesc, err := bopsdk.DescribeDeployment(deployId, sdkOpts...)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Could not describe deployment %v: %w",
+			deployId, err)
+	}
+	if !isTerminalDeployState(deployDesc) || deployDesc.EndTime == 0 {
+		return time.Time{}, fmt.Errorf("Deployment %v hasn't completed yet (state %v); wait for it with 'bopmatic deploy describe --wait --deployid %v'",
+			deployId, deployDesc.State, deployId)
+	}
+
+	return unixTime2Utc(deployDesc.EndTime), nil
+}
+
+// selectServices returns the services named by --service, in the order
+// given, after verifying that each is one of projSvcNames
+func selectServices(projSvcNames []string, selected []string) ([]string,
+	error) {
+
+	valid := make(map[string]bool)
+	for _, svcName := range projSvcNames {
+		valid[svcName] = true
+	}
+	svcNames := make([]string, 0, len(selected))
+	seen := make(map[string]bool)
+	for _, svcName := range selected {
+		if !valid[svcName] {
+			return nil, fmt.Errorf("Unknown --service %v; the project's services are: %v",
+				svcName, strings.Join(projSvcNames, ", "))
+		}
+		if seen[svcName] {
+			continue
+		}
+		seen[svcName] = true
+		svcNames = append(svcNames, svcName)
+	}
+
+	return svcNames, nil
+}
+
+// getLogWindow returns the window of logs to retrieve when --starttime isn't
+// specified: windowStr (from --window) when set, else the window persisted
+// in the config file, else DefaultTimeWindow
+func getLogWindow(windowStr string) (time.Duration, error) {
+	if windowStr != "" {
+		window, err := parseAge(windowStr)
+		if err != nil {
+			return 0, fmt.Errorf("Invalid --window: %w", err)
+		}
+		return window, nil
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return 0, err
+	}
+	if cfg.Logs == nil || cfg.Logs.DefaultWindow == "" {
+		return DefaultTimeWindow, nil
+	}
+	window, err := parseAge(cfg.Logs.DefaultWindow)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid logs.default_window in config: %w", err)
+	}
+
+	return window, nil
+}
+
+// configLogWindowMain shows, sets, or clears the persisted default log
+// window
+func configLogWindowMain(args []string) {
+	f := flag.NewFlagSet("bopmatic config log-window", flag.ExitOnError)
+	setGlobalFlags(f)
+	err := f.Parse(args)
+	if err != nil {
+		exitWithError(ExitUsage, "%v\n", err)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		exitWithError(ExitFailure, "%v\n", err)
+	}
+
+	if f.NArg() == 0 {
+		if cfg.Logs == nil || cfg.Logs.DefaultWindow == "" {
+			fmt.Printf("%v (default)\n", DefaultTimeWindow)
+		} else {
+			fmt.Printf("%v\n", cfg.Logs.DefaultWindow)
+		}
+		return
+	}
+
+	window := f.Arg(0)
+	if window != "default" {
+		_, err = parseAge(window)
+		if err != nil {
+			exitWithError(ExitUsage, "%v\n", err)
+		}
+	}
+	err = updateConfig(func(cfg *cliConfig) error {
+		if window == "default" {
+			cfg.Logs = nil
+		} else {
+			cfg.Logs = &logsConfig{DefaultWindow: window}
+		}
+		return nil
+	})
+	if err != nil {
+		exitWithError(ExitFailure, "%v\n", err)
+	}
+
+	if window == "default" {
+		fmt.Printf("Default log window reset to %v\n", DefaultTimeWindow)
+	} else {
+		fmt.Printf("Default log window set to %v\n", window)
+	}
+}
+
+// printRawLogs hands stdout directly to the sdk so that the server's log
+// output is never intercepted; with several services each one's logs are
+// printed in turn rather than prefixed or interleaved
+func printRawLogs(projId string, svcNames []string, startTime time.Time,
+	endTime time.Time, sdkOpts []bopsdk.DeployOption) error {
+
+	rawSdkOpts := append(append([]bopsdk.DeployOption{}, sdkOpts...),
+		bopsdk.DeployOptOutput(os.Stdout))
+	for _, svcName := range svcNames {
+		// @todo specify environment id
+		err := bopsdk.GetLogs(projId, "", svcName, startTime, endTime,
+			rawSdkOpts...)
+		if err != nil {
+			return fmt.Errorf("%v: %w", svcName, err)
+		}
+	}
+
+	return nil
+}
+
+// printServiceStatus describes each of svcNames on stderr, so that stdout
+// remains only log output. It is best effort; services which can't be
+// described are reported and skipped.
+func printServiceStatus(projId string, svcNames []string,
+	sdkOpts []bopsdk.DeployOption) {
+
+	svcDescList := make([]*pb.DescribeServiceReply, len(svcNames))
+	svcErrs := make([]error, len(svcNames))
+	wg := newFanOutGroup()
+	for idx, svcName := range svcNames {
+		wg.Go(func() error {
+			// @todo specify environment id
+			svcDescList[idx], svcErrs[idx] = bopsdk.DescribeService(projId, "",
+				svcName, sdkOpts...)
+			return nil
+		})
+	}
+	_ = wg.Wait()
+
+	for idx, svcName := range svcNames {
+		if svcErrs[idx] != nil {
+			logWarn("Could not describe service %v: %v", svcName, svcErrs[idx])
+			continue
+		}
+		svcDesc := svcDescList[idx].Desc
+		fmt.Fprintf(os.Stderr, "Service %v:\n", svcName)
+		fmt.Fprintf(os.Stderr, "\tApi Definition: %v\n", svcDesc.ApiDef)
+		fmt.Fprintf(os.Stderr, "\tPort: %v\n", svcDesc.Port)
+		if len(svcDesc.RpcEndpoints) > 0 {
+			fmt.Fprintf(os.Stderr, "\tEndpoints: %v\n",
+				strings.Join(svcDesc.RpcEndpoints, ", "))
+		}
+		if len(svcDesc.DatabaseNames) > 0 {
+			fmt.Fprintf(os.Stderr, "\tDatabases: %v\n",
+				strings.Join(svcDesc.DatabaseNames, ", "))
+		}
+		if len(svcDesc.DatastoreNames) > 0 {
+			fmt.Fprintf(os.Stderr, "\tDatastores: %v\n",
+				strings.Join(svcDesc.DatastoreNames, ", "))
+		}
+	}
+	fmt.Fprintf(os.Stderr, "\n")
+}
+
+type svcLogLine struct {
+	svcName string
+	time    time.Time
+	msg     string
+	line    string
+}
+
+// fetchSvcLogLines retrieves the logs of a single service
+func fetchSvcLogLines(projId string, svcName string, startTime time.Time,
+	endTime time.Time, sdkOpts []bopsdk.DeployOption) ([]svcLogLine, error) {
+
+	var logBuf bytes.Buffer
+	svcSdkOpts := append(append([]bopsdk.DeployOption{}, sdkOpts...),
+		bopsdk.DeployOptOutput(&logBuf))
+	// @todo specify environment id
+	err := bopsdk.GetLogs(projId, "", svcName, startTime, endTime,
+		svcSdkOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", svcName, err)
+	}
+
+	logLines := make([]svcLogLine, 0)
+	scanner := newLogScanner(&logBuf)
+	for scanner.Scan() {
+		logLines = append(logLines, parseSvcLogLine(svcName, scanner.Text()))
+	}
+	err = scanner.Err()
+	if err != nil {
+		return nil, fmt.Errorf("%v: failed to read logs: %w", svcName, err)
+	}
+
+	return logLines, nil
+}
+
+// fetchAllSvcLogLines retrieves the logs of each service concurrently,
+// invoking onFetched (when non-nil) as each service's logs arrive
+func fetchAllSvcLogLines(projId string, svcNames []string,
+	startTime time.Time, endTime time.Time, sdkOpts []bopsdk.DeployOption,
+	onFetched func(logLines []svcLogLine)) ([][]svcLogLine, error) {
+
+	var callbackLock sync.Mutex
+	svcLines := make([][]svcLogLine, len(svcNames))
+	wg := newFanOutGroup()
+	for idx, svcName := range svcNames {
+		wg.Go(func() error {
+			var err error
+			svcLines[idx], err = fetchSvcLogLines(projId, svcName, startTime,
+				endTime, sdkOpts)
+			if err != nil || onFetched == nil {
+				return err
+			}
+
+			callbackLock.Lock()
+			defer callbackLock.Unlock()
+			onFetched(svcLines[idx])
+
+			return nil
+		})
+	}
+
+	return svcLines, wg.Wait()
+}
+
+// mergeSvcLogLines combines the logs of several services into a single
+// slice ordered by timestamp. Lines without a timestamp (e.g. continuations
+// of a multi-line message) stay next to the line before them in their own
+// service's logs, or before the line after them when they come first.
+func mergeSvcLogLines(svcLines [][]svcLogLine) []svcLogLine {
+	type sortableLine struct {
+		svcLogLine
+		sortTime time.Time
+	}
+	sortLines := make([]sortableLine, 0)
+	for _, lines := range svcLines {
+		svcStart := len(sortLines)
+		var lastTime time.Time
+		for _, line := range lines {
+			if !line.time.IsZero() {
+				lastTime = line.time
+				// leading untimed lines sort with the first timed one
+				for idx := svcStart; idx < len(sortLines) &&
+					sortLines[idx].sortTime.IsZero(); idx++ {
+					sortLines[idx].sortTime = lastTime
+				}
+			}
+			sortLines = append(sortLines, sortableLine{
+				svcLogLine: line,
+				sortTime:   lastTime,
+			})
+		}
+	}
+	// stable so that each service's own ordering is preserved for entries
+	// sharing a timestamp
+	sort.SliceStable(sortLines, func(i, j int) bool {
+		return sortLines[i].sortTime.Before(sortLines[j].sortTime)
+	})
+
+	allLines := make([]svcLogLine, 0, len(sortLines))
+	for _, line := range sortLines {
+		allLines = append(allLines, line.svcLogLine)
+	}
+
+	return allLines
+}
+
+// printAllServicesLogs retrieves the logs of each service concurrently and
+// prints them prefixed by service name, either as each service's logs
+// arrive or, with mergeSort, as a single stream ordered by timestamp
+func printAllServicesLogs(projId string, svcNames []string,
+	startTime time.Time, endTime time.Time, mergeSort bool,
+	sdkOpts []bopsdk.DeployOption) error {
+
+	printLines := func(logLines []svcLogLine) {
+		for _, logLine := range logLines {
+			fmt.Printf("[%v] %v\n", logLine.svcName,
+				formatLogLine(logLine.line))
+		}
+	}
+
+	if !mergeSort {
+		_, err := fetchAllSvcLogLines(projId, svcNames, startTime, endTime,
+			sdkOpts, printLines)
+		return err
+	}
+
+	svcLines, err := fetchAllSvcLogLines(projId, svcNames, startTime,
+		endTime, sdkOpts, nil)
+	if err != nil {
+		return err
+	}
+	printLines(mergeSvcLogLines(svcLines))
+
+	return nil
+}
+
+// parseSvcLogLine extracts the timestamp & message from a '<time>: <message>'
+// line as formatted by the sdk; the time of lines without one is left zero
+func parseSvcLogLine(svcName string, line string) svcLogLine {
+	logLine := svcLogLine{
+		svcName: svcName,
+		msg:     line,
+		line:    line,
+	}
+	timeStr, msg, found := strings.Cut(line, ": ")
+	if found {
+		logLine.msg = msg
+		logTime, err := time.Parse("2006-01-02 15:04:05.999999999 -0700 MST",
+			timeStr)
+		if err == nil {
+			logLine.time = logTime
+		}
+	}
+
+	return logLine
+}
+
+// the longest log line that can be read; bufio.Scanner's default of 64KiB
+// is easily exceeded by json logs & stack traces
+const maxLogLineSize = 4 * 1024 * 1024
+
+// newLogScanner returns a line scanner of logs which accepts lines up to
+// maxLogLineSize
+func newLogScanner(logs io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(logs)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLogLineSize)
+
+	return scanner
+}
+
+const noEndpointLabel = "<no endpoint>"
+
+// matches structured endpoint fields such as endpoint=Foo, "method":"Foo",
+// or rpc: Foo as well as fully qualified gRPC methods like /pkg.Svc/Foo
+var logEndpointRegex = regexp.MustCompile(
+	`(?i)"?\b(?:endpoint|method|rpc)"?\s*[=:]\s*"?([A-Za-z0-9_./-]+)"?` +
+		`|(/[A-Za-z0-9_.]+/[A-Za-z0-9_]+)`)
+
+func parseLogEndpoint(msg string) string {
+	match := logEndpointRegex.FindStringSubmatch(msg)
+	if match == nil {
+		return ""
+	}
+	if match[1] != "" {
+		return match[1]
+	}
+
+	return match[2]
+}
+
+func printLogsByEndpoint(logs io.Reader) error {
+	endpointLines := make(map[string][]string)
+	endpointOrder := make([]string, 0)
+	foundEndpoint := false
+
+	scanner := newLogScanner(logs)
+	for scanner.Scan() {
+		line := scanner.Text()
+		// each line is formatted by the sdk as '<time>: <message>'
+		_, msg, _ := strings.Cut(line, ": ")
+		endpoint := parseLogEndpoint(msg)
+		if endpoint == "" {
+			endpoint = noEndpointLabel
+		} else {
+			foundEndpoint = true
+		}
+
+		_, ok := endpointLines[endpoint]
+		if !ok {
+			endpointOrder = append(endpointOrder, endpoint)
+		}
+		endpointLines[endpoint] = append(endpointLines[endpoint], line)
+	}
+	err := scanner.Err()
+	if err != nil {
+		return fmt.Errorf("Failed to read logs: %w", err)
+	}
+
+	if !foundEndpoint {
+		logWarn("no endpoint field found in log output; showing all lines ungrouped")
+		for _, line := range endpointLines[noEndpointLabel] {
+			fmt.Printf("%v\n", formatLogLine(line))
+		}
+		return nil
+	}
+
+	for idx, endpoint := range endpointOrder {
+		if idx > 0 {
+			fmt.Printf("\n")
+		}
+		fmt.Printf("==> %v (%v lines) <==\n", endpoint,
+			len(endpointLines[endpoint]))
+		for _, line := range endpointLines[endpoint] {
+			fmt.Printf("%v\n", formatLogLine(line))
+		}
 	}
+
+	return nil
+}
+
+// lineCounter is an io.Writer which counts the lines written to it rather
+// than retaining them
+type lineCounter struct {
+	lines   int
+	partial bool
+}
+
+func (c *lineCounter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	newlines := bytes.Count(p, []byte{'\n'})
+	c.lines += newlines
+	c.partial = p[len(p)-1] != '\n'
+
+	return len(p), nil
+}
+
+func (c *lineCounter) count() int {
+	if c.partial {
+		return c.lines + 1
+	}
+
+	return c.lines
+}
+
+type logCountOutput struct {
+	Total    int                 `json:"total"`
+	Services []svcLogCountOutput `json:"services,omitempty"`
+}
+
+type svcLogCountOutput struct {
+	Service string `json:"service"`
+	Count   int    `json:"count"`
+}
+
+// countLogs reports the number of log lines each of svcNames emitted within
+// the time window without printing the lines themselves
+func countLogs(projId string, svcNames []string, startTime time.Time,
+	endTime time.Time, perService bool, sdkOpts []bopsdk.DeployOption) error {
+
+	counters := make([]lineCounter, len(svcNames))
+	wg := newFanOutGroup()
+	for idx, svcName := range svcNames {
+		wg.Go(func() error {
+			svcSdkOpts := append(append([]bopsdk.DeployOption{}, sdkOpts...),
+				bopsdk.DeployOptOutput(&counters[idx]))
+			// @todo specify environment id
+			err := bopsdk.GetLogs(projId, "", svcName, startTime, endTime,
+				svcSdkOpts...)
+			if err != nil {
+				return fmt.Errorf("%v: %w", svcName, err)
+			}
+			return nil
+		})
+	}
+	err := wg.Wait()
+	if err != nil {
+		return err
+	}
+
+	var countOut logCountOutput
+	for idx, svcName := range svcNames {
+		svcCount := counters[idx].count()
+		countOut.Total += svcCount
+		if perService {
+			countOut.Services = append(countOut.Services,
+				svcLogCountOutput{Service: svcName, Count: svcCount})
+		}
+	}
+
+	if outputFormat != OutputText {
+		return printStructured(&countOut)
+	}
+
+	for _, svcCount := range countOut.Services {
+		fmt.Printf("%-32v%v\n", svcCount.Service, svcCount.Count)
+	}
+	fmt.Printf("%v log lines between %v and %v\n", countOut.Total, startTime,
+		endTime)
+
+	return nil
 }
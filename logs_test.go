@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMergeSvcLogLinesKeepsUntimedLinesInPlace(t *testing.T) {
+	at := func(secs int) time.Time {
+		return time.Date(2024, 6, 1, 12, 0, secs, 0, time.UTC)
+	}
+	svcA := []svcLogLine{
+		{svcName: "a", line: "a-lead"},
+		{svcName: "a", time: at(2), line: "a2"},
+		{svcName: "a", line: "a2-cont"},
+		{svcName: "a", time: at(4), line: "a4"},
+	}
+	svcB := []svcLogLine{
+		{svcName: "b", time: at(1), line: "b1"},
+		{svcName: "b", time: at(3), line: "b3"},
+		{svcName: "b", line: "b3-cont"},
+	}
+
+	merged := mergeSvcLogLines([][]svcLogLine{svcA, svcB})
+	expected := []string{"b1", "a-lead", "a2", "a2-cont", "b3", "b3-cont",
+		"a4"}
+	if len(merged) != len(expected) {
+		t.Fatalf("expected %v lines, got %v", len(expected), len(merged))
+	}
+	for idx, line := range merged {
+		if line.line != expected[idx] {
+			t.Errorf("line %v: expected %v, got %v", idx, expected[idx],
+				line.line)
+		}
+	}
+}
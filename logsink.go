@@ -0,0 +1,203 @@
+/* Copyright © 2024 Bopmatic, LLC. All Rights Reserved.
+ *
+ * See LICENSE file at the root of this package for license terms
+ */
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	bopsdk "github.com/bopmatic/sdk/golang"
+)
+
+const (
+	// number of log entries sent in each http POST
+	logSinkBatchSize = 500
+	// attempts made to deliver each batch before counting it as failed
+	logSinkMaxAttempts = 4
+)
+
+// logSinkEntry is the newline-delimited json encoding of a single log line
+type logSinkEntry struct {
+	Time    string `json:"time"`
+	Service string `json:"service"`
+	Message string `json:"message"`
+}
+
+func newLogSinkEntry(logLine svcLogLine) logSinkEntry {
+	entry := logSinkEntry{
+		Service: logLine.svcName,
+		Message: logLine.msg,
+	}
+	if !logLine.time.IsZero() {
+		entry.Time = logLine.time.UTC().Format(time.RFC3339Nano)
+	}
+
+	return entry
+}
+
+func encodeLogSinkEntries(logLines []svcLogLine) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, logLine := range logLines {
+		err := enc.Encode(newLogSinkEntry(logLine))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// sinkLogs fetches the logs of svcNames and delivers them, ordered by
+// timestamp, to sinkUrl
+func sinkLogs(sinkUrl string, projId string, svcNames []string,
+	startTime time.Time, endTime time.Time, sdkOpts []bopsdk.DeployOption) {
+
+	sink, err := url.Parse(sinkUrl)
+	if err != nil {
+		exitWithError(ExitUsage, "Invalid --sink %v: %v\n", sinkUrl, err)
+	}
+
+	var deliver func(logLines []svcLogLine) (int, int, error)
+	switch sink.Scheme {
+	case "file":
+		deliver = func(logLines []svcLogLine) (int, int, error) {
+			return deliverLogsToFile(sink, logLines)
+		}
+	case "http", "https":
+		deliver = func(logLines []svcLogLine) (int, int, error) {
+			return deliverLogsToHttp(sinkUrl, logLines)
+		}
+	default:
+		exitWithError(ExitUsage, "Unsupported --sink %v; must be a file://, http://, or https:// url\n",
+			sinkUrl)
+	}
+
+	svcLines, err := fetchAllSvcLogLines(projId, svcNames, startTime, endTime,
+		sdkOpts, nil)
+	if err != nil {
+		exitWithError(ExitServer, "%v\n", err)
+	}
+	logLines := mergeSvcLogLines(svcLines)
+
+	delivered, failed, err := deliver(logLines)
+	fmt.Printf("Delivered %v log entries to %v; %v failed\n", delivered,
+		sinkUrl, failed)
+	if err != nil {
+		exitWithError(ExitFailure, "%v\n", err)
+	}
+}
+
+func deliverLogsToFile(sink *url.URL, logLines []svcLogLine) (int, int,
+	error) {
+
+	// accept both file:///abs/path and file://rel/path
+	filePath := sink.Host + sink.Path
+	logFile, err := os.OpenFile(filePath, os.O_CREATE|os.O_APPEND|os.O_WRONLY,
+		0644)
+	if err != nil {
+		return 0, len(logLines), err
+	}
+	defer logFile.Close()
+
+	ndjson, err := encodeLogSinkEntries(logLines)
+	if err != nil {
+		return 0, len(logLines), err
+	}
+	_, err = logFile.Write(ndjson)
+	if err != nil {
+		return 0, len(logLines), fmt.Errorf("Failed to write %v: %w",
+			filePath, err)
+	}
+
+	return len(logLines), 0, nil
+}
+
+// deliverLogsToHttp POSTs the log entries in batches. When the receiver
+// pushes back (429 or 5xx) the batch is retried with exponential backoff,
+// honoring Retry-After when specified; batches which still fail are counted
+// and delivery continues with the next batch
+func deliverLogsToHttp(sinkUrl string, logLines []svcLogLine) (int, int,
+	error) {
+
+	httpClient := &http.Client{
+		Timeout: time.Second * 30,
+	}
+
+	delivered := 0
+	failed := 0
+	var lastErr error
+	for batchStart := 0; batchStart < len(logLines); batchStart += logSinkBatchSize {
+		batchEnd := min(batchStart+logSinkBatchSize, len(logLines))
+		batch := logLines[batchStart:batchEnd]
+		ndjson, err := encodeLogSinkEntries(batch)
+		if err != nil {
+			return delivered, len(logLines) - delivered, err
+		}
+
+		err = postLogBatch(httpClient, sinkUrl, ndjson)
+		if err != nil {
+			logWarn("Failed to deliver %v log entries: %v", len(batch), err)
+			failed += len(batch)
+			lastErr = err
+			continue
+		}
+		delivered += len(batch)
+	}
+
+	if lastErr != nil {
+		lastErr = fmt.Errorf("Failed to deliver %v log entries to %v: %w",
+			failed, sinkUrl, lastErr)
+	}
+
+	return delivered, failed, lastErr
+}
+
+func postLogBatch(httpClient *http.Client, sinkUrl string,
+	ndjson []byte) error {
+
+	backoff := time.Second
+	var err error
+	for attempt := 1; attempt <= logSinkMaxAttempts; attempt++ {
+		if attempt > 1 {
+			logDebug("retrying log delivery in %v (attempt %v)", backoff,
+				attempt)
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+
+		var resp *http.Response
+		resp, err = httpClient.Post(sinkUrl, "application/x-ndjson",
+			bytes.NewReader(ndjson))
+		if err != nil {
+			continue
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode/100 == 2 {
+			return nil
+		}
+		err = fmt.Errorf("%v responded %v", sinkUrl, resp.Status)
+		if resp.StatusCode != http.StatusTooManyRequests &&
+			resp.StatusCode/100 != 5 {
+			// the receiver rejected the batch; retrying won't help
+			return err
+		}
+		retryAfter, convErr := strconv.Atoi(resp.Header.Get("Retry-After"))
+		if convErr == nil && retryAfter > 0 {
+			backoff = time.Duration(retryAfter) * time.Second
+		}
+	}
+
+	return err
+}
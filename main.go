@@ -5,21 +5,30 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
+	"io/fs"
 	"os"
+	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"time"
 
 	_ "embed"
 
+	"github.com/araddon/dateparse"
+
 	bopsdk "github.com/bopmatic/sdk/golang"
 	"github.com/bopmatic/sdk/golang/pb"
+	"golang.org/x/sync/errgroup"
 )
 
 type commonOpts struct {
 	projectFilename string
+	projectDir      string
+	noDiscovery     bool
 	projectId       string
 	packageId       string
 	deployId        string
@@ -32,11 +41,17 @@ var subCommandTab = map[string]func(args []string){
 	"project": projMain,
 	"package": pkgMain,
 	"deploy":  deployMain,
+	"env":     envMain,
 	"help":    helpMain,
 	"config":  configMain,
 	"version": versionMain,
 	"upgrade": upgradeMain,
 	"logs":    logsMain,
+	"new":     projCreateMain,
+	"run":     runMain,
+
+	"request-access": requestAccessMain,
+	"self-uninstall": selfUninstallMain,
 }
 
 const (
@@ -46,6 +61,7 @@ const (
 	SiteAssetsSubdir     = "site_assets"
 	// update Makefile brewversion target if changing this value
 	BrewVersionSuffix = "b"
+	DefaultTimeWindow = 48 * time.Hour
 )
 
 func printExampleCurl(descReply *pb.DescribePackageReply) {
@@ -67,16 +83,317 @@ func unixTime2UtcStr(msecs uint64) string {
 	return unixTime2Utc(msecs).String()
 }
 
+// --timestamps values accepted by describe commands
+const (
+	TimestampsUtc      = "utc"
+	TimestampsRfc3339  = "rfc3339"
+	TimestampsUnix     = "unix"
+	TimestampsRelative = "relative"
+)
+
+var timestampFormat = TimestampsUtc
+
+type timestampsFlag struct{}
+
+func (t *timestampsFlag) String() string {
+	return timestampFormat
+}
+
+func (t *timestampsFlag) Set(val string) error {
+	switch val {
+	case TimestampsUtc, TimestampsRfc3339, TimestampsUnix, TimestampsRelative:
+		timestampFormat = val
+		return nil
+	}
+
+	return fmt.Errorf("invalid timestamp format %v; must be one of %v, %v, %v, or %v",
+		val, TimestampsUtc, TimestampsRfc3339, TimestampsUnix,
+		TimestampsRelative)
+}
+
+func setTimestampFlags(f *flag.FlagSet) {
+	f.Var(&timestampsFlag{}, "timestamps",
+		"How times are rendered; one of utc (default), rfc3339, unix (epoch ms), or relative")
+}
+
+// formatTimestamp renders msecs according to --timestamps; like
+// unixTime2UtcStr() an unset (0) time renders as an empty string
+func formatTimestamp(msecs uint64) string {
+	if msecs == 0 {
+		return ""
+	}
+
+	switch timestampFormat {
+	case TimestampsRfc3339:
+		return unixTime2Utc(msecs).Format(time.RFC3339Nano)
+	case TimestampsUnix:
+		return strconv.FormatUint(msecs, 10)
+	case TimestampsRelative:
+		return relativeTime(unixTime2Utc(msecs), time.Now())
+	}
+
+	return unixTime2UtcStr(msecs)
+}
+
+// relativeTime describes t relative to now, e.g. "3 minutes ago"
+func relativeTime(t time.Time, now time.Time) string {
+	delta := now.Sub(t)
+	suffix := "ago"
+	if delta < 0 {
+		delta = -delta
+		suffix = "from now"
+	}
+
+	var count int64
+	var unit string
+	switch {
+	case delta < time.Minute:
+		count, unit = int64(delta/time.Second), "second"
+	case delta < time.Hour:
+		count, unit = int64(delta/time.Minute), "minute"
+	case delta < 24*time.Hour:
+		count, unit = int64(delta/time.Hour), "hour"
+	default:
+		count, unit = int64(delta/(24*time.Hour)), "day"
+	}
+	if count != 1 {
+		unit += "s"
+	}
+
+	return fmt.Sprintf("%v %v %v", count, unit, suffix)
+}
+
+// confirmByTypedName prompts the user to re-type name and reports whether
+// what they entered matches; used to guard destructive operations
+func confirmByTypedName(what string, name string) bool {
+	fmt.Printf("Type the %v (%v) to confirm: ", what, name)
+	var answer string
+	_, err := fmt.Scanf("%s", &answer)
+	if err != nil {
+		return false
+	}
+
+	return strings.TrimSpace(answer) == name
+}
+
+// confirm asks the user a yes or no question, defaulting to no, and reports
+// whether they answered yes
+func confirm(question string) bool {
+	fmt.Printf("%v [y/N]: ", question)
+	var answer string
+	_, err := fmt.Scanf("%s", &answer)
+	if err != nil {
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+
+	return answer == "y" || answer == "yes"
+}
+
+// parseTimeWindow converts --starttime & --endtime values into a time range.
+// endTime defaults to now and startTime defaults to DefaultTimeWindow prior
+// to endTime
+func parseTimeWindow(startTimeStr string, endTimeStr string) (startTime time.Time,
+	endTime time.Time, err error) {
+
+	return parseTimeWindowWithDefault(startTimeStr, endTimeStr,
+		DefaultTimeWindow)
+}
+
+// parseTimeWindowWithDefault is parseTimeWindow with startTime defaulting to
+// defaultWindow prior to endTime
+func parseTimeWindowWithDefault(startTimeStr string, endTimeStr string,
+	defaultWindow time.Duration) (startTime time.Time, endTime time.Time,
+	err error) {
+
+	if endTimeStr == "" {
+		endTime = time.Now().UTC()
+	} else {
+		endTime, err = dateparse.ParseAny(endTimeStr)
+		if err != nil {
+			return startTime, endTime,
+				fmt.Errorf("Could not parse end time(%v): %w", endTimeStr, err)
+		}
+	}
+
+	if startTimeStr == "" {
+		startTime = endTime.Add(-defaultWindow)
+	} else {
+		startTime, err = dateparse.ParseAny(startTimeStr)
+		if err != nil {
+			return startTime, endTime,
+				fmt.Errorf("Could not parse start time(%v): %w", startTimeStr,
+					err)
+		}
+	}
+	if !endTime.After(startTime) {
+		return startTime, endTime,
+			fmt.Errorf("End time(%v) <= start time(%v). Please specify an end time that occurs later than start time.",
+				endTime, startTime)
+	}
+
+	return startTime, endTime, nil
+}
+
+// parseAge parses a duration such as 30d, 2w, or 36h; in addition to the
+// units supported by time.ParseDuration, d (days) and w (weeks) are accepted
+func parseAge(ageStr string) (time.Duration, error) {
+	ageStr = strings.TrimSpace(ageStr)
+	for suffix, unit := range map[string]time.Duration{
+		"d": 24 * time.Hour,
+		"w": 7 * 24 * time.Hour,
+	} {
+		numStr, found := strings.CutSuffix(ageStr, suffix)
+		if !found {
+			continue
+		}
+		num, err := strconv.ParseFloat(numStr, 64)
+		if err != nil || num < 0 {
+			return 0, fmt.Errorf("Could not parse age %v", ageStr)
+		}
+		return time.Duration(num * float64(unit)), nil
+	}
+
+	age, err := time.ParseDuration(ageStr)
+	if err != nil || age < 0 {
+		return 0, fmt.Errorf("Could not parse age %v; expected e.g. 30d, 2w, or 36h",
+			ageStr)
+	}
+
+	return age, nil
+}
+
 //go:embed help.txt
 var helpText string
 
+// helpMain prints the help text of the command named by args[0] (e.g.
+// 'bopmatic help package'), or the general help when there is no such
+// command
 func helpMain(args []string) {
+	cmdHelpText := map[string]string{
+		"project": projHelpText,
+		"new":     projHelpText,
+		"package": pkgHelpText,
+		"deploy":  deployHelpText,
+		"env":     envHelpText,
+		"logs":    logsHelpText,
+	}
+
+	if len(args) > 0 {
+		text, ok := cmdHelpText[args[0]]
+		if ok {
+			fmt.Printf(text)
+			return
+		}
+	}
+
 	fmt.Printf(helpText)
 }
 
+// DefaultParallel bounds how many concurrent requests are made of
+// ServiceRunner when fanning out describe calls
+const DefaultParallel = 8
+
+var parallelLimit = DefaultParallel
+
+type parallelFlag struct{}
+
+func (p *parallelFlag) String() string {
+	return strconv.Itoa(parallelLimit)
+}
+
+func (p *parallelFlag) Set(val string) error {
+	limit, err := strconv.Atoi(val)
+	if err != nil || limit < 1 {
+		return fmt.Errorf("invalid parallel limit %v; must be a positive integer",
+			val)
+	}
+	parallelLimit = limit
+
+	return nil
+}
+
+// newFanOutGroup returns an errgroup whose concurrency is bounded by
+// --parallel
+func newFanOutGroup() *errgroup.Group {
+	wg := &errgroup.Group{}
+	wg.SetLimit(parallelLimit)
+
+	return wg
+}
+
+// setGlobalFlags registers the flags accepted by every subcommand
+func setGlobalFlags(f *flag.FlagSet) {
+	f.Var(&outputFlag{}, "output", "Output format; one of text, json, or yaml")
+	f.Var(&logLevelFlag{}, "log-level",
+		"Diagnostic verbosity; one of error, warn, info, or debug")
+	f.Var(&parallelFlag{}, "parallel",
+		"Maximum number of concurrent requests made of Bopmatic ServiceRunner")
+	f.Var(&apiEndpointFlag{}, "api-endpoint",
+		"Alternate Bopmatic ServiceRunner endpoint url (e.g. staging)")
+	f.Var(&noUpgradeCheckFlag{}, "no-upgrade-check",
+		"Skip checking for newer CLI and build image versions at startup")
+	f.Var(&maskSecretsFlag{}, "mask-secrets",
+		"Mask api keys and tokens in log and error messages; default true")
+}
+
+// detectGlobalFlags looks for global flags ahead of flag parsing so that
+// they also apply to messages emitted before a subcommand parses its flags
+// (e.g. upgrade warnings or missing credentials)
+func detectGlobalFlags(args []string) {
+	globalFlags := map[string]flag.Value{
+		"output":           &outputFlag{},
+		"log-level":        &logLevelFlag{},
+		"parallel":         &parallelFlag{},
+		"api-endpoint":     &apiEndpointFlag{},
+		"no-upgrade-check": &noUpgradeCheckFlag{},
+		"mask-secrets":     &maskSecretsFlag{},
+	}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			break
+		}
+		// only flags are considered so that positional args & flag values
+		// which happen to match a global flag's name aren't applied
+		if len(arg) < 2 || arg[0] != '-' {
+			continue
+		}
+		arg = strings.TrimPrefix(arg[1:], "-")
+		name, val, found := strings.Cut(arg, "=")
+		flagVal, ok := globalFlags[name]
+		if !ok {
+			continue
+		}
+		if !found {
+			boolFlag, isBool := flagVal.(interface{ IsBoolFlag() bool })
+			if isBool && boolFlag.IsBoolFlag() {
+				val = "true"
+			} else if i+1 >= len(args) {
+				continue
+			} else {
+				// skip the value so it isn't itself considered a flag
+				i++
+				val = args[i]
+			}
+		}
+		err := flagVal.Set(val)
+		if err != nil {
+			exitWithError(ExitUsage, "invalid value %q for flag -%v: %v\n", val,
+				name, err)
+		}
+	}
+}
+
 func setCommonFlags(f *flag.FlagSet, o *commonOpts) {
+	setGlobalFlags(f)
 	f.StringVar(&o.projectFilename, "projfile", bopsdk.DefaultProjectFilename,
 		"Bopmatic project filename")
+	f.StringVar(&o.projectDir, "projdir", "",
+		"Bopmatic project directory; --projfile is relative to this")
+	f.BoolVar(&o.noDiscovery, "no-discovery", false,
+		"Don't search parent directories for the project file")
 	f.StringVar(&o.projectId, "projid", "", "Bopmatic project id")
 	f.StringVar(&o.packageId, "pkgid", "",
 		"Bopmatic project package identifier")
@@ -90,12 +407,137 @@ func setCommonFlags(f *flag.FlagSet, o *commonOpts) {
 		"The ending time in UTC to query; defaults to now.")
 }
 
+// stringListFlag collects the values of a flag which may be repeated
+type stringListFlag []string
+
+func (l *stringListFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringListFlag) Set(val string) error {
+	*l = append(*l, val)
+	return nil
+}
+
+// resolveProjFile returns the path of the project file to load; a relative
+// projectFilename is interpreted relative to projectDir when specified.
+// Unless discovery is disabled, a relative projectFilename which doesn't
+// exist is searched for in each parent directory, similar to how git finds
+// its repository
+func resolveProjFile(projectDir string, projectFilename string,
+	noDiscovery bool) string {
+
+	if filepath.IsAbs(projectFilename) {
+		return projectFilename
+	}
+	projFile := projectFilename
+	if projectDir != "" {
+		projFile = filepath.Join(projectDir, projectFilename)
+	}
+	if noDiscovery {
+		return projFile
+	}
+	if _, err := os.Stat(projFile); err == nil {
+		return projFile
+	}
+
+	startDir, err := filepath.Abs(filepath.Dir(projFile))
+	if err != nil {
+		return projFile
+	}
+	discovered, err := discoverProjFile(startDir,
+		filepath.Base(projectFilename))
+	if err != nil {
+		return projFile
+	}
+	logDebug("discovered project file %v", discovered)
+
+	return discovered
+}
+
+// discoverProjFile walks up from startDir until it finds projFileName or
+// reaches the filesystem root
+func discoverProjFile(startDir string, projFileName string) (string, error) {
+	for dir := startDir; ; {
+		candidate := filepath.Join(dir, projFileName)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("no %v found in %v or any parent directory",
+				projFileName, startDir)
+		}
+		dir = parent
+	}
+}
+
+func (o *commonOpts) projFile() string {
+	return resolveProjFile(o.projectDir, o.projectFilename, o.noDiscovery)
+}
+
+var errNoProject = errors.New("not within a Bopmatic project directory")
+
+const ProjectIdEnvVar = "BOPMATIC_PROJECT_ID"
+
+// getDefaultProjectId returns the project id to use outside of a Bopmatic
+// project directory when --projid isn't specified: ProjectIdEnvVar, else
+// the one persisted in the config file
+func getDefaultProjectId() string {
+	projId := os.Getenv(ProjectIdEnvVar)
+	if projId != "" {
+		return projId
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		logDebug("ignoring default project: %v", err)
+		return ""
+	}
+
+	return cfg.DefaultProject
+}
+
+// resolveProjectId returns the id of the project at projectFile or, when
+// there is no project file, the default project id
+func resolveProjectId(projectFile string) (string, error) {
+	proj, err := loadProject(projectFile)
+	if err == nil {
+		return proj.Desc.Id, nil
+	}
+	if errors.Is(err, errNoProject) {
+		projId := getDefaultProjectId()
+		if projId != "" {
+			logInfo("No project file found; using default projId:%v", projId)
+			return projId, nil
+		}
+	}
+
+	return "", err
+}
+
+// loadProject reads the project file at projectFile. A missing project file
+// is reported as errNoProject along with guidance on how to specify the
+// project, rather than as a bare open error.
+func loadProject(projectFile string) (*bopsdk.Project, error) {
+	proj, err := bopsdk.NewProject(projectFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("Could not find project file '%v' (%w). Please run from within a Bopmatic project directory or specify --projid, --projfile, or --projdir.",
+			projectFile, errNoProject)
+	} else if err != nil {
+		return nil, fmt.Errorf("Could not load project file '%v': %w",
+			projectFile, err)
+	}
+
+	return proj, nil
+}
+
 func checkAndPrintArchWarning() bool {
 	if runtime.GOARCH != "amd64" {
 		if runtime.GOOS == "darwin" {
-			fmt.Fprintf(os.Stderr, "*WARN*: bopmatic's build container is known not to run well on M1 based Macs; please try on a 64-bit Intel/AMD based system if possible.\n")
+			logWarn("bopmatic's build container is known not to run well on M1 based Macs; please try on a 64-bit Intel/AMD based system if possible.\n")
 		} else {
-			fmt.Fprintf(os.Stderr, "*WARN*: bopmatic's build container has not been tested on your CPU (%v); please try on a 64-bit Intel/AMD based system if possible.\n",
+			logWarn("bopmatic's build container has not been tested on your CPU (%v); please try on a 64-bit Intel/AMD based system if possible.\n",
 				runtime.GOARCH)
 		}
 		return true
@@ -107,11 +549,18 @@ func checkAndPrintArchWarning() bool {
 func main() {
 	versionText = strings.Split(versionText, "\n")[0]
 	exitStatus := 0
+	detectGlobalFlags(os.Args[1:])
 
-	printedUpgradeCLIWarning := checkAndPrintUpgradeCLIWarning()
-	printedUpgradeContainerWarning := checkAndPrintUpgradeContainerWarning()
+	printedUpgradeCLIWarning := false
+	printedUpgradeContainerWarning := false
+	if !skipUpgradeCheck() {
+		printedUpgradeCLIWarning = checkAndPrintUpgradeCLIWarning()
+		printedUpgradeContainerWarning = checkAndPrintUpgradeContainerWarning()
+	}
+	migrateConfigOnStartup()
 	printedArchWarning := checkAndPrintArchWarning()
-	if printedUpgradeCLIWarning || printedUpgradeContainerWarning || printedArchWarning {
+	if (printedUpgradeCLIWarning || printedUpgradeContainerWarning ||
+		printedArchWarning) && logEnabled(LogLevelWarn) {
 		fmt.Fprintf(os.Stderr, "\n")
 	}
 
@@ -133,6 +582,7 @@ func main() {
 		args = os.Args[2:]
 	}
 
+	logDebug("running subcommand %v with args %v", subCommandName, args)
 	subCommand(args)
 
 	os.Exit(exitStatus)
@@ -6,3 +6,45 @@ import (
 
 func TestMain(t *testing.T) {
 }
+
+func TestDetectGlobalFlags(t *testing.T) {
+	defer func() {
+		outputFormat = OutputText
+		maskSecretsEnabled = true
+	}()
+
+	tests := []struct {
+		args           []string
+		expectedOutput string
+		expectedMask   bool
+	}{
+		{[]string{"project", "list", "--output", "json"}, OutputJson, true},
+		{[]string{"project", "list", "-output=yaml"}, OutputYaml, true},
+		{[]string{"--mask-secrets=false", "project", "list"}, OutputText,
+			false},
+		// positional args & flag values matching a global flag's name
+		{[]string{"run", "--svcname", "s", "output", "json"}, OutputText,
+			true},
+		{[]string{"package", "deploy", "--note", "mask-secrets"},
+			OutputText, true},
+		// a global flag's value isn't itself considered a flag
+		{[]string{"--output", "json", "--mask-secrets=false"}, OutputJson,
+			false},
+		// nothing after -- is a flag
+		{[]string{"run", "--", "--output", "json"}, OutputText, true},
+	}
+
+	for _, test := range tests {
+		outputFormat = OutputText
+		maskSecretsEnabled = true
+		detectGlobalFlags(test.args)
+		if outputFormat != test.expectedOutput {
+			t.Errorf("%v: expected output %v, got %v", test.args,
+				test.expectedOutput, outputFormat)
+		}
+		if maskSecretsEnabled != test.expectedMask {
+			t.Errorf("%v: expected mask-secrets %v, got %v", test.args,
+				test.expectedMask, maskSecretsEnabled)
+		}
+	}
+}
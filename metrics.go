@@ -0,0 +1,209 @@
+/* Copyright © 2024 Bopmatic, LLC. All Rights Reserved.
+ *
+ * See LICENSE file at the root of this package for license terms
+ */
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bopmatic/sdk/golang/goswag"
+	"github.com/bopmatic/sdk/golang/goswag/service_runner"
+	"github.com/bopmatic/sdk/golang/models"
+)
+
+type metricSeries struct {
+	name    string
+	samples []float64
+}
+
+// getMetricSamples retrieves the OpenMetrics formatted samples for the
+// resource(s) identified by scope & scopeQualifier.
+// @todo move into the sdk alongside the other ServiceRunner wrappers
+func getMetricSamples(projId string, envId string, scope models.MetricsScope,
+	scopeQualifier string, startTime time.Time, endTime time.Time) (string,
+	error) {
+
+	authInfo, err := getSrAuthInfo()
+	if err != nil {
+		return "", err
+	}
+
+	getMetricsReq := &models.GetMetricSamplesRequest{
+		ProjID:         projId,
+		EnvID:          envId,
+		Scope:          scope.Pointer(),
+		ScopeQualifier: scopeQualifier,
+		StartTime:      strconv.FormatInt(startTime.UnixMilli(), 10),
+		EndTime:        strconv.FormatInt(endTime.UnixMilli(), 10),
+		Format:         models.MetricsFormatMETRICFORMATOPENMETRICS.Pointer(),
+	}
+	logDebug("requesting %v metrics for %v/%v from %v to %v", scope,
+		projId, scopeQualifier, startTime, endTime)
+	httpClient := newSrHttpClient()
+	getMetricsParams := service_runner.NewGetMetricSamplesParams().
+		WithBody(getMetricsReq).WithHTTPClient(httpClient)
+	client := goswag.NewHTTPClientWithConfig(nil,
+		goswag.DefaultTransportConfig())
+
+	resp, err := client.ServiceRunner.GetMetricSamples(getMetricsParams,
+		authInfo)
+	if err != nil {
+		return "", fmt.Errorf("Client/HTTP failure: %v", err)
+	}
+	getMetricsReply := resp.GetPayload()
+	if getMetricsReply.Result != nil &&
+		getMetricsReply.Result.Status != nil &&
+		*getMetricsReply.Result.Status != models.ServiceRunnerStatusSTATUSOK {
+		return "", fmt.Errorf("GetMetricSamples failure(%v): %v",
+			*getMetricsReply.Result.Status,
+			getMetricsReply.Result.StatusDetail)
+	}
+
+	return getMetricsReply.MetricBuf, nil
+}
+
+// parseOpenMetrics extracts sample values from an OpenMetrics text
+// exposition, grouped by metric name (including labels) in the order the
+// metrics first appear
+func parseOpenMetrics(metricBuf string) []*metricSeries {
+	seriesList := make([]*metricSeries, 0)
+	seriesMap := make(map[string]*metricSeries)
+
+	for _, line := range strings.Split(metricBuf, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		// labels may contain whitespace so split after the closing brace
+		name := line
+		rest := ""
+		if labelEnd := strings.LastIndex(line, "}"); labelEnd != -1 {
+			name = line[:labelEnd+1]
+			rest = line[labelEnd+1:]
+		} else if nameEnd := strings.IndexAny(line, " \t"); nameEnd != -1 {
+			name = line[:nameEnd]
+			rest = line[nameEnd:]
+		}
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			continue
+		}
+		val, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			continue
+		}
+
+		series, ok := seriesMap[name]
+		if !ok {
+			series = &metricSeries{name: name}
+			seriesMap[name] = series
+			seriesList = append(seriesList, series)
+		}
+		series.samples = append(series.samples, val)
+	}
+
+	return seriesList
+}
+
+func (series *metricSeries) summarize() (min float64, max float64,
+	avg float64) {
+
+	if len(series.samples) == 0 {
+		return 0, 0, 0
+	}
+
+	min = series.samples[0]
+	max = series.samples[0]
+	sum := 0.0
+	for _, val := range series.samples {
+		if val < min {
+			min = val
+		}
+		if val > max {
+			max = val
+		}
+		sum += val
+	}
+
+	return min, max, sum / float64(len(series.samples))
+}
+
+func (series *metricSeries) sparkline() string {
+	const sparkChars = "▁▂▃▄▅▆▇█"
+	sparkRunes := []rune(sparkChars)
+
+	min, max, _ := series.summarize()
+	var sb strings.Builder
+	for _, val := range series.samples {
+		idx := 0
+		if max > min {
+			idx = int((val - min) / (max - min) * float64(len(sparkRunes)-1))
+		}
+		sb.WriteRune(sparkRunes[idx])
+	}
+
+	return sb.String()
+}
+
+// printMetricsSummary renders min/max/avg and a sparkline for each metric in
+// metricBuf, or a note explaining why metrics are unavailable
+func printMetricsSummary(indent string, metricBuf string, err error) {
+	if err != nil {
+		fmt.Printf("%vMetrics: unavailable (%v)\n", indent, err)
+		return
+	}
+
+	seriesList := parseOpenMetrics(metricBuf)
+	if len(seriesList) == 0 {
+		fmt.Printf("%vMetrics: no samples in the requested window\n", indent)
+		return
+	}
+
+	fmt.Printf("%vMetrics:\n", indent)
+	for _, series := range seriesList {
+		min, max, avg := series.summarize()
+		fmt.Printf("%v\t%v: min:%v max:%v avg:%.2f %v\n", indent, series.name,
+			min, max, avg, series.sparkline())
+	}
+}
+
+type metricSeriesOutput struct {
+	Name string  `json:"name"`
+	Min  float64 `json:"min"`
+	Max  float64 `json:"max"`
+	Avg  float64 `json:"avg"`
+}
+
+type metricsOutput struct {
+	Series []metricSeriesOutput `json:"series"`
+	Error  string               `json:"error,omitempty"`
+}
+
+// newMetricsOutput is the structured output equivalent of
+// printMetricsSummary
+func newMetricsOutput(metricBuf string, err error) *metricsOutput {
+	metricsOut := &metricsOutput{
+		Series: make([]metricSeriesOutput, 0),
+	}
+	if err != nil {
+		metricsOut.Error = err.Error()
+		return metricsOut
+	}
+
+	for _, series := range parseOpenMetrics(metricBuf) {
+		min, max, avg := series.summarize()
+		metricsOut.Series = append(metricsOut.Series, metricSeriesOutput{
+			Name: series.name,
+			Min:  min,
+			Max:  max,
+			Avg:  avg,
+		})
+	}
+
+	return metricsOut
+}
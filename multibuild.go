@@ -0,0 +1,139 @@
+/* Copyright © 2024 Bopmatic, LLC. All Rights Reserved.
+ *
+ * See LICENSE file at the root of this package for license terms
+ */
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// DefaultBuildJobs bounds the number of concurrent project builds since
+// each one runs its own build container
+const DefaultBuildJobs = 2
+
+type projBuildResult struct {
+	projFile string
+	output   bytes.Buffer
+	err      error
+	skipped  bool
+}
+
+// findProjectFiles returns every file named projFileName beneath root
+func findProjectFiles(root string, projFileName string) ([]string, error) {
+	projFiles := make([]string, 0)
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry,
+		err error) error {
+
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != root && watchExcludedDirs[d.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Name() == projFileName {
+			projFiles = append(projFiles, path)
+		}
+
+		return nil
+	})
+
+	return projFiles, err
+}
+
+// buildAllProjects builds every project found beneath root and prints a
+// summary; it returns false if any build failed. Each build runs as a
+// separate invocation of this binary, passed childArgs, because the sdk
+// changes the working directory while building, which is not safe to do
+// concurrently
+func buildAllProjects(root string, projFileName string, jobs int,
+	failFast bool, childArgs []string) bool {
+
+	projFiles, err := findProjectFiles(root, projFileName)
+	if err != nil {
+		exitWithError(ExitFailure, "Failed to search %v for projects: %v\n",
+			root, err)
+	}
+	if len(projFiles) == 0 {
+		exitWithError(ExitNotFound, "No %v files found beneath %v\n",
+			projFileName, root)
+	}
+
+	myBinaryPath, err := os.Executable()
+	if err != nil {
+		exitWithError(ExitFailure, "Could not determine path to bopmatic CLI: %v\n",
+			err)
+	}
+
+	fmt.Printf("Building %v projects (%v at a time)...\n", len(projFiles),
+		jobs)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var outputLock sync.Mutex
+	results := make([]*projBuildResult, len(projFiles))
+	var wg errgroup.Group
+	wg.SetLimit(jobs)
+	for idx, projFile := range projFiles {
+		result := &projBuildResult{projFile: projFile}
+		results[idx] = result
+
+		wg.Go(func() error {
+			if ctx.Err() != nil {
+				result.skipped = true
+				return nil
+			}
+
+			buildArgs := append([]string{"package", "build", "--projfile",
+				projFile}, childArgs...)
+			cmd := exec.CommandContext(ctx, myBinaryPath, buildArgs...)
+			cmd.Stdout = &result.output
+			cmd.Stderr = &result.output
+			result.err = cmd.Run()
+			if result.err != nil && ctx.Err() != nil {
+				result.skipped = true
+			}
+
+			outputLock.Lock()
+			defer outputLock.Unlock()
+			fmt.Printf("\n========== %v ==========\n%s", projFile,
+				result.output.Bytes())
+			if result.err != nil && failFast {
+				cancel()
+			}
+
+			return nil
+		})
+	}
+	_ = wg.Wait()
+
+	allSucceeded := true
+	fmt.Printf("\nBuild summary:\n")
+	for _, result := range results {
+		status := "ok"
+		if result.skipped {
+			status = "SKIPPED"
+			allSucceeded = false
+		} else if result.err != nil {
+			status = fmt.Sprintf("FAILED (%v)", result.err)
+			allSucceeded = false
+		}
+		fmt.Printf("\t%v: %v\n", result.projFile, status)
+	}
+
+	return allSucceeded
+}
@@ -0,0 +1,205 @@
+/* Copyright © 2024 Bopmatic, LLC. All Rights Reserved.
+ *
+ * See LICENSE file at the root of this package for license terms
+ */
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+
+	bopsdk "github.com/bopmatic/sdk/golang"
+	"github.com/bopmatic/sdk/golang/pb"
+	"golang.org/x/sync/errgroup"
+)
+
+// parseEnvIds splits a comma separated --envids value, ignoring empty and
+// duplicate entries
+func parseEnvIds(envIdsStr string) []string {
+	return parseIdList(envIdsStr)
+}
+
+// parseIdList splits a comma separated list of ids (e.g. --projids),
+// ignoring empty and duplicate entries
+func parseIdList(idsStr string) []string {
+	ids := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, id := range strings.Split(idsStr, ",") {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
+type envDeployResult struct {
+	envId    string
+	deployId string
+	state    string
+	err      error
+	skipped  bool
+}
+
+type envDeployOpts struct {
+	// number of environments deployed to at once
+	jobs     int
+	failFast bool
+	wait     bool
+	note     string
+	// timeout of the requests which upload pkg & start each deployment
+	deployTimeout time.Duration
+}
+
+// deployToEnvironments deploys pkg to each of envIds and prints a summary
+// of each environment's deploy id and state; it returns false if any
+// deployment failed. The package is uploaded once and then deployed to each
+// environment.
+func deployToEnvironments(pkg *bopsdk.Package, envIds []string,
+	opts envDeployOpts, sdkOpts []bopsdk.DeployOption) bool {
+
+	fmt.Printf("Uploading pkgId:%v (%v)...", pkg.Id, pkg.AbsTarballPath())
+	err := uploadPackage(pkg, sdkOpts, opts.deployTimeout)
+	if err != nil && isHttpTimeout(err) {
+		exitWithError(ExitServer, "\nTimed out after %v uploading the package; retry with a longer --deploy-timeout: %v\n",
+			opts.deployTimeout, err)
+	} else if err != nil {
+		exitWithError(ExitServer, "%v\n", err)
+	}
+	fmt.Printf("ok\nDeploying to %v environments (%v at a time)...\n",
+		len(envIds), opts.jobs)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var outputLock sync.Mutex
+	results := make([]*envDeployResult, len(envIds))
+	var wg errgroup.Group
+	wg.SetLimit(opts.jobs)
+	for idx, envId := range envIds {
+		result := &envDeployResult{envId: envId}
+		results[idx] = result
+
+		wg.Go(func() error {
+			if ctx.Err() != nil {
+				result.skipped = true
+				return nil
+			}
+
+			deployToEnvironment(ctx, pkg, result, opts, sdkOpts)
+
+			outputLock.Lock()
+			defer outputLock.Unlock()
+			if result.err != nil {
+				fmt.Printf("[%v] deployment failed: %v\n", envId, result.err)
+				if opts.failFast {
+					cancel()
+				}
+			} else {
+				fmt.Printf("[%v] deployId:%v %v\n", envId, result.deployId,
+					result.state)
+			}
+
+			return nil
+		})
+	}
+	_ = wg.Wait()
+
+	// record notes once every deployment has started rather than from each
+	// goroutine so that they're saved in one write
+	if opts.note != "" {
+		deployIds := make([]string, 0, len(results))
+		for _, result := range results {
+			if result.deployId != "" {
+				deployIds = append(deployIds, result.deployId)
+			}
+		}
+		if len(deployIds) > 0 {
+			err := setDeployNotes(deployIds, opts.note)
+			if err != nil {
+				logWarn("Could not save note for deployIds %v: %v", deployIds,
+					err)
+			}
+		}
+	}
+
+	allSucceeded := true
+	fmt.Printf("\nDeployment summary:\n")
+	for _, result := range results {
+		status := result.state
+		if result.skipped {
+			status = "SKIPPED"
+			allSucceeded = false
+		} else if result.err != nil {
+			status = fmt.Sprintf("FAILED (%v)", result.err)
+			allSucceeded = false
+		}
+		if result.deployId == "" {
+			fmt.Printf("\t%v: %v\n", result.envId, status)
+		} else {
+			fmt.Printf("\t%v: deployId:%v %v\n", result.envId,
+				result.deployId, status)
+		}
+	}
+	if !opts.wait {
+		fmt.Printf("\nDeploying takes about 10 minutes. You can check deploy progress with:\n\t'bopmatic deploy describe --deployid <deployId>'\n")
+	}
+
+	return allSucceeded
+}
+
+// deployToEnvironment starts the deployment of an already uploaded pkg to
+// result.envId and, when opts.wait is set, waits for it to complete
+func deployToEnvironment(ctx context.Context, pkg *bopsdk.Package,
+	result *envDeployResult, opts envDeployOpts,
+	sdkOpts []bopsdk.DeployOption) {
+
+	deployment := bopsdk.NewDeployment(pkg.Id, pkg.Proj.Desc.Id, result.envId)
+	result.err = deployment.Deploy(withDeployTimeout(sdkOpts,
+		opts.deployTimeout)...)
+	if result.err != nil {
+		return
+	}
+	result.deployId = deployment.DeployId
+	result.state = "STARTED"
+	if !opts.wait {
+		return
+	}
+
+	deployDesc, err := pollUntil(ctx, pollOptions{
+		interval:    DefaultPollInterval,
+		maxInterval: DefaultPollMaxInterval,
+		out:         os.Stdout,
+	}, func(ctx context.Context) (*pb.DeploymentDescription, error) {
+		return bopsdk.DescribeDeployment(result.deployId, sdkOpts...)
+	}, isTerminalDeployState,
+		func(deployDesc *pb.DeploymentDescription) string {
+			return fmt.Sprintf("[%v] deployment %v", result.envId,
+				deployDesc.State)
+		})
+	if deployDesc != nil {
+		result.state = deployDesc.State.String()
+	}
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			result.err = fmt.Errorf("stopped waiting; deployment continues in the background")
+		} else {
+			result.err = err
+		}
+		return
+	}
+	if deployDesc.State != pb.DeploymentState_SUCCESS {
+		result.err = fmt.Errorf("%v", deployDesc.StateDetail)
+	}
+}
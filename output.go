@@ -0,0 +1,278 @@
+/* Copyright © 2024 Bopmatic, LLC. All Rights Reserved.
+ *
+ * See LICENSE file at the root of this package for license terms
+ */
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	OutputText = "text"
+	OutputJson = "json"
+	OutputYaml = "yaml"
+)
+
+// exit codes returned by the CLI; ExitUsage matches what the flag package
+// uses when flag parsing fails
+const (
+	ExitOk       = 0
+	ExitFailure  = 1
+	ExitUsage    = 2
+	ExitAuth     = 3
+	ExitNotFound = 4
+	ExitServer   = 5
+)
+
+var outputFormat = OutputText
+
+type outputFlag struct{}
+
+func (o *outputFlag) String() string {
+	return outputFormat
+}
+
+func (o *outputFlag) Set(val string) error {
+	switch val {
+	case OutputText, OutputJson, OutputYaml:
+		outputFormat = val
+		return nil
+	}
+
+	return fmt.Errorf("invalid output format %v; must be one of %v, %v, or %v",
+		val, OutputText, OutputJson, OutputYaml)
+}
+
+// printPorcelain prints one --porcelain record: fields separated by single
+// spaces with no header. The field order of each command's porcelain output
+// is a stable contract for scripts; new fields may only be appended and a
+// field which may contain spaces must be last.
+func printPorcelain(fields ...any) {
+	fmt.Println(fields...)
+}
+
+// checkPorcelain rejects --porcelain in combination with a structured
+// --output format
+func checkPorcelain(porcelain bool) {
+	if porcelain && outputFormat != OutputText {
+		exitWithError(ExitUsage, "--porcelain cannot be combined with --output %v\n",
+			outputFormat)
+	}
+}
+
+// printStructured renders v to stdout as json or yaml. yaml is derived from
+// the json encoding so that both formats share field names and ordering.
+func printStructured(v any) error {
+	buf, err := renderStructured(v, outputFormat)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s", buf)
+
+	return nil
+}
+
+// renderStructured encodes v as yaml when format is OutputYaml and as json
+// otherwise
+func renderStructured(v any, format string) ([]byte, error) {
+	jsonBuf, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if format != OutputYaml {
+		return append(jsonBuf, '\n'), nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(jsonBuf))
+	dec.UseNumber()
+	orderedVal, err := decodeOrderedJson(dec)
+	if err != nil {
+		return nil, err
+	}
+
+	return yaml.Marshal(orderedVal)
+}
+
+// structFieldNames returns the names of v's exported, serialized fields in
+// declaration order
+func structFieldNames(v any) []string {
+	typ := reflect.Indirect(reflect.ValueOf(v)).Type()
+	names := make([]string, 0, typ.NumField())
+	for idx := 0; idx < typ.NumField(); idx++ {
+		field := typ.Field(idx)
+		if !field.IsExported() || field.Tag.Get("json") == "-" {
+			continue
+		}
+		names = append(names, field.Name)
+	}
+
+	return names
+}
+
+// parseFields validates a comma separated --fields value against the fields
+// of v (a describe command's structured output); names are matched case
+// insensitively and returned in their canonical form
+func parseFields(fieldsStr string, v any) ([]string, error) {
+	if fieldsStr == "" {
+		return nil, nil
+	}
+
+	validNames := structFieldNames(v)
+	fields := make([]string, 0)
+	for _, name := range strings.Split(fieldsStr, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		found := false
+		for _, validName := range validNames {
+			if strings.EqualFold(name, validName) {
+				fields = append(fields, validName)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("Unknown field %v; valid fields are: %v",
+				name, strings.Join(validNames, ","))
+		}
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("--fields requires at least one field; valid fields are: %v",
+			strings.Join(validNames, ","))
+	}
+
+	return fields, nil
+}
+
+// printFields prints each of fields from v as key=value, one per line.
+// Scalars are printed as is while lists and nested objects are printed as
+// compact json.
+func printFields(v any, fields []string) error {
+	val := reflect.Indirect(reflect.ValueOf(v))
+	for _, name := range fields {
+		fieldVal := val.FieldByName(name)
+		switch fieldVal.Kind() {
+		case reflect.Slice, reflect.Map, reflect.Struct, reflect.Pointer:
+			if fieldVal.Kind() == reflect.Pointer && fieldVal.IsNil() {
+				fmt.Printf("%v=\n", name)
+				continue
+			}
+			fieldJson, err := json.Marshal(fieldVal.Interface())
+			if err != nil {
+				return err
+			}
+			fmt.Printf("%v=%s\n", name, fieldJson)
+		default:
+			fmt.Printf("%v=%v\n", name, fieldVal.Interface())
+		}
+	}
+
+	return nil
+}
+
+// decodeOrderedJson decodes the next json value from dec, representing
+// objects as yaml.MapSlice (rather than a map) to preserve the json key
+// order and keep the yaml output deterministic
+func decodeOrderedJson(dec *json.Decoder) (any, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch val := tok.(type) {
+	case json.Delim:
+		if val == '{' {
+			fields := make(yaml.MapSlice, 0)
+			for dec.More() {
+				keyTok, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				fieldVal, err := decodeOrderedJson(dec)
+				if err != nil {
+					return nil, err
+				}
+				fields = append(fields, yaml.MapItem{Key: keyTok,
+					Value: fieldVal})
+			}
+			_, err = dec.Token() // consume '}'
+			return fields, err
+		}
+
+		elems := make([]any, 0)
+		for dec.More() {
+			elem, err := decodeOrderedJson(dec)
+			if err != nil {
+				return nil, err
+			}
+			elems = append(elems, elem)
+		}
+		_, err = dec.Token() // consume ']'
+		return elems, err
+	case json.Number:
+		intVal, err := val.Int64()
+		if err == nil {
+			return intVal, nil
+		}
+		return val.Float64()
+	default:
+		return val, nil
+	}
+}
+
+// nonNilStrings ensures empty lists render as [] rather than null
+func nonNilStrings(strs []string) []string {
+	if strs == nil {
+		return make([]string, 0)
+	}
+
+	return strs
+}
+
+type errorOutput struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// exitWithError reports an error to stderr in the current output format and
+// exits with the specified code
+func exitWithError(code int, format string, a ...any) {
+	msg := maskSecrets(strings.TrimRight(fmt.Sprintf(format, a...), "\n"))
+
+	if outputFormat == OutputJson {
+		var errOut errorOutput
+		errOut.Error.Code = code
+		errOut.Error.Message = msg
+		_ = json.NewEncoder(os.Stderr).Encode(&errOut)
+	} else {
+		fmt.Fprintf(os.Stderr, "%v\n", msg)
+	}
+
+	exit(code)
+}
+
+// cleanup run before the CLI exits, including via exitWithError()
+var exitHooks []func()
+
+func atExit(hook func()) {
+	exitHooks = append(exitHooks, hook)
+}
+
+func exit(code int) {
+	for idx := len(exitHooks) - 1; idx >= 0; idx-- {
+		exitHooks[idx]()
+	}
+
+	os.Exit(code)
+}
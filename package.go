@@ -5,9 +5,21 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	_ "embed"
 
@@ -17,10 +29,13 @@ import (
 
 var pkgSubCommandTab = map[string]func(args []string){
 	"build":    pkgBuildMain,
+	"rebuild":  pkgRebuildMain,
 	"deploy":   pkgDeployMain,
 	"list":     pkgListMain,
 	"delete":   pkgDeleteMain,
 	"describe": pkgDescribeMain,
+	"diff":     pkgDiffMain,
+	"ship":     pkgShipMain,
 	"help":     pkgHelpMain,
 }
 
@@ -51,108 +66,1053 @@ func pkgMain(args []string) {
 
 func pkgBuildMain(args []string) {
 	type buildOpts struct {
-		common commonOpts
+		common    commonOpts
+		watch     bool
+		recursive bool
+		failFast  bool
+		jobs      int
+		targets   stringListFlag
+		force     bool
+		platform  string
+		verbose   bool
+		summary   string
+		noPackage bool
+		keepGoing bool
+		manifest  bool
 	}
 
 	var opts buildOpts
 
 	f := flag.NewFlagSet("bopmatic package build", flag.ExitOnError)
 	setCommonFlags(f, &opts.common)
+	f.BoolVar(&opts.watch, "watch", false,
+		"Rebuild whenever project files change until interrupted")
+	f.BoolVar(&opts.recursive, "recursive", false,
+		"Build every project found beneath the current (or --projdir) directory")
+	f.BoolVar(&opts.failFast, "fail-fast", false,
+		"With --recursive, stop building after the first failure")
+	f.IntVar(&opts.jobs, "jobs", DefaultBuildJobs,
+		"With --recursive, the maximum number of projects to build at once")
+	f.Var(&opts.targets, "target",
+		"Only build the named service; may be repeated")
+	f.BoolVar(&opts.force, "force", false,
+		"Rebuild even when the project is unchanged since its last build")
+	f.StringVar(&opts.platform, "platform", "",
+		"Run the build container for this platform (e.g. linux/arm64) rather than the default linux/amd64")
+	f.BoolVar(&opts.verbose, "verbose-container", false,
+		"Show the build container's full output rather than only on failure")
+	f.StringVar(&opts.summary, "summary-json", "",
+		"Write a json report of the build's outcome to this file, even if the build fails")
+	// @todo stop after the compile/validation phase once the sdk exposes one
+	// rather than running the full build command
+	f.BoolVar(&opts.noPackage, "lint-only", false,
+		"Check for compile errors by running the full build command without packaging")
+	f.BoolVar(&opts.keepGoing, "keep-going", false,
+		"Build each service separately, continuing past failures, and report which failed")
+	f.BoolVar(&opts.manifest, "manifest", false,
+		"After building, list the package's top-level contents with their sizes")
 
 	err := f.Parse(args)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
-		os.Exit(1)
+		exitWithError(ExitUsage, "%v\n", err)
 	}
-	proj, err := bopsdk.NewProject(opts.common.projectFilename)
+	if opts.keepGoing && (opts.watch || opts.recursive) {
+		exitWithError(ExitUsage, "--keep-going cannot be combined with --watch or --recursive\n")
+	}
+	if opts.manifest && (opts.watch || opts.recursive || opts.noPackage) {
+		exitWithError(ExitUsage, "--manifest cannot be combined with --watch, --recursive, or --lint-only\n")
+	}
+	// build progress; kept off of stdout when it holds the structured manifest
+	var progress io.Writer = os.Stdout
+	if opts.manifest && outputFormat != OutputText {
+		progress = os.Stderr
+	}
+	showManifest := func(pkg *bopsdk.Package) {
+		if !opts.manifest || pkg == nil {
+			return
+		}
+		manifest, err := readPkgManifest(pkg)
+		if err != nil {
+			exitWithError(ExitFailure, "Failed to read package contents: %v\n",
+				err)
+		}
+		err = printPkgManifest(manifest)
+		if err != nil {
+			exitWithError(ExitFailure, "Failed to render package contents: %v\n",
+				err)
+		}
+	}
+	if opts.noPackage && (opts.watch || opts.recursive || opts.force) {
+		exitWithError(ExitUsage, "--lint-only cannot be combined with --watch, --recursive, or --force\n")
+	}
+	if opts.summary != "" && (opts.watch || opts.recursive) {
+		exitWithError(ExitUsage, "--summary-json cannot be combined with --watch or --recursive\n")
+	}
+
+	if opts.recursive {
+		if opts.watch {
+			exitWithError(ExitUsage, "--watch cannot be combined with --recursive\n")
+		}
+		if opts.jobs < 1 {
+			exitWithError(ExitUsage, "--jobs must be at least 1\n")
+		}
+		if len(opts.targets) > 0 {
+			exitWithError(ExitUsage, "--target cannot be combined with --recursive\n")
+		}
+		root := opts.common.projectDir
+		if root == "" {
+			root = "."
+		}
+		// flags which each project's build must honor
+		childArgs := make([]string, 0)
+		if opts.verbose {
+			childArgs = append(childArgs, "--verbose-container")
+		}
+		if opts.platform != "" {
+			childArgs = append(childArgs, "--platform", opts.platform)
+		}
+		if opts.force {
+			childArgs = append(childArgs, "--force")
+		}
+		if !buildAllProjects(root, filepath.Base(opts.common.projectFilename),
+			opts.jobs, opts.failFast, childArgs) {
+			exit(ExitFailure)
+		}
+		return
+	}
+
+	summary := buildSummary{startTime: time.Now()}
+	// record the failure in the --summary-json report before exiting
+	buildFailed := func(code int, format string, a ...any) {
+		if opts.summary != "" {
+			summary.Error = strings.TrimRight(fmt.Sprintf(format, a...), "\n")
+			err := summary.write(opts.summary)
+			if err != nil {
+				logWarn("%v", err)
+			}
+		}
+		exitWithError(code, format, a...)
+	}
+	buildSucceeded := func(pkg *bopsdk.Package) {
+		if opts.summary == "" {
+			return
+		}
+		summary.Success = true
+		summary.setPackage(pkg)
+		err := summary.write(opts.summary)
+		if err != nil {
+			exitWithError(ExitFailure, "%v\n", err)
+		}
+	}
+
+	proj, err := loadProject(opts.common.projFile())
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
-		os.Exit(1)
+		buildFailed(ExitUsage, "%v\n", err)
+	}
+	summary.Project = proj.Desc.Name
+	err = validateBuildTargets(proj, opts.targets)
+	if err != nil {
+		buildFailed(ExitUsage, "%v\n", err)
+	}
+	if opts.platform != "" {
+		err = validateBuildPlatform(opts.platform)
+		if err != nil {
+			buildFailed(ExitUsage, "%v\n", err)
+		}
 	}
 
 	if proj.Desc.BuildCmd == "" {
-		fmt.Printf("Project %v is a static site only; no build required\n",
+		fmt.Fprintf(progress, "Project %v is a static site only; no build required\n",
 			proj.Desc.Name)
+		buildSucceeded(nil)
 		os.Exit(0)
 	}
 
-	err = proj.Build(os.Stdout, os.Stderr)
+	// partial and non-default platform builds don't produce the package a
+	// default build would, so they neither use nor update the cache
+	cacheable := len(opts.targets) == 0 && opts.platform == "" &&
+		!opts.noPackage && !opts.keepGoing
+	if cacheable && !opts.watch && !opts.force {
+		pkg := findCachedPackage(proj)
+		if pkg != nil {
+			fmt.Fprintf(progress, "No changes; reusing pkgId:%v (%v)\n", pkg.Id,
+				pkg.AbsTarballPath())
+			fmt.Fprintf(progress, "To deploy your package, next run:\n\t'bopmatic package deploy'\n")
+			summary.Cached = true
+			buildSucceeded(pkg)
+			showManifest(pkg)
+			return
+		}
+	}
+
+	if opts.platform != "" {
+		err = applyBuildPlatform(opts.platform)
+		if err != nil {
+			buildFailed(ExitFailure, "%v\n", err)
+		}
+	}
+
+	if opts.watch {
+		watchAndBuild(opts.common.projFile(), opts.targets, opts.verbose)
+		return
+	}
+	if opts.keepGoing {
+		pkg, err := buildServicesKeepGoing(opts.common.projFile(),
+			opts.targets, opts.verbose, opts.noPackage, progress)
+		if err != nil {
+			buildFailed(ExitFailure, "%v\n", err)
+		}
+		if pkg != nil && len(opts.targets) > 0 {
+			fmt.Fprintf(progress, "Note: the package still contains every service; deploying it deploys\nthe full project including services which were not rebuilt.\n")
+		}
+		buildSucceeded(pkg)
+		if pkg != nil {
+			fmt.Fprintf(progress, "To deploy your package, next run:\n\t'bopmatic package deploy'\n")
+		}
+		showManifest(pkg)
+		return
+	}
+	if opts.noPackage {
+		_, err = buildProject(opts.common.projFile(), opts.targets,
+			opts.verbose, true, progress)
+		if err != nil {
+			buildFailed(ExitFailure, "%v\n", err)
+		}
+		buildSucceeded(nil)
+		return
+	}
+
+	pkg, err := buildProject(opts.common.projFile(), opts.targets,
+		opts.verbose, false, progress)
+	if err != nil {
+		buildFailed(ExitFailure, "%v\n", err)
+	}
+	if len(opts.targets) > 0 {
+		fmt.Fprintf(progress, "Note: the package still contains every service; deploying it deploys\nthe full project including services which were not rebuilt.\n")
+	} else if cacheable {
+		err = cacheBuiltPackage(proj, pkg)
+		if err != nil {
+			logWarn("Could not record build in cache: %v", err)
+		}
+	}
+	buildSucceeded(pkg)
+	fmt.Fprintf(progress, "To deploy your package, next run:\n\t'bopmatic package deploy'\n")
+	showManifest(pkg)
+}
+
+// buildSummary is the report written by 'bopmatic package build
+// --summary-json' for consumption by CI
+type buildSummary struct {
+	Project         string  `json:"project"`
+	PackageId       string  `json:"packageId,omitempty"`
+	TarballPath     string  `json:"tarballPath,omitempty"`
+	TarballSize     int64   `json:"tarballSize,omitempty"`
+	DurationSeconds float64 `json:"durationSeconds"`
+	// the package was reused from the build cache rather than rebuilt
+	Cached  bool   `json:"cached,omitempty"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+
+	startTime time.Time
+}
+
+func (summary *buildSummary) setPackage(pkg *bopsdk.Package) {
+	if pkg == nil {
+		return
+	}
+	summary.PackageId = pkg.Id
+	summary.TarballPath = pkg.AbsTarballPath()
+	fileInfo, err := os.Stat(summary.TarballPath)
+	if err == nil {
+		summary.TarballSize = fileInfo.Size()
+	}
+}
+
+func (summary *buildSummary) write(summaryFile string) error {
+	summary.DurationSeconds = time.Since(summary.startTime).Seconds()
+	summaryData, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(summaryFile, append(summaryData, '\n'), 0644)
+	if err != nil {
+		return fmt.Errorf("Could not write build summary %v: %w",
+			summaryFile, err)
+	}
+
+	return nil
+}
+
+// pkgRebuildMain discards all of the project's local packages along with
+// its build cache entry and then builds a fresh package
+func pkgRebuildMain(args []string) {
+	type rebuildOpts struct {
+		common  commonOpts
+		verbose bool
+	}
+
+	var opts rebuildOpts
+
+	f := flag.NewFlagSet("bopmatic package rebuild", flag.ExitOnError)
+	setCommonFlags(f, &opts.common)
+	f.BoolVar(&opts.verbose, "verbose-container", false,
+		"Show the build container's full output rather than only on failure")
+
+	err := f.Parse(args)
+	if err != nil {
+		exitWithError(ExitUsage, "%v\n", err)
+	}
+
+	proj, err := loadProject(opts.common.projFile())
+	if err != nil {
+		exitWithError(ExitUsage, "%v\n", err)
+	}
+
+	err = proj.RemoveStalePackages()
+	if err != nil {
+		exitWithError(ExitFailure, "Failed to remove local packages: %v\n", err)
+	}
+	err = forgetCachedPackage(proj)
+	if err != nil {
+		logWarn("Could not clear build cache: %v", err)
+	}
+	fmt.Printf("Removed local packages for %v\n", proj.Desc.Name)
+
+	if proj.Desc.BuildCmd == "" {
+		fmt.Printf("Project %v is a static site only; no build required\n",
+			proj.Desc.Name)
+		return
+	}
+
+	pkg, err := buildAndPackage(opts.common.projFile(), nil, opts.verbose)
+	if err != nil {
+		exitWithError(ExitFailure, "%v\n", err)
+	}
+	err = cacheBuiltPackage(proj, pkg)
+	if err != nil {
+		logWarn("Could not record build in cache: %v", err)
+	}
+	fmt.Printf("To deploy your package, next run:\n\t'bopmatic package deploy'\n")
+}
+
+func validateBuildTargets(proj *bopsdk.Project, targets []string) error {
+	for _, target := range targets {
+		found := false
+		for _, svc := range proj.Desc.Services {
+			if svc.Name == target {
+				found = true
+				break
+			}
+		}
+		if !found {
+			svcList := make([]string, 0)
+			for _, svc := range proj.Desc.Services {
+				svcList = append(svcList, svc.Name)
+			}
+			return fmt.Errorf("Unknown --target %v; project %v has services: %v",
+				target, proj.Desc.Name, svcList)
+		}
+	}
+
+	return nil
+}
+
+// buildProjectTargets runs the project's build command within the build
+// container, passing any target service names as arguments to the build
+// command (e.g. 'make svc1 svc2'). Unlike the sdk's Project.Build it runs
+// within getBuildImageName() so that a pinned build image is honored.
+// @todo move into the sdk's Project.Build once it supports targets & images
+func buildProjectTargets(proj *bopsdk.Project, targets []string,
+	stdOut io.Writer, stdErr io.Writer) error {
+	curWd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	err = os.Chdir(proj.Desc.GetRoot())
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Chdir(curWd) }()
+
+	if proj.Desc.BuildCmd == "" {
+		return fmt.Errorf("Project %v has no buildcmd to build %v with",
+			proj.Desc.Name, targets)
+	}
+	buildArgv := buildCmdArgv(proj.Desc.BuildCmd, targets)
+	if len(targets) > 0 {
+		fmt.Fprintf(stdOut, "Building targets %v: %v\n", targets,
+			strings.Join(buildArgv, " "))
+	}
+
+	return runBuildContainerCommand(context.Background(), buildArgv, stdOut,
+		stdErr)
+}
+
+// buildCmdArgv splits buildCmd into the argv run within the build container
+// and appends each of targets as its own argument; the container runs argv
+// directly rather than via a shell
+func buildCmdArgv(buildCmd string, targets []string) []string {
+	return append(strings.Fields(buildCmd), targets...)
+}
+
+// containerLog collects the interleaved stdout & stderr of the build
+// container so that it can be shown only if the build fails
+type containerLog struct {
+	lock sync.Mutex
+	buf  bytes.Buffer
+}
+
+func (l *containerLog) Write(p []byte) (int, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.buf.Write(p)
+}
+
+// buildAndPackage builds the project described by projectFilename (or only
+// the specified target services) and creates a new package from the result.
+// Unless verboseContainer is set, the build container's output is only
+// shown if the build fails.
+func buildAndPackage(projectFilename string, targets []string,
+	verboseContainer bool) (*bopsdk.Package, error) {
+
+	return buildProject(projectFilename, targets, verboseContainer, false,
+		os.Stdout)
+}
+
+// buildProject implements buildAndPackage, reporting progress (and with
+// verboseContainer, the build container's stdout) to progress; with
+// noPackage it stops once the build command succeeds, neither removing stale
+// packages nor creating a new one, and returns a nil package
+func buildProject(projectFilename string, targets []string,
+	verboseContainer bool, noPackage bool,
+	progress io.Writer) (*bopsdk.Package, error) {
+
+	// re-read the project each time so that --watch picks up edits to the
+	// project file
+	proj, err := loadProject(projectFilename)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to build %v: %v\n", proj.Desc.Name, err)
-		os.Exit(1)
+		return nil, err
+	}
+
+	var stdOut, stdErr io.Writer = progress, os.Stderr
+	var buildLog *containerLog
+	if !verboseContainer {
+		buildLog = &containerLog{}
+		stdOut, stdErr = buildLog, buildLog
+		fmt.Fprintf(progress, "Building %v...", proj.Desc.Name)
+	}
+	startTime := time.Now()
+	failed := func(format string, a ...any) error {
+		if buildLog != nil {
+			fmt.Fprintf(progress, "failed\n")
+			fmt.Fprintf(os.Stderr, "========== build container output ==========\n%s",
+				buildLog.buf.Bytes())
+			fmt.Fprintf(os.Stderr, "============================================\n")
+		}
+		return fmt.Errorf(format, a...)
+	}
+
+	if len(targets) > 0 {
+		err = validateBuildTargets(proj, targets)
+		if err == nil {
+			err = buildProjectTargets(proj, targets, stdOut, stdErr)
+		}
+	} else if proj.Desc.BuildCmd != "" {
+		err = buildProjectTargets(proj, nil, stdOut, stdErr)
+	}
+	if err != nil {
+		return nil, failed("Failed to build %v: %w", proj.Desc.Name, err)
+	}
+	if noPackage {
+		if buildLog != nil {
+			fmt.Fprintf(progress, "done in %v\n", time.Since(startTime).Round(time.Second))
+		}
+		fmt.Fprintf(progress, "Built %v; no package was created\n",
+			proj.Desc.Name)
+		return nil, nil
 	}
 
 	err = proj.RemoveStalePackages()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to remove stale packages: %v\n", err)
-		os.Exit(1)
+		return nil, failed("Failed to remove stale packages: %w", err)
 	}
 
-	pkg, err := proj.NewPackageCreate("", os.Stdout, os.Stderr)
+	pkg, err := proj.NewPackageCreate("", stdOut, stdErr)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to package %v: %v\n", proj.Desc.Name, err)
-		os.Exit(1)
+		return nil, failed("Failed to package %v: %w", proj.Desc.Name, err)
+	}
+	if buildLog != nil {
+		fmt.Fprintf(progress, "done in %v (use --verbose-container to see build output)\n",
+			time.Since(startTime).Round(time.Second))
 	}
 
-	fmt.Printf("Successfully built pkgId:%v (%v)\n", pkg.Id,
+	fmt.Fprintf(progress, "Successfully built pkgId:%v (%v)\n", pkg.Id,
 		pkg.AbsTarballPath())
-	fmt.Printf("To deploy your package, next run:\n\t'bopmatic package deploy'\n")
+
+	return pkg, nil
+}
+
+// serviceBuildResult is one service's outcome within a --keep-going build
+type serviceBuildResult struct {
+	service  string
+	duration time.Duration
+	err      error
+}
+
+// buildServicesKeepGoing builds each of the project's services (or only the
+// specified target services) separately so that one service failing to
+// build doesn't prevent the rest from being built. Once every service has
+// been attempted the outcomes are reported together. Only if every service
+// built and noPackage isn't set is the project then built & packaged as
+// buildAndPackage would, so that anything the build command does beyond
+// building individual services is part of the package.
+func buildServicesKeepGoing(projectFilename string, targets []string,
+	verboseContainer bool, noPackage bool,
+	progress io.Writer) (*bopsdk.Package, error) {
+
+	proj, err := loadProject(projectFilename)
+	if err != nil {
+		return nil, err
+	}
+	err = validateBuildTargets(proj, targets)
+	if err != nil {
+		return nil, err
+	}
+	services := targets
+	if len(services) == 0 {
+		for _, svc := range proj.Desc.Services {
+			services = append(services, svc.Name)
+		}
+	}
+
+	results := make([]serviceBuildResult, 0, len(services))
+	failedSvcs := make([]string, 0)
+	for _, svcName := range services {
+		var stdOut, stdErr io.Writer = progress, os.Stderr
+		var buildLog *containerLog
+		if !verboseContainer {
+			buildLog = &containerLog{}
+			stdOut, stdErr = buildLog, buildLog
+			fmt.Fprintf(progress, "Building service %v...", svcName)
+		}
+		startTime := time.Now()
+		err := buildProjectTargets(proj, []string{svcName}, stdOut, stdErr)
+		result := serviceBuildResult{
+			service:  svcName,
+			duration: time.Since(startTime).Round(time.Second),
+			err:      err,
+		}
+		results = append(results, result)
+		if err != nil {
+			failedSvcs = append(failedSvcs, svcName)
+		}
+		if buildLog == nil {
+			continue
+		}
+		if err == nil {
+			fmt.Fprintf(progress, "done in %v\n", result.duration)
+			continue
+		}
+		fmt.Fprintf(progress, "failed\n")
+		fmt.Fprintf(os.Stderr, "========== %v build container output ==========\n%s",
+			svcName, buildLog.buf.Bytes())
+		fmt.Fprintf(os.Stderr, "============================================\n")
+	}
+
+	fmt.Fprintf(progress, "\nBuild results for %v:\n", proj.Desc.Name)
+	for _, result := range results {
+		if result.err != nil {
+			fmt.Fprintf(progress, "\t%v: FAILED after %v: %v\n", result.service,
+				result.duration, result.err)
+		} else {
+			fmt.Fprintf(progress, "\t%v: ok (%v)\n", result.service, result.duration)
+		}
+	}
+	if len(failedSvcs) > 0 {
+		return nil, fmt.Errorf("%v of %v services failed to build: %v",
+			len(failedSvcs), len(services), strings.Join(failedSvcs, ", "))
+	}
+	if noPackage {
+		fmt.Fprintf(progress, "Every service built; no package was created\n")
+		return nil, nil
+	}
+
+	fmt.Fprintf(progress, "Every service built; building & packaging %v\n",
+		proj.Desc.Name)
+
+	return buildProject(projectFilename, targets, verboseContainer, false,
+		progress)
 }
 
 func pkgDeployMain(args []string) {
 	sdkOpts, err := getAuthSdkOpts()
 	if err != nil {
-		fmt.Fprintf(os.Stderr,
-			"Failed to get user creds; did you run bompatic config? err: %v\n",
-			err)
-		os.Exit(1)
+		exitWithError(ExitAuth, "%v\n", err)
 	}
 
 	type deployOpts struct {
-		common commonOpts
+		common        commonOpts
+		wait          bool
+		detach        bool
+		note          string
+		envIds        string
+		failFast      bool
+		confirmProd   bool
+		verify        bool
+		verifyTimeout time.Duration
+		reuseLatest   bool
+		deployTimeout time.Duration
 	}
 
 	var opts deployOpts
 
 	f := flag.NewFlagSet("bopmatic package deploy", flag.ExitOnError)
 	setCommonFlags(f, &opts.common)
+	setLockFlags(f)
+	f.BoolVar(&opts.wait, "wait", false,
+		"Wait for the deployment to complete before returning")
+	f.BoolVar(&opts.detach, "detach", false,
+		"Return once the deployment starts, even if config sets deploy.wait_default")
+	f.StringVar(&opts.note, "note", "",
+		"Annotate the deployment (e.g. \"hotfix for issue #123\"); shown by 'bopmatic deploy describe'")
+	f.StringVar(&opts.envIds, "envids", "",
+		"Comma separated environment ids to deploy to (e.g. staging,canary); defaults to your project's prod environment")
+	f.BoolVar(&opts.failFast, "fail-fast", false,
+		"With several --envids, stop deploying to further environments after the first failure")
+	f.BoolVar(&opts.confirmProd, "confirm-prod", false,
+		"Acknowledge deploying to a protected environment without prompting")
+	f.BoolVar(&opts.verify, "verify", false,
+		"With --wait, check that the site endpoint is serving once the deployment succeeds")
+	f.DurationVar(&opts.verifyTimeout, "verify-timeout", DefaultVerifyTimeout,
+		"How long --verify waits for the site endpoint to respond")
+	f.BoolVar(&opts.reuseLatest, "reuse-latest", false,
+		"Deploy the project's most recently uploaded BUILT package rather than a local package")
+	setDeployHookFlags(f)
+	f.DurationVar(&opts.deployTimeout, "deploy-timeout", DefaultSrHttpTimeout,
+		"Timeout of the package upload and of each request which starts the deployment (e.g. 10m for a large package on a slow link)")
 
 	err = f.Parse(args)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
-		os.Exit(1)
+		exitWithError(ExitUsage, "%v\n", err)
 	}
-	proj, err := bopsdk.NewProject(opts.common.projectFilename)
+	wait, err := resolveDeployWait(f, opts.wait, opts.detach)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
-		os.Exit(1)
+		exitWithError(ExitUsage, "%v\n", err)
 	}
-
-	pkg, err := proj.NewPackageExisting("")
+	envIds := parseEnvIds(opts.envIds)
+	if opts.verify {
+		if !wait {
+			exitWithError(ExitUsage, "--verify requires --wait\n")
+		}
+		if len(envIds) > 1 {
+			exitWithError(ExitUsage, "--verify cannot be combined with multiple --envids\n")
+		}
+	}
+	if deployCompletionHooks.isSet() {
+		if !wait {
+			exitWithError(ExitUsage, "--on-success and --on-failure require --wait\n")
+		}
+		if len(envIds) > 1 {
+			exitWithError(ExitUsage, "--on-success and --on-failure cannot be combined with multiple --envids\n")
+		}
+	}
+	if opts.deployTimeout <= 0 {
+		exitWithError(ExitUsage, "--deploy-timeout must be positive\n")
+	}
+	if opts.reuseLatest && len(envIds) > 1 {
+		exitWithError(ExitUsage, "--reuse-latest cannot be combined with multiple --envids\n")
+	}
+	err = confirmProtectedEnvs(envIds, opts.confirmProd)
 	if err != nil {
-		_ = proj.RemoveStalePackages()
+		exitWithError(ExitUsage, "%v\n", err)
+	}
+	// environments are deployed to one at a time unless --parallel is
+	// explicitly specified
+	envJobs := 1
+	f.Visit(func(fl *flag.Flag) {
+		if fl.Name == "parallel" {
+			envJobs = parallelLimit
+		}
+	})
+	var projId, pkgId string
+	var pkg *bopsdk.Package
+	if opts.reuseLatest {
+		// deploy-only; neither the project's sources nor docker are needed
+		projId = opts.common.projectId
+		if projId == "" {
+			projId, err = resolveProjectId(opts.common.projFile())
+			if err != nil {
+				exitWithError(ExitUsage, "%v\n", err)
+			}
+		}
+		pkgId, err = findLatestBuiltPackage(projId, sdkOpts)
+		if errors.Is(err, errNoBuiltPackage) {
+			exitWithError(ExitNotFound, "%v\n", err)
+		} else if err != nil {
+			exitWithError(ExitServer, "%v\n", err)
+		}
+	} else {
+		proj, err := loadProject(opts.common.projFile())
+		if err != nil {
+			exitWithError(ExitUsage, "%v\n", err)
+		}
 
-		pkg, err = proj.NewPackageCreate("", os.Stdout, os.Stderr)
+		pkg, err = proj.NewPackageExisting("")
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to package %v: %v\n", proj.Desc.Name, err)
-			os.Exit(1)
+			_ = proj.RemoveStalePackages()
+
+			pkg, err = proj.NewPackageCreate("", os.Stdout, os.Stderr)
+			if err != nil {
+				exitWithError(ExitFailure, "Failed to package %v: %v\n", proj.Desc.Name, err)
+			}
 		}
+		projId = proj.Desc.Id
+		pkgId = pkg.Id
 	}
 
-	validateNoConflicts(sdkOpts, pkg)
+	defer lockProjectOrExit(projId)()
+	if pkg != nil {
+		validateNoConflicts(sdkOpts, pkg)
+	}
 
-	fmt.Printf("Deploying pkgId:%v (%v)...", pkg.Id, pkg.AbsTarballPath())
-	// @todo specify envId
-	deployId, err := pkg.Deploy("", sdkOpts...)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
-		os.Exit(1)
+	if len(envIds) > 1 {
+		if !deployToEnvironments(pkg, envIds, envDeployOpts{
+			jobs:          envJobs,
+			failFast:      opts.failFast,
+			wait:          wait,
+			note:          opts.note,
+			deployTimeout: opts.deployTimeout,
+		}, sdkOpts) {
+			exit(ExitFailure)
+		}
+		return
+	}
+	envId := ""
+	if len(envIds) == 1 {
+		envId = envIds[0]
+	}
+
+	deploySdkOpts := withDeployTimeout(sdkOpts, opts.deployTimeout)
+	var deployId string
+	if pkg != nil {
+		fmt.Printf("Deploying pkgId:%v (%v)...", pkg.Id, pkg.AbsTarballPath())
+		err = uploadPackage(pkg, sdkOpts, opts.deployTimeout)
+		if err == nil {
+			deployment := bopsdk.NewDeployment(pkg.Id, pkg.Proj.Desc.Id,
+				envId)
+			err = deployment.Deploy(deploySdkOpts...)
+			deployId = deployment.DeployId
+		}
+	} else {
+		fmt.Printf("Deploying previously built pkgId:%v...", pkgId)
+		deployment := bopsdk.NewDeployment(pkgId, projId, envId)
+		err = deployment.Deploy(deploySdkOpts...)
+		deployId = deployment.DeployId
+	}
+	if err != nil && isHttpTimeout(err) {
+		exitWithError(ExitServer, "\nTimed out after %v uploading the package or starting the deployment; retry with a longer --deploy-timeout: %v\n",
+			opts.deployTimeout, err)
+	} else if err != nil {
+		exitWithError(ExitServer, "\nFailed to start deployment: %v\n", err)
+	}
+	if opts.note != "" {
+		err = setDeployNote(deployId, opts.note)
+		if err != nil {
+			logWarn("Could not save note for deployId:%v: %v", deployId, err)
+		}
+	}
+
+	if wait {
+		fmt.Printf("Started deployId:%v\n", deployId)
+		waitForDeployment(deployId, "deployment", sdkOpts)
+		fmt.Printf("Deployed pkgId:%v via deployId:%v\n", pkgId, deployId)
+		if opts.verify {
+			err = verifySiteEndpoint(projId, envId, opts.verifyTimeout,
+				sdkOpts)
+			if err != nil {
+				exitWithError(ExitFailure, "%v\n", err)
+			}
+		}
+		return
 	}
 
 	fmt.Printf("Started\nDeploying takes about 10 minutes. You can check deploy progress with:\n\t'bopmatic deploy describe --deployid %v'\n",
 		deployId)
 }
 
+// withDeployTimeout returns a copy of sdkOpts whose requests time out after
+// timeout rather than DefaultSrHttpTimeout; the sdk uses the last http
+// client option it's given
+func withDeployTimeout(sdkOpts []bopsdk.DeployOption,
+	timeout time.Duration) []bopsdk.DeployOption {
+
+	return append(append([]bopsdk.DeployOption{}, sdkOpts...),
+		bopsdk.DeployOptHttpClient(newSrHttpClientWithTimeout(timeout)))
+}
+
+// uploadPackage uploads pkg, giving up after timeout. The sdk PUTs the
+// tarball with its own http client rather than the one in sdkOpts, so the
+// upload as a whole is bounded here; an abandoned upload ends when the CLI
+// exits.
+func uploadPackage(pkg *bopsdk.Package, sdkOpts []bopsdk.DeployOption,
+	timeout time.Duration) error {
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- pkg.Upload(withDeployTimeout(sdkOpts, timeout)...)
+	}()
+	select {
+	case err := <-errCh:
+		return err
+	case <-time.After(timeout):
+		return fmt.Errorf("upload of pkgId:%v did not finish: %w", pkg.Id,
+			context.DeadlineExceeded)
+	}
+}
+
+var errNoBuiltPackage = errors.New("no BUILT package found")
+
+// findLatestBuiltPackage returns the id of projId's most recently uploaded
+// package which ServiceRunner has successfully built
+func findLatestBuiltPackage(projId string,
+	sdkOpts []bopsdk.DeployOption) (string, error) {
+
+	pkgs, err := bopsdk.ListPackages(projId, sdkOpts...)
+	if err != nil {
+		return "", err
+	}
+
+	pkgDescList := make([]*pb.PackageDescription, len(pkgs))
+	wg := newFanOutGroup()
+	for idx := range pkgs {
+		pkg := &pkgs[idx]
+		wg.Go(func() error {
+			pkgDesc, err := bopsdk.Describe(pkg.PackageId, sdkOpts...)
+			if err != nil {
+				return fmt.Errorf("%v: %w", pkg.PackageId, err)
+			}
+			pkgDescList[idx] = pkgDesc
+			return nil
+		})
+	}
+	err = wg.Wait()
+	if err != nil {
+		return "", err
+	}
+
+	var latest *pb.PackageDescription
+	for _, pkgDesc := range pkgDescList {
+		if pkgDesc.State != pb.PackageState_BUILT {
+			continue
+		}
+		if latest == nil || pkgDesc.UploadTime > latest.UploadTime {
+			latest = pkgDesc
+		}
+	}
+	if latest == nil {
+		return "", fmt.Errorf("%w for project %v; build and deploy one with 'bopmatic package deploy'",
+			errNoBuiltPackage, projId)
+	}
+
+	return latest.PackageId, nil
+}
+
+// resolveDeployWait decides whether a deploy should wait for completion.
+// An explicitly specified --wait or --detach takes precedence over
+// deploy.wait_default from the config file, which in turn defaults to not
+// waiting.
+func resolveDeployWait(f *flag.FlagSet, wait bool, detach bool) (bool,
+	error) {
+
+	waitSet := false
+	detachSet := false
+	f.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "wait":
+			waitSet = true
+		case "detach":
+			detachSet = true
+		}
+	})
+
+	if waitSet && detachSet {
+		if wait == detach {
+			return false, fmt.Errorf("--wait=%v conflicts with --detach=%v",
+				wait, detach)
+		}
+		return wait, nil
+	} else if waitSet {
+		return wait, nil
+	} else if detachSet {
+		return !detach, nil
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return false, err
+	}
+
+	return cfg.Deploy != nil && cfg.Deploy.WaitDefault, nil
+}
+
+// configDeployWaitMain shows or sets whether deploys wait for completion
+// by default
+func configDeployWaitMain(args []string) {
+	f := flag.NewFlagSet("bopmatic config deploy-wait", flag.ExitOnError)
+	setGlobalFlags(f)
+	err := f.Parse(args)
+	if err != nil {
+		exitWithError(ExitUsage, "%v\n", err)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		exitWithError(ExitFailure, "%v\n", err)
+	}
+
+	if f.NArg() == 0 {
+		fmt.Printf("%v\n", cfg.Deploy != nil && cfg.Deploy.WaitDefault)
+		return
+	}
+
+	waitDefault, err := strconv.ParseBool(f.Arg(0))
+	if err != nil {
+		exitWithError(ExitUsage, "Expected true or false; got %v\n", f.Arg(0))
+	}
+	err = updateConfig(func(cfg *cliConfig) error {
+		if cfg.Deploy == nil {
+			cfg.Deploy = &deployConfig{}
+		}
+		cfg.Deploy.WaitDefault = waitDefault
+		if cfg.Deploy.isEmpty() {
+			cfg.Deploy = nil
+		}
+		return nil
+	})
+	if err != nil {
+		exitWithError(ExitFailure, "%v\n", err)
+	}
+
+	if waitDefault {
+		fmt.Printf("'bopmatic package deploy' now waits for completion unless --detach is specified\n")
+	} else {
+		fmt.Printf("'bopmatic package deploy' now returns once the deployment starts unless --wait is specified\n")
+	}
+}
+
+// DefaultEnvName is how the protected environment list refers to a
+// project's default environment, which deploys target when no environment
+// id is specified
+const DefaultEnvName = "prod"
+
+// getProtectedEnvs returns the environment ids from the config file which
+// require confirmation to deploy to
+func getProtectedEnvs() ([]string, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Deploy == nil {
+		return nil, nil
+	}
+
+	return cfg.Deploy.ProtectedEnvs, nil
+}
+
+// confirmProtectedEnvs ensures that deploying to any protected environment
+// among envIds was acknowledged, either via --confirm-prod or by typing the
+// environment's name when interactive. An empty envId is the project's
+// default environment.
+func confirmProtectedEnvs(envIds []string, confirmProd bool) error {
+	protectedEnvs, err := getProtectedEnvs()
+	if err != nil {
+		return err
+	}
+	if len(envIds) == 0 {
+		envIds = []string{""}
+	}
+
+	for _, envId := range envIds {
+		envName := envId
+		if envName == "" {
+			envName = DefaultEnvName
+		}
+		protected := false
+		for _, protectedEnv := range protectedEnvs {
+			if protectedEnv == envName {
+				protected = true
+				break
+			}
+		}
+		if !protected || confirmProd {
+			continue
+		}
+
+		if !stdinIsTerminal() {
+			return fmt.Errorf("Environment %v is protected; re-run with --confirm-prod to deploy to it",
+				envName)
+		}
+		fmt.Printf("Environment %v is protected.\n", envName)
+		if !confirmByTypedName("environment", envName) {
+			return fmt.Errorf("Confirmation did not match; not deploying to %v",
+				envName)
+		}
+	}
+
+	return nil
+}
+
+// configProtectedEnvsMain shows or sets the environments which require
+// confirmation to deploy to
+func configProtectedEnvsMain(args []string) {
+	f := flag.NewFlagSet("bopmatic config protected-envs", flag.ExitOnError)
+	setGlobalFlags(f)
+	err := f.Parse(args)
+	if err != nil {
+		exitWithError(ExitUsage, "%v\n", err)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		exitWithError(ExitFailure, "%v\n", err)
+	}
+
+	if f.NArg() == 0 {
+		if cfg.Deploy == nil || len(cfg.Deploy.ProtectedEnvs) == 0 {
+			fmt.Printf("No protected environments\n")
+		} else {
+			fmt.Printf("%v\n", strings.Join(cfg.Deploy.ProtectedEnvs, ","))
+		}
+		return
+	}
+
+	var protectedEnvs []string
+	if f.Arg(0) != "none" {
+		protectedEnvs = parseEnvIds(f.Arg(0))
+	}
+	err = updateConfig(func(cfg *cliConfig) error {
+		if cfg.Deploy == nil {
+			cfg.Deploy = &deployConfig{}
+		}
+		cfg.Deploy.ProtectedEnvs = protectedEnvs
+		if cfg.Deploy.isEmpty() {
+			cfg.Deploy = nil
+		}
+		return nil
+	})
+	if err != nil {
+		exitWithError(ExitFailure, "%v\n", err)
+	}
+
+	if len(protectedEnvs) == 0 {
+		fmt.Printf("No environments are protected\n")
+	} else {
+		fmt.Printf("Deploying to %v now requires --confirm-prod or a typed confirmation\n",
+			strings.Join(protectedEnvs, ", "))
+	}
+}
+
 func validateNoConflicts(sdkOpts []bopsdk.DeployOption, pkg *bopsdk.Package) {
 	// @todo for UX purposes consider evaluating conflicts client-side here
 	// rather than just relying on server-side conflict checks
@@ -161,57 +1121,183 @@ func validateNoConflicts(sdkOpts []bopsdk.DeployOption, pkg *bopsdk.Package) {
 func pkgListMain(args []string) {
 	sdkOpts, err := getAuthSdkOpts()
 	if err != nil {
-		fmt.Fprintf(os.Stderr,
-			"Failed to get user creds; did you run bompatic config? err: %v\n",
-			err)
-		os.Exit(1)
+		exitWithError(ExitAuth, "%v\n", err)
 	}
 
 	type listOpts struct {
-		common commonOpts
+		common    commonOpts
+		porcelain bool
+		projIds   string
+		count     bool
+		byState   bool
 	}
 
 	var opts listOpts
 
 	f := flag.NewFlagSet("bopmatic package list", flag.ExitOnError)
 	setCommonFlags(f, &opts.common)
+	f.BoolVar(&opts.porcelain, "porcelain", false,
+		"Print a stable, header-less, space separated format for scripts")
+	f.StringVar(&opts.projIds, "projids", "",
+		"Comma separated project ids whose packages are listed together")
+	f.BoolVar(&opts.count, "count", false,
+		"Print only the number of packages")
+	f.BoolVar(&opts.byState, "by-state", false,
+		"With --count, break the number of packages down by state")
 
 	err = f.Parse(args)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
-		os.Exit(1)
+		exitWithError(ExitUsage, "%v\n", err)
 	}
-	if opts.common.projectId == "" {
-		proj, err := bopsdk.NewProject(opts.common.projectFilename)
-		if err == nil {
-			opts.common.projectId = proj.Desc.Id
+	checkPorcelain(opts.porcelain)
+	if opts.byState && !opts.count {
+		exitWithError(ExitUsage, "--by-state requires --count\n")
+	}
+	if opts.count && opts.porcelain {
+		exitWithError(ExitUsage, "--count cannot be combined with --porcelain\n")
+	}
+	projIds := parseIdList(opts.projIds)
+	if opts.projIds != "" {
+		if opts.common.projectId != "" {
+			exitWithError(ExitUsage, "--projids cannot be combined with --projid\n")
+		}
+		if len(projIds) == 0 {
+			exitWithError(ExitUsage, "--projids requires at least one project id\n")
 		}
+	} else {
+		if opts.common.projectId == "" {
+			proj, err := bopsdk.NewProject(opts.common.projFile())
+			if err == nil {
+				opts.common.projectId = proj.Desc.Id
+			} else if errors.Is(err, fs.ErrNotExist) {
+				opts.common.projectId = getDefaultProjectId()
+			} else {
+				exitWithError(ExitFailure, "Could not load project file '%v': %v\n",
+					opts.common.projFile(), err)
+			}
+		}
+		// an empty project id lists every project's packages
+		projIds = []string{opts.common.projectId}
 	}
 
-	if opts.common.projectId == "" {
-		fmt.Printf("Listing packages for all projects...")
-	} else {
-		fmt.Printf("Listing packages for project %v...",
-			opts.common.projectId)
+	if !opts.porcelain && !opts.count {
+		if len(projIds) == 1 && projIds[0] == "" {
+			fmt.Printf("Listing packages for all projects...")
+		} else {
+			fmt.Printf("Listing packages for %v...", describeProjIds(projIds))
+		}
 	}
 
-	pkgs, err := bopsdk.ListPackages(opts.common.projectId, sdkOpts...)
+	pkgLists := make([][]pb.ListPackagesReply_ListPackagesItem, len(projIds))
+	wg := newFanOutGroup()
+	for idx, projId := range projIds {
+		wg.Go(func() error {
+			var err error
+			pkgLists[idx], err = bopsdk.ListPackages(projId, sdkOpts...)
+			if err != nil && len(projIds) > 1 {
+				return fmt.Errorf("%v: %w", projId, err)
+			}
+			return err
+		})
+	}
+	err = wg.Wait()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
-		os.Exit(1)
+		exitWithError(ExitServer, "%v\n", err)
+	}
+	pkgs := make([]pb.ListPackagesReply_ListPackagesItem, 0)
+	for _, pkgList := range pkgLists {
+		pkgs = append(pkgs, pkgList...)
 	}
 
-	if len(pkgs) == 0 {
+	if opts.count {
+		err = printPkgCount(pkgs, opts.byState, sdkOpts)
+		if err != nil {
+			exitWithError(ExitServer, "%v\n", err)
+		}
+		return
+	}
+
+	if opts.porcelain {
+		// <project id> <package id>
+		for idx := range pkgs {
+			pkg := &pkgs[idx]
+			printPorcelain(pkg.ProjId, pkg.PackageId)
+		}
+	} else if len(pkgs) == 0 {
 		fmt.Printf("\nNo currently deployed packages\n")
 	} else {
 		fmt.Printf("\nProjectId\t\t\tPackageId\n")
 
-		for _, pkg := range pkgs {
+		for idx := range pkgs {
+			pkg := &pkgs[idx]
 			fmt.Printf("%v\t\t%v\n", pkg.ProjId, pkg.PackageId)
 		}
 	}
 }
 
+type pkgCountOutput struct {
+	Count int `json:"count"`
+	// only populated with --by-state
+	ByState map[string]int `json:"byState,omitempty"`
+}
+
+// printPkgCount prints how many packages are in pkgs and, with byState,
+// how many are in each state, which requires describing every package
+func printPkgCount(pkgs []pb.ListPackagesReply_ListPackagesItem, byState bool,
+	sdkOpts []bopsdk.DeployOption) error {
+
+	countOut := pkgCountOutput{
+		Count: len(pkgs),
+	}
+	if byState {
+		pkgStates := make([]pb.PackageState, len(pkgs))
+		wg := newFanOutGroup()
+		for idx := range pkgs {
+			pkg := &pkgs[idx]
+			wg.Go(func() error {
+				pkgDesc, err := bopsdk.Describe(pkg.PackageId, sdkOpts...)
+				if err != nil {
+					return fmt.Errorf("%v: %w", pkg.PackageId, err)
+				}
+				pkgStates[idx] = pkgDesc.State
+				return nil
+			})
+		}
+		err := wg.Wait()
+		if err != nil {
+			return err
+		}
+		countOut.ByState = make(map[string]int)
+		for _, state := range pkgStates {
+			countOut.ByState[state.String()]++
+		}
+	}
+
+	if outputFormat != OutputText {
+		err := printStructured(&countOut)
+		if err != nil {
+			return fmt.Errorf("Failed to render package count: %w", err)
+		}
+		return nil
+	}
+
+	if !byState {
+		fmt.Printf("%v\n", countOut.Count)
+		return nil
+	}
+	states := make([]string, 0, len(countOut.ByState))
+	for state := range countOut.ByState {
+		states = append(states, state)
+	}
+	sort.Strings(states)
+	for _, state := range states {
+		fmt.Printf("%v\t%v\n", state, countOut.ByState[state])
+	}
+	fmt.Printf("TOTAL\t%v\n", countOut.Count)
+
+	return nil
+}
+
 //go:embed pkgHelp.txt
 var pkgHelpText string
 
@@ -219,44 +1305,190 @@ func pkgHelpMain(args []string) {
 	fmt.Printf(pkgHelpText)
 }
 
+type pkgDescribeOutput struct {
+	PackageId  string `json:"packageId"`
+	ProjectId  string `json:"projectId"`
+	State      string `json:"state"`
+	Size       uint64 `json:"size"`
+	UploadTime string `json:"uploadTime"`
+	// only populated with --history
+	Deployments []pkgDeploymentOutput `json:"deployments,omitempty"`
+}
+
+type pkgDeploymentOutput struct {
+	DeployId   string `json:"deployId"`
+	Type       string `json:"type"`
+	State      string `json:"state"`
+	CreateTime string `json:"createTime"`
+	EndTime    string `json:"endTime"`
+}
+
+// getPkgDeployHistory returns every deployment of projId which deployed
+// pkgId, oldest first
+func getPkgDeployHistory(projId string, pkgId string,
+	sdkOpts []bopsdk.DeployOption) ([]*pb.DeploymentDescription, error) {
+
+	// @todo add envId
+	deployIds, err := bopsdk.ListDeployments(projId, "", sdkOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	deployDescList := make([]*pb.DeploymentDescription, len(deployIds))
+	wg := newFanOutGroup()
+	for idx, deployId := range deployIds {
+		wg.Go(func() error {
+			deployDesc, err := bopsdk.DescribeDeployment(deployId, sdkOpts...)
+			if err != nil {
+				return err
+			}
+			deployDescList[idx] = deployDesc
+			return nil
+		})
+	}
+	err = wg.Wait()
+	if err != nil {
+		return nil, err
+	}
+
+	history := make([]*pb.DeploymentDescription, 0)
+	for _, deployDesc := range deployDescList {
+		if deployDesc.Header.PkgId == pkgId {
+			history = append(history, deployDesc)
+		}
+	}
+	sort.Slice(history, func(i, j int) bool {
+		return history[i].CreateTime < history[j].CreateTime
+	})
+
+	return history, nil
+}
+
+func printPkgDeployHistory(history []*pb.DeploymentDescription) {
+	if len(history) == 0 {
+		fmt.Printf("This package has not been deployed\n")
+		return
+	}
+
+	fmt.Printf("%-24v%-12v%-12v%-32v%v\n", "Deployment Id", "Type", "State",
+		"Created", "Ended")
+	fmt.Printf("%-24v%-12v%-12v%-32v%v\n", "-------------", "----", "-----",
+		"-------", "-----")
+	for _, deployDesc := range history {
+		fmt.Printf("%-24v%-12v%-12v%-32v%v\n", deployDesc.Id,
+			deployDesc.Header.Type, deployDesc.State,
+			formatTimestamp(deployDesc.CreateTime),
+			formatTimestamp(deployDesc.EndTime))
+	}
+}
+
 func pkgDescribeMain(args []string) {
 	sdkOpts, err := getAuthSdkOpts()
 	if err != nil {
-		fmt.Fprintf(os.Stderr,
-			"Failed to get user creds; did you run bompatic config? err: %v\n",
-			err)
-		os.Exit(1)
+		exitWithError(ExitAuth, "%v\n", err)
 	}
 
 	type describeOpts struct {
-		common commonOpts
+		common       commonOpts
+		history      bool
+		fields       string
+		downloadLogs string
 	}
 
 	var opts describeOpts
 
 	f := flag.NewFlagSet("bopmatic package describe", flag.ExitOnError)
 	setCommonFlags(f, &opts.common)
+	f.BoolVar(&opts.history, "history", false,
+		"Also list every deployment of this package")
+	setTimestampFlags(f)
+	f.StringVar(&opts.fields, "fields", "",
+		"Only print these comma separated fields (e.g. State,Size) as key=value")
+	f.StringVar(&opts.downloadLogs, "download-logs", "",
+		"Save the package's validation & build logs to this file (not yet supported)")
 
 	err = f.Parse(args)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
-		os.Exit(1)
+		exitWithError(ExitUsage, "%v\n", err)
+	}
+	// @todo save the logs once ServiceRunner exposes them
+	if opts.downloadLogs != "" {
+		exitWithError(ExitUsage, "--download-logs is not yet supported; Bopmatic ServiceRunner does not currently provide package validation or build logs\n")
+	}
+	fields, err := parseFields(opts.fields, pkgDescribeOutput{})
+	if err != nil {
+		exitWithError(ExitUsage, "%v\n", err)
 	}
 	if opts.common.packageId == "" {
-		fmt.Fprintf(os.Stderr, "Please specify package id with --pkgid. If you don't know this, try 'bopmatic package list'\n")
-		os.Exit(1)
+		if !stdinIsTerminal() {
+			exitWithError(ExitUsage, "Please specify package id with --pkgid. If you don't know this, try 'bopmatic package list'\n")
+		}
+		if opts.common.projectId == "" {
+			proj, err := bopsdk.NewProject(opts.common.projFile())
+			if err == nil {
+				opts.common.projectId = proj.Desc.Id
+			} else if errors.Is(err, fs.ErrNotExist) {
+				opts.common.projectId = getDefaultProjectId()
+			} else {
+				exitWithError(ExitFailure, "Could not load project file '%v': %v\n",
+					opts.common.projFile(), err)
+			}
+		}
+		opts.common.packageId, err = pickPackageId(opts.common.projectId,
+			sdkOpts)
+		if err != nil {
+			exitPickError(err)
+		}
 	}
 
-	fmt.Printf("Describing pkgId:%v...", opts.common.packageId)
+	if outputFormat == OutputText && fields == nil {
+		fmt.Printf("Describing pkgId:%v...", opts.common.packageId)
+	}
 	pkgDesc, err := bopsdk.Describe(opts.common.packageId, sdkOpts...)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
-		os.Exit(1)
+		exitWithError(ExitServer, "%v\n", err)
+	}
+	var history []*pb.DeploymentDescription
+	if opts.history {
+		history, err = getPkgDeployHistory(pkgDesc.ProjId, pkgDesc.PackageId,
+			sdkOpts)
+		if err != nil {
+			exitWithError(ExitServer, "Failed to list deployments: %v\n", err)
+		}
+	}
+
+	if outputFormat != OutputText || fields != nil {
+		pkgOut := pkgDescribeOutput{
+			PackageId:  pkgDesc.PackageId,
+			ProjectId:  pkgDesc.ProjId,
+			State:      pkgDesc.State.String(),
+			Size:       pkgDesc.PackageSize,
+			UploadTime: formatTimestamp(pkgDesc.UploadTime),
+		}
+		for _, deployDesc := range history {
+			pkgOut.Deployments = append(pkgOut.Deployments,
+				pkgDeploymentOutput{
+					DeployId:   deployDesc.Id,
+					Type:       deployDesc.Header.Type.String(),
+					State:      deployDesc.State.String(),
+					CreateTime: formatTimestamp(deployDesc.CreateTime),
+					EndTime:    formatTimestamp(deployDesc.EndTime),
+				})
+		}
+		if fields != nil {
+			err = printFields(&pkgOut, fields)
+		} else {
+			err = printStructured(&pkgOut)
+		}
+		if err != nil {
+			exitWithError(ExitFailure, "Failed to render package: %v\n", err)
+		}
+		return
 	}
 
 	fmt.Printf("\nPackageId %v:\n\tProjectId: %v\n\tState: %v\n\tSize: %v MiB\n\tUploadTime: %v\n",
 		pkgDesc.PackageId, pkgDesc.ProjId, pkgDesc.State,
-		pkgDesc.PackageSize/1024/1024, unixTime2UtcStr(pkgDesc.UploadTime))
+		pkgDesc.PackageSize/1024/1024, formatTimestamp(pkgDesc.UploadTime))
 
 	switch pkgDesc.State {
 	case pb.PackageState_UPLOADING:
@@ -281,60 +1513,314 @@ func pkgDescribeMain(args []string) {
 	default:
 		fmt.Printf("\nAn error occurred within Bopmatic ServiceRunner and a support staff member needs to examine the situation.\n")
 	}
+
+	if opts.history {
+		fmt.Printf("\nDeployment history:\n")
+		printPkgDeployHistory(history)
+	}
+}
+
+// getDeployedPkgIds returns the ids of the packages referenced by the active
+// and pending deployments of each of projIds
+func getDeployedPkgIds(projIds []string,
+	sdkOpts []bopsdk.DeployOption) (map[string]bool, error) {
+
+	var lock sync.Mutex
+	deployedPkgIds := make(map[string]bool)
+	wg := newFanOutGroup()
+	for _, projId := range projIds {
+		wg.Go(func() error {
+			projDesc, err := bopsdk.DescribeProject(projId, sdkOpts...)
+			if err != nil {
+				return err
+			}
+			deployIds := append(append([]string{}, projDesc.ActiveDeployIds...),
+				projDesc.PendingDeployIds...)
+			for _, deployId := range deployIds {
+				deployDesc, err := bopsdk.DescribeDeployment(deployId,
+					sdkOpts...)
+				if err != nil {
+					return err
+				}
+				lock.Lock()
+				deployedPkgIds[deployDesc.Header.PkgId] = true
+				lock.Unlock()
+			}
+			return nil
+		})
+	}
+
+	return deployedPkgIds, wg.Wait()
+}
+
+// deleteOldPackages deletes the packages of projId which were uploaded more
+// than age ago and are not part of an active or pending deployment
+func deleteOldPackages(projId string, age time.Duration, skipConfirm bool,
+	sdkOpts []bopsdk.DeployOption) {
+
+	fmt.Printf("Listing packages...")
+	pkgs, err := bopsdk.ListPackages(projId, sdkOpts...)
+	if err != nil {
+		exitWithError(ExitServer, "%v\n", err)
+	}
+
+	pkgDescList := make([]*pb.PackageDescription, len(pkgs))
+	projIdSet := make(map[string]bool)
+	wg := newFanOutGroup()
+	for idx := range pkgs {
+		pkg := &pkgs[idx]
+		projIdSet[pkg.ProjId] = true
+		wg.Go(func() error {
+			var err error
+			pkgDescList[idx], err = bopsdk.Describe(pkg.PackageId, sdkOpts...)
+			return err
+		})
+	}
+	err = wg.Wait()
+	if err != nil {
+		exitWithError(ExitServer, "\nFailed to describe package: %v\n", err)
+	}
+
+	projIds := make([]string, 0, len(projIdSet))
+	for projId := range projIdSet {
+		projIds = append(projIds, projId)
+	}
+	deployedPkgIds, err := getDeployedPkgIds(projIds, sdkOpts)
+	if err != nil {
+		exitWithError(ExitServer,
+			"\nFailed to determine which packages are deployed: %v\n", err)
+	}
+
+	cutoff := time.Now().Add(-age)
+	candidates := make([]*pb.PackageDescription, 0)
+	for _, pkgDesc := range pkgDescList {
+		if deployedPkgIds[pkgDesc.PackageId] ||
+			pkgDesc.State == pb.PackageState_PKG_DELETED ||
+			!unixTime2Utc(pkgDesc.UploadTime).Before(cutoff) {
+			continue
+		}
+		candidates = append(candidates, pkgDesc)
+	}
+
+	if len(candidates) == 0 {
+		fmt.Printf("\nNo undeployed packages were uploaded before %v\n",
+			cutoff.UTC())
+		return
+	}
+
+	fmt.Printf("\nThe following %v packages were uploaded before %v and are not deployed:\n",
+		len(candidates), cutoff.UTC())
+	fmt.Printf("ProjectId\t\t\tPackageId\t\tUploadTime\n")
+	for _, pkgDesc := range candidates {
+		fmt.Printf("%v\t\t%v\t%v\n", pkgDesc.ProjId, pkgDesc.PackageId,
+			unixTime2UtcStr(pkgDesc.UploadTime))
+	}
+
+	if !skipConfirm &&
+		!confirm(fmt.Sprintf("Delete these %v packages?", len(candidates))) {
+		fmt.Printf("Not deleting any packages\n")
+		return
+	}
+
+	failed := 0
+	for _, pkgDesc := range candidates {
+		fmt.Printf("Deleting pkgId:%v...", pkgDesc.PackageId)
+		err = bopsdk.DeletePackage(pkgDesc.PackageId, sdkOpts...)
+		if err != nil {
+			fmt.Printf("failed: %v\n", err)
+			failed++
+			continue
+		}
+		fmt.Printf("done\n")
+	}
+	if failed > 0 {
+		exitWithError(ExitServer, "Failed to delete %v of %v packages\n",
+			failed, len(candidates))
+	}
 }
 
 func pkgDeleteMain(args []string) {
 	sdkOpts, err := getAuthSdkOpts()
 	if err != nil {
-		fmt.Fprintf(os.Stderr,
-			"Failed to get user creds; did you run bompatic config? err: %v\n",
-			err)
-		os.Exit(1)
+		exitWithError(ExitAuth, "%v\n", err)
 	}
 
 	type deleteOpts struct {
-		common commonOpts
+		common    commonOpts
+		olderThan string
+		yes       bool
 	}
 
 	var opts deleteOpts
 
 	f := flag.NewFlagSet("bopmatic package delete", flag.ExitOnError)
 	setCommonFlags(f, &opts.common)
+	setLockFlags(f)
+	f.StringVar(&opts.olderThan, "older-than", "",
+		"Delete every package uploaded longer ago than this age (e.g. 30d) which isn't actively deployed")
+	f.BoolVar(&opts.yes, "yes", false,
+		"With --older-than, delete without prompting for confirmation")
 
 	err = f.Parse(args)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
-		os.Exit(1)
+		exitWithError(ExitUsage, "%v\n", err)
+	}
+	if opts.olderThan != "" {
+		if opts.common.packageId != "" {
+			exitWithError(ExitUsage, "--older-than cannot be combined with --pkgid\n")
+		}
+		age, err := parseAge(opts.olderThan)
+		if err != nil {
+			exitWithError(ExitUsage, "%v\n", err)
+		}
+		// an empty project id would delete every project's packages, so
+		// the default project isn't used either
+		if opts.common.projectId == "" {
+			proj, err := loadProject(opts.common.projFile())
+			if errors.Is(err, errNoProject) {
+				exitWithError(ExitUsage, "--older-than deletes packages in bulk so requires --projid or a project file\n")
+			} else if err != nil {
+				exitWithError(ExitFailure, "%v\n", err)
+			}
+			opts.common.projectId = proj.Desc.Id
+		}
+		defer lockProjectOrExit(opts.common.projectId)()
+		deleteOldPackages(opts.common.projectId, age, opts.yes, sdkOpts)
+		return
 	}
 	if opts.common.packageId == "" {
-		fmt.Fprintf(os.Stderr, "Please specify package id with --pkgid. If you don't know this, try 'bopmatic package list'\n")
-		os.Exit(1)
+		exitWithError(ExitUsage, "Please specify package id with --pkgid. If you don't know this, try 'bopmatic package list'\n")
 	}
 
 	fmt.Printf("Listing packages...")
 	pkgs, err := bopsdk.ListPackages(opts.common.projectId, sdkOpts...)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
-		os.Exit(1)
+		exitWithError(ExitServer, "%v\n", err)
 	}
 	found := false
-	for _, pkg := range pkgs {
+	projId := opts.common.projectId
+	for idx := range pkgs {
+		pkg := &pkgs[idx]
 		if pkg.PackageId == opts.common.packageId {
 			found = true
+			projId = pkg.ProjId
 		}
 	}
 
 	if !found {
-		fmt.Printf("\nPackage id %v no longer exists\n", opts.common.packageId)
-		os.Exit(1)
+		fmt.Printf("\n")
+		exitWithError(ExitNotFound, "Package id %v no longer exists\n",
+			opts.common.packageId)
 	}
 
+	defer lockProjectOrExit(projId)()
 	fmt.Printf("Deleting pkgId:%v...", opts.common.packageId)
 	err = bopsdk.DeletePackage(opts.common.packageId, sdkOpts...)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
-		os.Exit(1)
+		exitWithError(ExitServer, "%v\n", err)
 	}
 
 	fmt.Printf("\nDeleted pkgId:%v", opts.common.packageId)
 }
+
+type pkgFieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+type pkgDiffOutput struct {
+	Old     pkgDescribeOutput `json:"old"`
+	New     pkgDescribeOutput `json:"new"`
+	Changes []pkgFieldChange  `json:"changes"`
+}
+
+// pkgDiffMain compares the descriptions of two packages
+// @todo compare the services and resources each package contains once the
+// sdk can describe a package's manifest
+func pkgDiffMain(args []string) {
+	sdkOpts, err := getAuthSdkOpts()
+	if err != nil {
+		exitWithError(ExitAuth, "%v\n", err)
+	}
+
+	f := flag.NewFlagSet("bopmatic package diff", flag.ExitOnError)
+	setGlobalFlags(f)
+	setTimestampFlags(f)
+	err = f.Parse(args)
+	if err != nil {
+		exitWithError(ExitUsage, "%v\n", err)
+	}
+	if f.NArg() != 2 {
+		exitWithError(ExitUsage, "Please specify two package ids: bopmatic package diff <pkgid1> <pkgid2>\n")
+	}
+
+	var pkgDescs [2]*pb.PackageDescription
+	for idx := range pkgDescs {
+		pkgDescs[idx], err = bopsdk.Describe(f.Arg(idx), sdkOpts...)
+		if err != nil {
+			exitWithError(ExitServer, "Failed to describe %v: %v\n", f.Arg(idx),
+				err)
+		}
+	}
+
+	diff := diffPackages(pkgDescs[0], pkgDescs[1])
+	if outputFormat != OutputText {
+		err = printStructured(&diff)
+		if err != nil {
+			exitWithError(ExitFailure, "Failed to render diff: %v\n", err)
+		}
+		return
+	}
+
+	fmt.Printf("--- pkgId:%v\n+++ pkgId:%v\n", diff.Old.PackageId,
+		diff.New.PackageId)
+	if len(diff.Changes) == 0 {
+		fmt.Printf("No differences\n")
+		return
+	}
+	for _, change := range diff.Changes {
+		fmt.Printf("-%v: %v\n+%v: %v\n", change.Field, change.Old,
+			change.Field, change.New)
+	}
+	if diff.Old.Size != diff.New.Size {
+		fmt.Printf("Size changed by %+d bytes\n",
+			int64(diff.New.Size)-int64(diff.Old.Size))
+	}
+}
+
+func diffPackages(oldDesc *pb.PackageDescription,
+	newDesc *pb.PackageDescription) pkgDiffOutput {
+
+	describe := func(pkgDesc *pb.PackageDescription) pkgDescribeOutput {
+		return pkgDescribeOutput{
+			PackageId:  pkgDesc.PackageId,
+			ProjectId:  pkgDesc.ProjId,
+			State:      pkgDesc.State.String(),
+			Size:       pkgDesc.PackageSize,
+			UploadTime: formatTimestamp(pkgDesc.UploadTime),
+		}
+	}
+	diff := pkgDiffOutput{
+		Old:     describe(oldDesc),
+		New:     describe(newDesc),
+		Changes: make([]pkgFieldChange, 0),
+	}
+
+	addChange := func(field string, oldVal string, newVal string) {
+		if oldVal != newVal {
+			diff.Changes = append(diff.Changes, pkgFieldChange{
+				Field: field,
+				Old:   oldVal,
+				New:   newVal,
+			})
+		}
+	}
+	addChange("ProjectId", diff.Old.ProjectId, diff.New.ProjectId)
+	addChange("State", diff.Old.State, diff.New.State)
+	addChange("Size", fmt.Sprintf("%v", diff.Old.Size),
+		fmt.Sprintf("%v", diff.New.Size))
+	addChange("UploadTime", diff.Old.UploadTime, diff.New.UploadTime)
+
+	return diff
+}
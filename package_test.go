@@ -0,0 +1,28 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBuildCmdArgv(t *testing.T) {
+	tests := []struct {
+		buildCmd string
+		targets  []string
+		expected []string
+	}{
+		{"make", nil, []string{"make"}},
+		{"make", []string{"svc1"}, []string{"make", "svc1"}},
+		{"make", []string{"svc1", "svc2"}, []string{"make", "svc1", "svc2"}},
+		{"make -j4", []string{"svc1"}, []string{"make", "-j4", "svc1"}},
+		{"  make  ", []string{"svc1"}, []string{"make", "svc1"}},
+	}
+
+	for _, test := range tests {
+		argv := buildCmdArgv(test.buildCmd, test.targets)
+		if !reflect.DeepEqual(argv, test.expected) {
+			t.Errorf("buildCmdArgv(%q, %v): expected %q, got %q",
+				test.buildCmd, test.targets, test.expected, argv)
+		}
+	}
+}
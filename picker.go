@@ -0,0 +1,109 @@
+/* Copyright © 2024 Bopmatic, LLC. All Rights Reserved.
+ *
+ * See LICENSE file at the root of this package for license terms
+ */
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	bopsdk "github.com/bopmatic/sdk/golang"
+)
+
+var (
+	errNoChoices   = errors.New("nothing to choose from")
+	errNoSelection = errors.New("nothing selected")
+)
+
+// stdinIsTerminal reports whether stdin is attached to a terminal, i.e.
+// whether the user can answer an interactive prompt
+func stdinIsTerminal() bool {
+	stat, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+
+	return stat.Mode()&os.ModeCharDevice != 0
+}
+
+// pickOne prints a numbered list of choices and returns the one the user
+// selects. The prompt is written to stderr so that structured output on
+// stdout isn't disturbed.
+func pickOne(what string, choices []string) (string, error) {
+	if len(choices) == 0 {
+		return "", fmt.Errorf("%w: no %vs found", errNoChoices, what)
+	}
+
+	for idx, choice := range choices {
+		fmt.Fprintf(os.Stderr, "%4v) %v\n", idx+1, choice)
+	}
+	for {
+		fmt.Fprintf(os.Stderr, "Select a %v [1-%v]: ", what, len(choices))
+		var answer string
+		_, err := fmt.Scanf("%s", &answer)
+		if err != nil {
+			return "", fmt.Errorf("%w: no %v selected", errNoSelection, what)
+		}
+		selection, err := strconv.Atoi(strings.TrimSpace(answer))
+		if err == nil && selection >= 1 && selection <= len(choices) {
+			return choices[selection-1], nil
+		}
+		fmt.Fprintf(os.Stderr, "Invalid selection %v\n", answer)
+	}
+}
+
+// pickPackageId interactively selects one of projId's packages, or one of
+// any project's packages when projId is empty
+func pickPackageId(projId string, sdkOpts []bopsdk.DeployOption) (string,
+	error) {
+
+	pkgs, err := bopsdk.ListPackages(projId, sdkOpts...)
+	if err != nil {
+		return "", err
+	}
+	choices := make([]string, 0, len(pkgs))
+	for idx := range pkgs {
+		pkg := &pkgs[idx]
+		if projId == "" {
+			choices = append(choices, fmt.Sprintf("%v\t(projId:%v)",
+				pkg.PackageId, pkg.ProjId))
+		} else {
+			choices = append(choices, pkg.PackageId)
+		}
+	}
+
+	choice, err := pickOne("package", choices)
+	if err != nil {
+		return "", err
+	}
+	pkgId, _, _ := strings.Cut(choice, "\t")
+
+	return pkgId, nil
+}
+
+// pickDeployId interactively selects one of projId's deployments
+func pickDeployId(projId string, sdkOpts []bopsdk.DeployOption) (string,
+	error) {
+
+	// @todo specify envId
+	deployments, err := bopsdk.ListDeployments(projId, "", sdkOpts...)
+	if err != nil {
+		return "", err
+	}
+
+	return pickOne("deployment", deployments)
+}
+
+// exitPickError exits with the status appropriate to a failed pick
+func exitPickError(err error) {
+	if errors.Is(err, errNoChoices) {
+		exitWithError(ExitNotFound, "%v\n", err)
+	} else if errors.Is(err, errNoSelection) {
+		exitWithError(ExitUsage, "%v\n", err)
+	}
+	exitWithError(ExitServer, "%v\n", err)
+}
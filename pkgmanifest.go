@@ -0,0 +1,177 @@
+/* Copyright © 2024 Bopmatic, LLC. All Rights Reserved.
+ *
+ * See LICENSE file at the root of this package for license terms
+ */
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	bopsdk "github.com/bopmatic/sdk/golang"
+	"github.com/bopmatic/sdk/golang/util"
+)
+
+// the directory within a package tarball which holds its contents
+const pkgTarballRoot = "pkg"
+
+// pkgManifestEntry summarizes one top-level file or directory within a
+// package tarball
+type pkgManifestEntry struct {
+	Name  string `json:"name"`
+	IsDir bool   `json:"isDir"`
+	Files int    `json:"files"`
+	// uncompressed bytes of the regular files beneath the entry
+	Size int64 `json:"size"`
+}
+
+type pkgManifestOutput struct {
+	PackageId   string             `json:"packageId"`
+	TarballPath string             `json:"tarballPath"`
+	TarballSize int64              `json:"tarballSize"`
+	TotalSize   int64              `json:"totalSize"`
+	Entries     []pkgManifestEntry `json:"entries"`
+}
+
+// readPkgManifest lists the contents of pkg's tarball grouped by top-level
+// entry, largest first. Like the sdk the tarball is read with tar & xz
+// within the build container since the host may lack them.
+func readPkgManifest(pkg *bopsdk.Package) (*pkgManifestOutput, error) {
+	tarballPath := pkg.AbsTarballPath()
+	tarballInfo, err := os.Stat(tarballPath)
+	if err != nil {
+		return nil, err
+	}
+
+	curWd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	err = os.Chdir(filepath.Dir(tarballPath))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = os.Chdir(curWd) }()
+
+	var listing, stdErr bytes.Buffer
+	err = util.RunContainerCommand(context.Background(),
+		[]string{"tar", "-Jtvf", filepath.Base(tarballPath)}, &listing, &stdErr)
+	if err != nil {
+		return nil, fmt.Errorf("Could not list %v: %w: %v", tarballPath, err,
+			strings.TrimSpace(stdErr.String()))
+	}
+
+	manifest := &pkgManifestOutput{
+		PackageId:   pkg.Id,
+		TarballPath: tarballPath,
+		TarballSize: tarballInfo.Size(),
+		Entries:     parseTarListing(&listing),
+	}
+	for _, entry := range manifest.Entries {
+		manifest.TotalSize += entry.Size
+	}
+
+	return manifest, nil
+}
+
+// parseTarListing groups 'tar -tv' output, e.g.
+//
+//	-rwxr-xr-x 0/0 1234 2024-06-01 12:00 pkg/svc/bin/server
+//
+// by top-level entry beneath pkgTarballRoot
+func parseTarListing(listing *bytes.Buffer) []pkgManifestEntry {
+	entryMap := make(map[string]*pkgManifestEntry)
+	scanner := bufio.NewScanner(listing)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 6 {
+			continue
+		}
+		perms := fields[0]
+		size, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			continue
+		}
+		name := strings.Join(fields[5:], " ")
+		// symlinks are listed as '<name> -> <target>'
+		name, _, _ = strings.Cut(name, " -> ")
+		name = strings.TrimPrefix(strings.TrimPrefix(name, "./"),
+			pkgTarballRoot+"/")
+		name = strings.TrimSuffix(name, "/")
+		if name == "" || name == pkgTarballRoot {
+			continue
+		}
+
+		topName, rest, nested := strings.Cut(name, "/")
+		entry, ok := entryMap[topName]
+		if !ok {
+			entry = &pkgManifestEntry{Name: topName}
+			entryMap[topName] = entry
+		}
+		if nested || perms[0] == 'd' {
+			entry.IsDir = true
+		}
+		if perms[0] == '-' && (nested || rest == "") {
+			entry.Files++
+			entry.Size += size
+		}
+	}
+
+	entries := make([]pkgManifestEntry, 0, len(entryMap))
+	for _, entry := range entryMap {
+		entries = append(entries, *entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Size != entries[j].Size {
+			return entries[i].Size > entries[j].Size
+		}
+		return entries[i].Name < entries[j].Name
+	})
+
+	return entries
+}
+
+// formatSize renders a byte count using the largest binary unit which
+// keeps it at least 1
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%v B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+func printPkgManifest(manifest *pkgManifestOutput) error {
+	if outputFormat != OutputText {
+		return printStructured(manifest)
+	}
+
+	fmt.Printf("\nContents of pkgId:%v (%v compressed):\n", manifest.PackageId,
+		formatSize(manifest.TarballSize))
+	fmt.Printf("  %-40v%8v  %v\n", "Name", "Files", "Size")
+	fmt.Printf("  %-40v%8v  %v\n", "----", "-----", "----")
+	for _, entry := range manifest.Entries {
+		name := entry.Name
+		if entry.IsDir {
+			name += "/"
+		}
+		fmt.Printf("  %-40v%8v  %v\n", name, entry.Files,
+			formatSize(entry.Size))
+	}
+	fmt.Printf("  %-40v%8v  %v\n", "Total", "", formatSize(manifest.TotalSize))
+
+	return nil
+}
@@ -0,0 +1,96 @@
+/* Copyright © 2024 Bopmatic, LLC. All Rights Reserved.
+ *
+ * See LICENSE file at the root of this package for license terms
+ */
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	DefaultPollInterval    = 5 * time.Second
+	DefaultPollMaxInterval = 30 * time.Second
+)
+
+var errPollTimeout = errors.New("timed out waiting for a terminal state")
+
+type pollOptions struct {
+	// initial delay between polls; doubled after each poll up to
+	// maxInterval
+	interval    time.Duration
+	maxInterval time.Duration
+	// 0 means wait indefinitely (or until ctx is cancelled)
+	timeout time.Duration
+	// when non-nil, describeState() is written here each time it changes
+	out io.Writer
+}
+
+// pollUntil repeatedly invokes poll with exponential backoff until
+// isTerminal reports true for the polled state, poll fails, the timeout
+// expires, or ctx is cancelled. The most recently polled state is returned
+// in all cases.
+func pollUntil[T any](ctx context.Context, opts pollOptions,
+	poll func(ctx context.Context) (T, error), isTerminal func(state T) bool,
+	describeState func(state T) string) (T, error) {
+
+	if opts.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.timeout)
+		defer cancel()
+	}
+
+	interval := opts.interval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	maxInterval := opts.maxInterval
+	if maxInterval < interval {
+		maxInterval = interval
+	}
+
+	var state T
+	lastDesc := ""
+	for {
+		var err error
+		state, err = poll(ctx)
+		if err != nil {
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return state, errPollTimeout
+			}
+			return state, err
+		}
+
+		if opts.out != nil && describeState != nil {
+			desc := describeState(state)
+			if desc != lastDesc {
+				fmt.Fprintf(opts.out, "%v: %v\n",
+					time.Now().Format(time.TimeOnly), desc)
+				lastDesc = desc
+			}
+		}
+		if isTerminal(state) {
+			return state, nil
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return state, errPollTimeout
+			}
+			return state, ctx.Err()
+		case <-timer.C:
+		}
+
+		interval *= 2
+		if interval > maxInterval {
+			interval = maxInterval
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func fakePoller(states []string, failAt int) func(context.Context) (string, error) {
+	idx := 0
+	return func(ctx context.Context) (string, error) {
+		if idx == failAt {
+			return "", errors.New("poll failed")
+		}
+		state := states[len(states)-1]
+		if idx < len(states) {
+			state = states[idx]
+		}
+		idx++
+		return state, nil
+	}
+}
+
+func isDone(state string) bool {
+	return state == "SUCCESS" || state == "FAILED"
+}
+
+func describe(state string) string {
+	return "state " + state
+}
+
+var fastPoll = pollOptions{
+	interval:    time.Millisecond,
+	maxInterval: 4 * time.Millisecond,
+}
+
+func TestPollUntilSuccess(t *testing.T) {
+	var out bytes.Buffer
+	opts := fastPoll
+	opts.out = &out
+
+	state, err := pollUntil(context.Background(), opts,
+		fakePoller([]string{"CREATED", "CREATED", "BUILDING", "SUCCESS"}, -1),
+		isDone, describe)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if state != "SUCCESS" {
+		t.Errorf("expected SUCCESS, got %v", state)
+	}
+
+	// repeated states should only be reported once
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 state changes, got %v: %q", len(lines), lines)
+	}
+	for idx, expected := range []string{"CREATED", "BUILDING", "SUCCESS"} {
+		if !strings.HasSuffix(lines[idx], "state "+expected) {
+			t.Errorf("line %v: expected state %v, got %q", idx, expected,
+				lines[idx])
+		}
+	}
+}
+
+func TestPollUntilPollFailure(t *testing.T) {
+	_, err := pollUntil(context.Background(), fastPoll,
+		fakePoller([]string{"CREATED", "BUILDING"}, 2), isDone, describe)
+	if err == nil || err.Error() != "poll failed" {
+		t.Fatalf("expected poll failure, got %v", err)
+	}
+}
+
+func TestPollUntilTerminalFailureState(t *testing.T) {
+	state, err := pollUntil(context.Background(), fastPoll,
+		fakePoller([]string{"CREATED", "FAILED"}, -1), isDone, describe)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if state != "FAILED" {
+		t.Errorf("expected FAILED, got %v", state)
+	}
+}
+
+func TestPollUntilTimeout(t *testing.T) {
+	opts := fastPoll
+	opts.timeout = 20 * time.Millisecond
+
+	state, err := pollUntil(context.Background(), opts,
+		fakePoller([]string{"BUILDING"}, -1), isDone, describe)
+	if !errors.Is(err, errPollTimeout) {
+		t.Fatalf("expected timeout, got %v", err)
+	}
+	if state != "BUILDING" {
+		t.Errorf("expected last state BUILDING, got %v", state)
+	}
+}
+
+func TestPollUntilCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := pollUntil(ctx, fastPoll, fakePoller([]string{"BUILDING"}, -1),
+		isDone, describe)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected cancellation, got %v", err)
+	}
+}
@@ -0,0 +1,212 @@
+/* Copyright © 2024 Bopmatic, LLC. All Rights Reserved.
+ *
+ * See LICENSE file at the root of this package for license terms
+ */
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	bopsdk "github.com/bopmatic/sdk/golang"
+)
+
+// projResourceNames is the set of names of each kind of resource compared
+// by 'bopmatic project describe --compare'
+type projResourceNames struct {
+	services   []string
+	databases  []string
+	datastores []string
+	// <service>/<rpc endpoint>
+	endpoints []string
+}
+
+// resourceDiff partitions the names of one kind of resource between the
+// described project and the --compare project
+type resourceDiff struct {
+	InBoth        []string `json:"inBoth"`
+	OnlyInProject []string `json:"onlyInProject"`
+	OnlyInCompare []string `json:"onlyInCompare"`
+}
+
+func (diff *resourceDiff) aligned() bool {
+	return len(diff.OnlyInProject) == 0 && len(diff.OnlyInCompare) == 0
+}
+
+type projCompareOutput struct {
+	ProjectId        string       `json:"projectId"`
+	CompareProjectId string       `json:"compareProjectId"`
+	Aligned          bool         `json:"aligned"`
+	Services         resourceDiff `json:"services"`
+	Databases        resourceDiff `json:"databases"`
+	Datastores       resourceDiff `json:"datastores"`
+	Endpoints        resourceDiff `json:"endpoints"`
+}
+
+// getProjResourceNames describes each of projId's deployed resources; a
+// project with no active deployment has none
+func getProjResourceNames(projId string,
+	sdkOpts []bopsdk.DeployOption) (*projResourceNames, error) {
+
+	projDesc, err := bopsdk.DescribeProject(projId, sdkOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to describe project %v: %w", projId,
+			err)
+	}
+	names := &projResourceNames{}
+	if len(projDesc.ActiveDeployIds) == 0 {
+		return names, nil
+	}
+
+	_, svcDescList, dbDescList, dstoreDescList, err :=
+		describeAllProjResources(projId, sdkOpts)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to describe project %v's resources: %w",
+			projId, err)
+	}
+	for _, svcDesc := range svcDescList {
+		svcName := svcDesc.Desc.SvcHeader.ServiceName
+		names.services = append(names.services, svcName)
+		for _, rpcEnd := range svcDesc.Desc.RpcEndpoints {
+			names.endpoints = append(names.endpoints, svcName+"/"+rpcEnd)
+		}
+	}
+	for _, dbDesc := range dbDescList {
+		names.databases = append(names.databases,
+			dbDesc.Desc.DatabaseHeader.DatabaseName)
+	}
+	for _, dstoreDesc := range dstoreDescList {
+		names.datastores = append(names.datastores,
+			dstoreDesc.Desc.DatastoreHeader.DatastoreName)
+	}
+
+	return names, nil
+}
+
+// diffNames returns the sorted names present in both, only projNames, and
+// only compareNames
+func diffNames(projNames []string, compareNames []string) resourceDiff {
+	diff := resourceDiff{
+		InBoth:        []string{},
+		OnlyInProject: []string{},
+		OnlyInCompare: []string{},
+	}
+	inCompare := make(map[string]bool)
+	for _, name := range compareNames {
+		inCompare[name] = true
+	}
+	inProj := make(map[string]bool)
+	for _, name := range projNames {
+		if inProj[name] {
+			continue
+		}
+		inProj[name] = true
+		if inCompare[name] {
+			diff.InBoth = append(diff.InBoth, name)
+		} else {
+			diff.OnlyInProject = append(diff.OnlyInProject, name)
+		}
+	}
+	for name := range inCompare {
+		if !inProj[name] {
+			diff.OnlyInCompare = append(diff.OnlyInCompare, name)
+		}
+	}
+	sort.Strings(diff.InBoth)
+	sort.Strings(diff.OnlyInProject)
+	sort.Strings(diff.OnlyInCompare)
+
+	return diff
+}
+
+// compareProjects describes projId & compareProjId concurrently and diffs
+// their services, databases, datastores, and rpc endpoints by name
+func compareProjects(projId string, compareProjId string,
+	sdkOpts []bopsdk.DeployOption) (*projCompareOutput, error) {
+
+	var projNames, compareNames *projResourceNames
+	wg := newFanOutGroup()
+	wg.Go(func() error {
+		var err error
+		projNames, err = getProjResourceNames(projId, sdkOpts)
+		return err
+	})
+	wg.Go(func() error {
+		var err error
+		compareNames, err = getProjResourceNames(compareProjId, sdkOpts)
+		return err
+	})
+	err := wg.Wait()
+	if err != nil {
+		return nil, err
+	}
+
+	compareOut := &projCompareOutput{
+		ProjectId:        projId,
+		CompareProjectId: compareProjId,
+		Services:         diffNames(projNames.services, compareNames.services),
+		Databases:        diffNames(projNames.databases, compareNames.databases),
+		Datastores: diffNames(projNames.datastores,
+			compareNames.datastores),
+		Endpoints: diffNames(projNames.endpoints, compareNames.endpoints),
+	}
+	compareOut.Aligned = compareOut.Services.aligned() &&
+		compareOut.Databases.aligned() && compareOut.Datastores.aligned() &&
+		compareOut.Endpoints.aligned()
+
+	return compareOut, nil
+}
+
+// printProjCompare renders compareOut as side by side columns, one section
+// per kind of resource. Resources missing from one project are marked '<'
+// (only in the described project) or '>' (only in the --compare project)
+// and, on a terminal, colored.
+func printProjCompare(compareOut *projCompareOutput) {
+	color := colorEnabled(false)
+	const colWidth = 40
+	row := func(marker string, left string, right string, rowColor string) {
+		line := fmt.Sprintf("%v %-*v%v", marker, colWidth, left, right)
+		if color && rowColor != "" {
+			line = rowColor + line + colorReset
+		}
+		fmt.Printf("  %v\n", line)
+	}
+
+	sections := []struct {
+		kind string
+		diff *resourceDiff
+	}{
+		{"Services", &compareOut.Services},
+		{"Databases", &compareOut.Databases},
+		{"Datastores", &compareOut.Datastores},
+		{"Endpoints", &compareOut.Endpoints},
+	}
+	for _, section := range sections {
+		fmt.Printf("%v:\n", section.kind)
+		row(" ", compareOut.ProjectId, compareOut.CompareProjectId, "")
+		diff := section.diff
+		if len(diff.InBoth)+len(diff.OnlyInProject)+
+			len(diff.OnlyInCompare) == 0 {
+			row(" ", "(none)", "(none)", "")
+		}
+		for _, name := range diff.InBoth {
+			row(" ", name, name, "")
+		}
+		for _, name := range diff.OnlyInProject {
+			row("<", name, "-", highlightColors[0])
+		}
+		for _, name := range diff.OnlyInCompare {
+			row(">", "-", name, highlightColors[1])
+		}
+		fmt.Printf("\n")
+	}
+
+	if compareOut.Aligned {
+		fmt.Printf("Projects %v and %v are structurally aligned\n",
+			compareOut.ProjectId, compareOut.CompareProjectId)
+	} else {
+		fmt.Printf("Projects %v and %v differ; '<' marks resources only in %v and '>' those only in %v\n",
+			compareOut.ProjectId, compareOut.CompareProjectId,
+			compareOut.ProjectId, compareOut.CompareProjectId)
+	}
+}
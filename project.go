@@ -7,19 +7,26 @@ package main
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io/fs"
 	"io/ioutil"
 	"os"
+	"os/exec"
+	"os/signal"
 	"os/user"
 	"path"
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
 	_ "embed"
 
 	bopsdk "github.com/bopmatic/sdk/golang"
+	"github.com/bopmatic/sdk/golang/models"
 	"github.com/bopmatic/sdk/golang/pb"
 	"github.com/bopmatic/sdk/golang/util"
 	"golang.org/x/sync/errgroup"
@@ -27,6 +34,8 @@ import (
 
 type projOpts struct {
 	projectFilename string
+	projectDir      string
+	noDiscovery     bool
 	projectId       string
 }
 
@@ -42,87 +51,258 @@ var projSubCommandTab = map[string]func(args []string){
 func projDescribeMain(args []string) {
 	sdkOpts, err := getAuthSdkOpts()
 	if err != nil {
-		fmt.Fprintf(os.Stderr,
-			"Failed to get user creds; did you run bompatic config? err: %v\n",
-			err)
-		os.Exit(1)
+		exitWithError(ExitAuth, "%v\n", err)
 	}
 
 	var opts projOpts
+	var includeMetrics, showCosts bool
+	var startTimeStr, endTimeStr string
+	var resourceNames stringListFlag
+	var watchDstoreName string
+	var watchInterval time.Duration
+	var fieldsStr string
+	var graphFormat, graphOutfile string
+	var maxServices, maxTables, maxDatastores int
+	var showAll bool
+	var compareProjId string
 	f := flag.NewFlagSet("bopmatic project describe", flag.ExitOnError)
 	setProjFlags(f, &opts)
+	f.Var(&resourceNames, "resource",
+		"Only describe the named service, database, or datastore (repeatable)")
+	setTimestampFlags(f)
+	f.BoolVar(&includeMetrics, "include-metrics", false,
+		"Include datastore & database utilization over the --starttime/--endtime window")
+	f.BoolVar(&showCosts, "show-costs", false,
+		"Include estimated cost per resource over the --starttime/--endtime window")
+	f.StringVar(&startTimeStr, "starttime", "",
+		"The starting time in UTC to query; defaults to 48 hours ago.")
+	f.StringVar(&endTimeStr, "endtime", "",
+		"The ending time in UTC to query; defaults to now.")
+	f.StringVar(&watchDstoreName, "watch-datastore", "",
+		"Repeatedly report the named datastore's object count and size until interrupted")
+	f.DurationVar(&watchInterval, "interval", DefaultPollInterval,
+		"With --watch-datastore, how often to describe the datastore")
+	f.StringVar(&fieldsStr, "fields", "",
+		"Only print these comma separated fields (e.g. State,DnsPrefix) as key=value")
+	f.StringVar(&graphFormat, "graph", "",
+		"Instead print the service dependency graph; one of dot or mermaid")
+	f.StringVar(&graphOutfile, "outfile", "",
+		"With --graph, write the graph to this file rather than stdout")
+	f.IntVar(&maxServices, "max-services", DefaultDescribeMaxServices,
+		"Maximum number of services to print")
+	f.IntVar(&maxTables, "max-tables", DefaultDescribeMaxTables,
+		"Maximum number of tables to print per database")
+	f.IntVar(&maxDatastores, "max-datastores", DefaultDescribeMaxDatastores,
+		"Maximum number of datastores to print")
+	f.BoolVar(&showAll, "all", false,
+		"Print every service, table, and datastore regardless of --max-*")
+	f.StringVar(&compareProjId, "compare", "",
+		"Instead diff this project's services, databases, datastores, and endpoints against another project's")
 
 	err = f.Parse(args)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
-		os.Exit(1)
+		exitWithError(ExitUsage, "%v\n", err)
+	}
+	if graphFormat != "" {
+		if graphFormat != GraphDot && graphFormat != GraphMermaid {
+			exitWithError(ExitUsage, "--graph must be one of %v or %v\n",
+				GraphDot, GraphMermaid)
+		}
+		if outputFormat != OutputText || fieldsStr != "" ||
+			watchDstoreName != "" || includeMetrics || showCosts {
+			exitWithError(ExitUsage, "--graph cannot be combined with --output, --fields, --watch-datastore, --include-metrics, or --show-costs\n")
+		}
+	} else if graphOutfile != "" {
+		exitWithError(ExitUsage, "--outfile requires --graph\n")
+	}
+	if compareProjId != "" && (graphFormat != "" || fieldsStr != "" ||
+		watchDstoreName != "" || includeMetrics || showCosts ||
+		len(resourceNames) > 0) {
+		exitWithError(ExitUsage, "--compare cannot be combined with --graph, --fields, --watch-datastore, --include-metrics, --show-costs, or --resource\n")
+	}
+	if maxServices < 0 || maxTables < 0 || maxDatastores < 0 {
+		exitWithError(ExitUsage, "--max-services, --max-tables, and --max-datastores cannot be negative\n")
+	}
+	if showAll {
+		maxServices, maxTables, maxDatastores = -1, -1, -1
+	}
+	fields, err := parseFields(fieldsStr, projDescribeOutput{})
+	if err != nil {
+		exitWithError(ExitUsage, "%v\n", err)
 	}
 	err = setProjIdFromOpts(&opts)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
-		os.Exit(1)
+		exitWithError(ExitUsage, "%v\n", err)
+	}
+	if compareProjId != "" {
+		compareOut, err := compareProjects(opts.projectId, compareProjId,
+			sdkOpts)
+		if err != nil {
+			exitWithError(ExitServer, "%v\n", err)
+		}
+		if outputFormat != OutputText {
+			err = printStructured(compareOut)
+			if err != nil {
+				exitWithError(ExitFailure, "Failed to render comparison: %v\n",
+					err)
+			}
+			return
+		}
+		printProjCompare(compareOut)
+		return
+	}
+	if watchDstoreName != "" {
+		if outputFormat != OutputText {
+			exitWithError(ExitUsage, "--watch-datastore only supports text output\n")
+		}
+		if watchInterval <= 0 {
+			exitWithError(ExitUsage, "--interval must be positive\n")
+		}
+		watchDatastore(opts.projectId, watchDstoreName, watchInterval, sdkOpts)
+		return
+	}
+	// the window only applies to metrics & costs so isn't validated without them
+	var startTime, endTime time.Time
+	if includeMetrics || showCosts {
+		startTime, endTime, err = parseTimeWindow(startTimeStr, endTimeStr)
+		if err != nil {
+			exitWithError(ExitFailure, "%v\n", err)
+		}
 	}
 
 	projDesc, err := bopsdk.DescribeProject(opts.projectId, sdkOpts...)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to describe project: %v\n", err)
-		os.Exit(1)
+		exitWithError(ExitServer, "Failed to describe project: %v\n", err)
+	}
+	var costs *projCostsOutput
+	var costsErr error
+	if showCosts {
+		costs, costsErr = getProjectCosts(projDesc.Id, startTime, endTime,
+			sdkOpts)
 	}
 
-	fmt.Printf("Project %v:\n", projDesc.Id)
-	fmt.Printf("\tName: %v\n", projDesc.Header.Name)
-	fmt.Printf("\tDnsPrefix: %v\n", projDesc.Header.DnsPrefix)
-	fmt.Printf("\tDnsDomain: %v\n", projDesc.Header.DnsDomain)
-	fmt.Printf("\tCreated: %v (%v)\n", unixTime2UtcStr(projDesc.CreateTime),
-		unixTime2Local(projDesc.CreateTime))
-	fmt.Printf("\tState: %v\n", projDesc.State)
-	fmt.Printf("\tActive deployments: %v\n", projDesc.ActiveDeployIds)
-	fmt.Printf("\tPending deployments: %v\n", projDesc.PendingDeployIds)
+	if outputFormat == OutputText && fields == nil && graphFormat == "" {
+		fmt.Printf("Project %v:\n", projDesc.Id)
+		fmt.Printf("\tName: %v\n", projDesc.Header.Name)
+		fmt.Printf("\tDnsPrefix: %v\n", projDesc.Header.DnsPrefix)
+		fmt.Printf("\tDnsDomain: %v\n", projDesc.Header.DnsDomain)
+		if timestampFormat == TimestampsUtc {
+			fmt.Printf("\tCreated: %v (%v)\n",
+				unixTime2UtcStr(projDesc.CreateTime),
+				unixTime2Local(projDesc.CreateTime))
+		} else {
+			fmt.Printf("\tCreated: %v\n", formatTimestamp(projDesc.CreateTime))
+		}
+		fmt.Printf("\tState: %v\n", projDesc.State)
+		fmt.Printf("\tActive deployments: %v\n", projDesc.ActiveDeployIds)
+		fmt.Printf("\tPending deployments: %v\n", projDesc.PendingDeployIds)
+		if showCosts {
+			printProjectCosts(costs, costsErr)
+		}
+	}
 
 	if len(projDesc.ActiveDeployIds) == 0 {
+		if graphFormat != "" {
+			// nothing is deployed so the graph is empty
+			err = writeProjGraph(newProjGraph(projDesc.Id, nil, nil, nil),
+				graphFormat, graphOutfile)
+			if err != nil {
+				exitWithError(ExitFailure, "%v\n", err)
+			}
+		} else if outputFormat != OutputText || fields != nil {
+			printProjDescribeStructured(&projDescribeResults{
+				projDesc:  projDesc,
+				showCosts: showCosts,
+				costs:     costs,
+			}, fields)
+		}
 		return
 	}
 
-	var wg errgroup.Group
 	var descSiteReply *pb.DescribeSiteReply
 	var svcDescList []*pb.DescribeServiceReply
 	var dbDescList []*pb.DescribeDatabaseReply
 	var dstoreDescList []*pb.DescribeDatastoreReply
 
-	wg.Go(func() error {
-		var err error
-		descSiteReply, err = bopsdk.DescribeSite(projDesc.Id, "", sdkOpts...)
-		return err
-	})
-	wg.Go(func() error {
-		var err error
-		svcDescList, err = bopsdk.DescribeAllServices(projDesc.Id, "",
-			sdkOpts...)
-		return err
-	})
-	wg.Go(func() error {
-		var err error
-		dbDescList, err = bopsdk.DescribeAllDatabases(projDesc.Id, "",
-			sdkOpts...)
-		return err
-	})
-	wg.Go(func() error {
-		var err error
-		dstoreDescList, err = bopsdk.DescribeAllDatastores(projDesc.Id, "",
-			sdkOpts...)
-		return err
-	})
-
-	err = wg.Wait()
-	if err != nil {
-		fmt.Fprintf(os.Stderr,
+	if len(resourceNames) > 0 {
+		svcDescList, dbDescList, dstoreDescList, err =
+			describeProjResources(projDesc.Id, resourceNames, sdkOpts)
+	} else {
+		descSiteReply, svcDescList, dbDescList, dstoreDescList, err =
+			describeAllProjResources(projDesc.Id, sdkOpts)
+	}
+	if errors.Is(err, errResourceNotFound) {
+		exitWithError(ExitNotFound, "%v\n", err)
+	} else if err != nil {
+		exitWithError(ExitServer,
 			"Failed to retrieve additional project details: %v\n", err)
-		os.Exit(1)
 	}
 
-	fmt.Printf("\tWebsite: %v\n", descSiteReply.SiteEndpoint)
+	if graphFormat != "" {
+		err = writeProjGraph(newProjGraph(projDesc.Id, svcDescList, dbDescList,
+			dstoreDescList), graphFormat, graphOutfile)
+		if err != nil {
+			exitWithError(ExitFailure, "%v\n", err)
+		}
+		return
+	}
+
+	// metrics are best effort; failures are reported inline per resource
+	// rather than failing the describe
+	dbMetrics := make([]string, len(dbDescList))
+	dbMetricsErrs := make([]error, len(dbDescList))
+	dstoreMetrics := make([]string, len(dstoreDescList))
+	dstoreMetricsErrs := make([]error, len(dstoreDescList))
+	if includeMetrics {
+		metricsWg := newFanOutGroup()
+		for idx, dbDesc := range dbDescList {
+			metricsWg.Go(func() error {
+				dbMetrics[idx], dbMetricsErrs[idx] = getMetricSamples(
+					projDesc.Id, "", models.MetricsScopeMETRICSCOPEDATABASE,
+					dbDesc.Desc.DatabaseHeader.DatabaseName, startTime,
+					endTime)
+				return nil
+			})
+		}
+		for idx, dstoreDesc := range dstoreDescList {
+			metricsWg.Go(func() error {
+				dstoreMetrics[idx], dstoreMetricsErrs[idx] = getMetricSamples(
+					projDesc.Id, "", models.MetricsScopeMETRICSCOPEDATASTORE,
+					dstoreDesc.Desc.DatastoreHeader.DatastoreName, startTime,
+					endTime)
+				return nil
+			})
+		}
+		_ = metricsWg.Wait()
+	}
+
+	if outputFormat != OutputText || fields != nil {
+		printProjDescribeStructured(&projDescribeResults{
+			projDesc:          projDesc,
+			site:              descSiteReply,
+			svcDescList:       svcDescList,
+			dbDescList:        dbDescList,
+			dstoreDescList:    dstoreDescList,
+			includeMetrics:    includeMetrics,
+			dbMetrics:         dbMetrics,
+			dbMetricsErrs:     dbMetricsErrs,
+			dstoreMetrics:     dstoreMetrics,
+			dstoreMetricsErrs: dstoreMetricsErrs,
+			showCosts:         showCosts,
+			costs:             costs,
+		}, fields)
+		return
+	}
+
+	if includeMetrics {
+		fmt.Printf("\tMetrics window: %v - %v\n", startTime, endTime)
+	}
 
-	for _, svcDesc := range svcDescList {
+	if descSiteReply != nil {
+		fmt.Printf("\tWebsite: %v\n", descSiteReply.SiteEndpoint)
+	}
+
+	for _, svcDesc := range truncateList(svcDescList, maxServices) {
 		fmt.Printf("\tService %v:\n", svcDesc.Desc.SvcHeader.ServiceName)
 		fmt.Printf("\t\tApi Definition: %v\n", svcDesc.Desc.ApiDef)
 		fmt.Printf("\t\tPort: %v\n", svcDesc.Desc.Port)
@@ -148,7 +328,9 @@ func projDescribeMain(args []string) {
 		}
 	}
 
-	for _, dbDesc := range dbDescList {
+	printMoreFooter("\t", len(svcDescList), maxServices, "services")
+
+	for dbIdx, dbDesc := range dbDescList {
 		fmt.Printf("\tDatabase %v:\n", dbDesc.Desc.DatabaseHeader.DatabaseName)
 		if len(dbDesc.Desc.ServiceNames) > 0 {
 			fmt.Printf("\t\tServices: ")
@@ -158,15 +340,21 @@ func projDescribeMain(args []string) {
 			fmt.Printf("\n")
 		}
 		if len(dbDesc.Desc.Tables) > 0 {
-			for _, tbl := range dbDesc.Desc.Tables {
+			for _, tbl := range truncateList(dbDesc.Desc.Tables, maxTables) {
 				fmt.Printf("\t\tTable %v:\n", tbl.Name)
 				fmt.Printf("\t\t\tNumRows: %v\n", tbl.NumRows)
 				fmt.Printf("\t\t\tSize: %v MiB\n", tbl.Size/1024/1024)
 			}
+			printMoreFooter("\t\t", len(dbDesc.Desc.Tables), maxTables,
+				"tables")
+		}
+		if includeMetrics {
+			printMetricsSummary("\t\t", dbMetrics[dbIdx], dbMetricsErrs[dbIdx])
 		}
 	}
 
-	for _, dstoreDesc := range dstoreDescList {
+	for dstoreIdx, dstoreDesc := range truncateList(dstoreDescList,
+		maxDatastores) {
 		fmt.Printf("\tDatastore %v:\n",
 			dstoreDesc.Desc.DatastoreHeader.DatastoreName)
 		fmt.Printf("\t\tNumObjects: %v\n", dstoreDesc.Desc.NumObjects)
@@ -179,19 +367,425 @@ func projDescribeMain(args []string) {
 			}
 			fmt.Printf("\n")
 		}
+		if includeMetrics {
+			printMetricsSummary("\t\t", dstoreMetrics[dstoreIdx],
+				dstoreMetricsErrs[dstoreIdx])
+		}
+	}
+	printMoreFooter("\t", len(dstoreDescList), maxDatastores, "datastores")
+}
+
+// how many of each resource 'bopmatic project describe' prints without --all
+const (
+	DefaultDescribeMaxServices   = 25
+	DefaultDescribeMaxTables     = 25
+	DefaultDescribeMaxDatastores = 25
+)
+
+// truncateList returns at most the first max elements of list; a negative
+// max means no limit
+func truncateList[T any](list []T, max int) []T {
+	if max < 0 || len(list) <= max {
+		return list
 	}
+
+	return list[:max]
+}
+
+// printMoreFooter notes how many of total resources truncateList omitted
+func printMoreFooter(indent string, total int, max int, resourceKind string) {
+	if max < 0 || total <= max {
+		return
+	}
+
+	fmt.Printf("%v... and %v more %v (use --all to show all)\n", indent,
+		total-max, resourceKind)
+}
+
+var errResourceNotFound = errors.New("resource not found")
+
+// watchDatastore describes dstoreName every interval and prints its object
+// count and size along with the change since the previous sample until
+// interrupted
+func watchDatastore(projId string, dstoreName string, interval time.Duration,
+	sdkOpts []bopsdk.DeployOption) {
+
+	// @todo specify environment id
+	dstoreNames, err := bopsdk.ListDatastores(projId, "", sdkOpts...)
+	if err != nil {
+		exitWithError(ExitServer, "%v\n", err)
+	}
+	found := false
+	for _, name := range dstoreNames {
+		if name == dstoreName {
+			found = true
+			break
+		}
+	}
+	if !found {
+		exitWithError(ExitNotFound, "%v: projId:%v has no datastore named %v\n",
+			errResourceNotFound, projId, dstoreName)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	fmt.Printf("Watching datastore %v every %v; press Ctrl-C to stop\n",
+		dstoreName, interval)
+	fmt.Printf("%-10v%16v%12v%16v%16v\n", "TIME", "OBJECTS", "DELTA",
+		"BYTES", "DELTA")
+	var lastObjects, lastBytes uint64
+	havePrev := false
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		// @todo specify environment id
+		dstoreDesc, err := bopsdk.DescribeDatastore(projId, "", dstoreName,
+			sdkOpts...)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			logWarn("Failed to describe datastore %v: %v", dstoreName, err)
+		} else {
+			numObjects := dstoreDesc.Desc.NumObjects
+			numBytes := dstoreDesc.Desc.CapacityConsumedInBytes
+			objectsDelta, bytesDelta := "-", "-"
+			if havePrev {
+				objectsDelta = fmt.Sprintf("%+d",
+					int64(numObjects)-int64(lastObjects))
+				bytesDelta = fmt.Sprintf("%+d", int64(numBytes)-int64(lastBytes))
+			}
+			fmt.Printf("%-10v%16v%12v%16v%16v\n",
+				time.Now().Format(time.TimeOnly), numObjects, objectsDelta,
+				numBytes, bytesDelta)
+			lastObjects, lastBytes = numObjects, numBytes
+			havePrev = true
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// describeAllProjResources describes the site and every service, database,
+// and datastore of projId
+func describeAllProjResources(projId string,
+	sdkOpts []bopsdk.DeployOption) (*pb.DescribeSiteReply,
+	[]*pb.DescribeServiceReply, []*pb.DescribeDatabaseReply,
+	[]*pb.DescribeDatastoreReply, error) {
+
+	wg := newFanOutGroup()
+	var descSiteReply *pb.DescribeSiteReply
+	var svcDescList []*pb.DescribeServiceReply
+	var dbDescList []*pb.DescribeDatabaseReply
+	var dstoreDescList []*pb.DescribeDatastoreReply
+
+	wg.Go(func() error {
+		var err error
+		descSiteReply, err = bopsdk.DescribeSite(projId, "", sdkOpts...)
+		return err
+	})
+	wg.Go(func() error {
+		var err error
+		svcDescList, err = bopsdk.DescribeAllServices(projId, "", sdkOpts...)
+		return err
+	})
+	wg.Go(func() error {
+		var err error
+		dbDescList, err = bopsdk.DescribeAllDatabases(projId, "", sdkOpts...)
+		return err
+	})
+	wg.Go(func() error {
+		var err error
+		dstoreDescList, err = bopsdk.DescribeAllDatastores(projId, "",
+			sdkOpts...)
+		return err
+	})
+	err := wg.Wait()
+
+	return descSiteReply, svcDescList, dbDescList, dstoreDescList, err
+}
+
+// describeProjResources describes only the services, databases, and
+// datastores of projId named in resourceNames. Names are resolved by
+// listing each resource type so that unrelated resources are never
+// described; errResourceNotFound is returned for names matching none of
+// them.
+func describeProjResources(projId string, resourceNames []string,
+	sdkOpts []bopsdk.DeployOption) ([]*pb.DescribeServiceReply,
+	[]*pb.DescribeDatabaseReply, []*pb.DescribeDatastoreReply, error) {
+
+	var svcNames, dbNames, dstoreNames []string
+	wg := newFanOutGroup()
+	wg.Go(func() error {
+		var err error
+		svcNames, err = bopsdk.ListServices(projId, "", sdkOpts...)
+		return err
+	})
+	wg.Go(func() error {
+		var err error
+		dbNames, err = bopsdk.ListDatabases(projId, "", sdkOpts...)
+		return err
+	})
+	wg.Go(func() error {
+		var err error
+		dstoreNames, err = bopsdk.ListDatastores(projId, "", sdkOpts...)
+		return err
+	})
+	err := wg.Wait()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	contains := func(names []string, name string) bool {
+		for _, n := range names {
+			if n == name {
+				return true
+			}
+		}
+		return false
+	}
+	var wantSvcs, wantDbs, wantDstores, missing []string
+	for _, name := range resourceNames {
+		found := false
+		if contains(svcNames, name) {
+			wantSvcs = append(wantSvcs, name)
+			found = true
+		}
+		if contains(dbNames, name) {
+			wantDbs = append(wantDbs, name)
+			found = true
+		}
+		if contains(dstoreNames, name) {
+			wantDstores = append(wantDstores, name)
+			found = true
+		}
+		if !found {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, nil, nil, fmt.Errorf("%w: projId:%v has no service, database, or datastore named %v",
+			errResourceNotFound, projId, strings.Join(missing, ", "))
+	}
+
+	svcDescList := make([]*pb.DescribeServiceReply, len(wantSvcs))
+	dbDescList := make([]*pb.DescribeDatabaseReply, len(wantDbs))
+	dstoreDescList := make([]*pb.DescribeDatastoreReply, len(wantDstores))
+	wg = newFanOutGroup()
+	for idx, svcName := range wantSvcs {
+		wg.Go(func() error {
+			var err error
+			svcDescList[idx], err = bopsdk.DescribeService(projId, "",
+				svcName, sdkOpts...)
+			return err
+		})
+	}
+	for idx, dbName := range wantDbs {
+		wg.Go(func() error {
+			var err error
+			dbDescList[idx], err = bopsdk.DescribeDatabase(projId, "",
+				dbName, sdkOpts...)
+			return err
+		})
+	}
+	for idx, dstoreName := range wantDstores {
+		wg.Go(func() error {
+			var err error
+			dstoreDescList[idx], err = bopsdk.DescribeDatastore(projId, "",
+				dstoreName, sdkOpts...)
+			return err
+		})
+	}
+	err = wg.Wait()
+
+	return svcDescList, dbDescList, dstoreDescList, err
+}
+
+// projDescribeResults holds everything gathered by projDescribeMain so that
+// it can be rendered in a structured output format
+type projDescribeResults struct {
+	projDesc          *pb.ProjectDescription
+	site              *pb.DescribeSiteReply
+	svcDescList       []*pb.DescribeServiceReply
+	dbDescList        []*pb.DescribeDatabaseReply
+	dstoreDescList    []*pb.DescribeDatastoreReply
+	includeMetrics    bool
+	dbMetrics         []string
+	dbMetricsErrs     []error
+	dstoreMetrics     []string
+	dstoreMetricsErrs []error
+	showCosts         bool
+	costs             *projCostsOutput
+}
+
+type projServiceOutput struct {
+	Name         string   `json:"name"`
+	ApiDef       string   `json:"apiDef"`
+	Port         uint64   `json:"port"`
+	Databases    []string `json:"databases"`
+	Datastores   []string `json:"datastores"`
+	RpcEndpoints []string `json:"rpcEndpoints"`
+}
+
+type projTableOutput struct {
+	Name    string `json:"name"`
+	NumRows uint64 `json:"numRows"`
+	Size    uint64 `json:"size"`
+}
+
+type projDatabaseOutput struct {
+	Name     string            `json:"name"`
+	Services []string          `json:"services"`
+	Tables   []projTableOutput `json:"tables"`
+	Metrics  *metricsOutput    `json:"metrics,omitempty"`
+}
+
+type projDatastoreOutput struct {
+	Name       string         `json:"name"`
+	NumObjects uint64         `json:"numObjects"`
+	Size       uint64         `json:"size"`
+	Services   []string       `json:"services"`
+	Metrics    *metricsOutput `json:"metrics,omitempty"`
+}
+
+type projDescribeOutput struct {
+	Id               string                `json:"id"`
+	Name             string                `json:"name"`
+	DnsPrefix        string                `json:"dnsPrefix"`
+	DnsDomain        string                `json:"dnsDomain"`
+	Created          string                `json:"created"`
+	State            string                `json:"state"`
+	ActiveDeployIds  []string              `json:"activeDeployIds"`
+	PendingDeployIds []string              `json:"pendingDeployIds"`
+	Website          string                `json:"website,omitempty"`
+	Services         []projServiceOutput   `json:"services"`
+	Databases        []projDatabaseOutput  `json:"databases"`
+	Datastores       []projDatastoreOutput `json:"datastores"`
+	Costs            json.RawMessage       `json:"costs,omitempty"`
+}
+
+// printProjDescribeStructured renders results as json or yaml, or only
+// fields as key=value when --fields was specified
+func printProjDescribeStructured(results *projDescribeResults,
+	fields []string) {
+
+	projDesc := results.projDesc
+	projOut := projDescribeOutput{
+		Id:               projDesc.Id,
+		Name:             projDesc.Header.Name,
+		DnsPrefix:        projDesc.Header.DnsPrefix,
+		DnsDomain:        projDesc.Header.DnsDomain,
+		Created:          formatTimestamp(projDesc.CreateTime),
+		State:            projDesc.State.String(),
+		ActiveDeployIds:  nonNilStrings(projDesc.ActiveDeployIds),
+		PendingDeployIds: nonNilStrings(projDesc.PendingDeployIds),
+		Services:         make([]projServiceOutput, 0),
+		Databases:        make([]projDatabaseOutput, 0),
+		Datastores:       make([]projDatastoreOutput, 0),
+	}
+	if results.site != nil {
+		projOut.Website = results.site.SiteEndpoint
+	}
+	if results.showCosts {
+		// costs are null rather than omitted when requested but unavailable
+		costsJson, err := json.Marshal(results.costs)
+		if err != nil {
+			exitWithError(ExitFailure, "Failed to render costs: %v\n", err)
+		}
+		projOut.Costs = costsJson
+	}
+
+	for _, svcDesc := range results.svcDescList {
+		projOut.Services = append(projOut.Services, projServiceOutput{
+			Name:         svcDesc.Desc.SvcHeader.ServiceName,
+			ApiDef:       svcDesc.Desc.ApiDef,
+			Port:         svcDesc.Desc.Port,
+			Databases:    nonNilStrings(svcDesc.Desc.DatabaseNames),
+			Datastores:   nonNilStrings(svcDesc.Desc.DatastoreNames),
+			RpcEndpoints: nonNilStrings(svcDesc.Desc.RpcEndpoints),
+		})
+	}
+	for dbIdx, dbDesc := range results.dbDescList {
+		dbOut := projDatabaseOutput{
+			Name:     dbDesc.Desc.DatabaseHeader.DatabaseName,
+			Services: nonNilStrings(dbDesc.Desc.ServiceNames),
+			Tables:   make([]projTableOutput, 0),
+		}
+		for _, tbl := range dbDesc.Desc.Tables {
+			dbOut.Tables = append(dbOut.Tables, projTableOutput{
+				Name:    tbl.Name,
+				NumRows: tbl.NumRows,
+				Size:    tbl.Size,
+			})
+		}
+		if results.includeMetrics {
+			dbOut.Metrics = newMetricsOutput(results.dbMetrics[dbIdx],
+				results.dbMetricsErrs[dbIdx])
+		}
+		projOut.Databases = append(projOut.Databases, dbOut)
+	}
+	for dstoreIdx, dstoreDesc := range results.dstoreDescList {
+		dstoreOut := projDatastoreOutput{
+			Name:       dstoreDesc.Desc.DatastoreHeader.DatastoreName,
+			NumObjects: dstoreDesc.Desc.NumObjects,
+			Size:       dstoreDesc.Desc.CapacityConsumedInBytes,
+			Services:   nonNilStrings(dstoreDesc.Desc.ServiceNames),
+		}
+		if results.includeMetrics {
+			dstoreOut.Metrics = newMetricsOutput(
+				results.dstoreMetrics[dstoreIdx],
+				results.dstoreMetricsErrs[dstoreIdx])
+		}
+		projOut.Datastores = append(projOut.Datastores, dstoreOut)
+	}
+
+	var err error
+	if fields != nil {
+		err = printFields(&projOut, fields)
+	} else {
+		err = printStructured(&projOut)
+	}
+	if err != nil {
+		exitWithError(ExitFailure, "Failed to render project: %v\n", err)
+	}
+}
+
+func (o *projOpts) projFile() string {
+	return resolveProjFile(o.projectDir, o.projectFilename, o.noDiscovery)
 }
 
 func setProjIdFromOpts(opts *projOpts) error {
 	if opts.projectId == "" {
-		proj, err := bopsdk.NewProject(opts.projectFilename)
+		projId, err := resolveProjectId(opts.projFile())
 		if err != nil {
-			err = fmt.Errorf("Could not find project file '%v': %v. Please specify --projid, --projfile, run from within a Bopmatic project directory.\n",
-				opts.projectFilename, err)
 			return err
 		}
-		opts.projectId = proj.Desc.Id
+		opts.projectId = projId
+	}
+
+	return nil
+}
+
+// requireProjIdFromOpts is setProjIdFromOpts for destructive commands, which
+// must name their project explicitly via --projid or a project file rather
+// than acting on ProjectIdEnvVar or the default project
+func requireProjIdFromOpts(opts *projOpts) error {
+	if opts.projectId != "" {
+		return nil
 	}
+	proj, err := loadProject(opts.projFile())
+	if errors.Is(err, errNoProject) && getDefaultProjectId() != "" {
+		return fmt.Errorf("%w The default project is not used by destructive commands.",
+			err)
+	} else if err != nil {
+		return err
+	}
+	opts.projectId = proj.Desc.Id
 
 	return nil
 }
@@ -212,17 +806,58 @@ func selectProjectTemplateKey(tmplNameIn string,
 		return tmplNameIn
 	}
 
-	fmt.Fprintf(os.Stderr, "%v is not a valid project template\n", tmplNameIn)
+	logWarn("%v is not a valid project template", tmplNameIn)
 	return ""
 }
 
+const (
+	// bounds on listing a directory within the build container so that a
+	// hung container or a pathological directory can't wedge the CLI
+	containerDirReadTimeout   = 30 * time.Second
+	containerDirMaxOutputSize = 1024 * 1024
+)
+
+var errContainerOutputTooLarge = errors.New("container output too large")
+
+// boundedBuffer is a bytes.Buffer which refuses writes beyond max bytes and
+// invokes onOverflow the first time that happens
+type boundedBuffer struct {
+	bytes.Buffer
+	max        int
+	overflowed bool
+	onOverflow func()
+}
+
+func (b *boundedBuffer) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > b.max {
+		if !b.overflowed {
+			b.overflowed = true
+			b.onOverflow()
+		}
+		return 0, errContainerOutputTooLarge
+	}
+
+	return b.Buffer.Write(p)
+}
+
 func readContainerDir(dir string) (dirEntries []string, err error) {
 
-	ctx := context.Background()
-	tmpBuf := new(bytes.Buffer)
+	ctx, cancel := context.WithTimeout(context.Background(),
+		containerDirReadTimeout)
+	defer cancel()
+	tmpBuf := &boundedBuffer{
+		max:        containerDirMaxOutputSize,
+		onOverflow: cancel,
+	}
 
-	err = util.RunContainerCommand(ctx, []string{"ls", dir}, tmpBuf, os.Stderr)
-	if err != nil {
+	err = runBuildContainerCommand(ctx, []string{"ls", dir}, tmpBuf, os.Stderr)
+	if tmpBuf.overflowed {
+		return nil, fmt.Errorf("listing %v exceeded %v bytes: %w", dir,
+			containerDirMaxOutputSize, errContainerOutputTooLarge)
+	} else if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return nil, fmt.Errorf("listing %v timed out after %v", dir,
+			containerDirReadTimeout)
+	} else if err != nil {
 		return nil, err
 	}
 
@@ -236,21 +871,49 @@ func readContainerDir(dir string) (dirEntries []string, err error) {
 	return dirEntries, nil
 }
 
-func fetchTemplateSet(subdirs []string) map[string]ProjTemplate {
+// TemplateListJobs bounds how many build containers are run at once when
+// listing project templates
+const TemplateListJobs = 3
 
-	tmplSet := make(map[string]ProjTemplate)
+// listTemplateDirs concurrently lists each subdir of ExamplesDir within the
+// build container. Subdirs which can't be listed are skipped with a warning.
+func listTemplateDirs(subdirs []string) map[string][]string {
+	subdirEntries := make([][]string, len(subdirs))
+	var wg errgroup.Group
+	wg.SetLimit(TemplateListJobs)
+	for idx, subdir := range subdirs {
+		wg.Go(func() error {
+			dir := fmt.Sprintf("%v/%v", ExamplesDir, subdir)
+			dirEntries, err := readContainerDir(dir)
+			if err != nil {
+				// can occur if user has an older build container'
+				logWarn("Failed to retrieve list of %v templates: %v. Skipping.",
+					subdir, err)
+				return nil
+			}
+			subdirEntries[idx] = dirEntries
+			return nil
+		})
+	}
+	_ = wg.Wait()
 
-	for _, subdir := range subdirs {
-		dir := fmt.Sprintf("%v/%v", ExamplesDir, subdir)
-		dirEntries, err := readContainerDir(dir)
-		if err != nil {
-			// can occur if user has an older build container'
-			fmt.Fprintf(os.Stderr, "Failed to retrieve list of %v templates: %v. Skipping.\n",
-				subdir, err)
-			continue
+	listing := make(map[string][]string)
+	for idx, subdir := range subdirs {
+		if subdirEntries[idx] != nil {
+			listing[subdir] = subdirEntries[idx]
 		}
+	}
+
+	return listing
+}
+
+func fetchTemplateSet(subdirs []string,
+	listing map[string][]string) map[string]ProjTemplate {
 
-		for _, tmpl := range dirEntries {
+	tmplSet := make(map[string]ProjTemplate)
+
+	for _, subdir := range subdirs {
+		for _, tmpl := range listing[subdir] {
 			nameKey := subdir + "/" + tmpl
 			tmplSet[nameKey] = ProjTemplate{
 				name:    nameKey,
@@ -266,35 +929,72 @@ func fetchTemplates() (serviceTemplates, clientTemplates map[string]ProjTemplate
 
 	supportedLanguages := []string{"golang", "java", "python", "nodejs"}
 
-	serviceTemplates = fetchTemplateSet(supportedLanguages)
+	listing := listTemplateDirs(append([]string{ClientTemplateSubdir},
+		supportedLanguages...))
+
+	serviceTemplates = fetchTemplateSet(supportedLanguages, listing)
 
 	serviceTemplates["staticsite"] = ProjTemplate{
 		name:    "staticsite",
 		srcPath: ExamplesDir + "/staticsite",
 	}
 
-	clientTemplates = fetchTemplateSet([]string{ClientTemplateSubdir})
+	clientTemplates = fetchTemplateSet([]string{ClientTemplateSubdir}, listing)
 
 	return serviceTemplates, clientTemplates
 }
 
+func sortedTemplateKeys(templates map[string]ProjTemplate) []string {
+	keys := make([]string, 0, len(templates))
+	for key := range templates {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+type templateListOutput struct {
+	ServiceTemplates []string `json:"serviceTemplates"`
+	ClientTemplates  []string `json:"clientTemplates"`
+}
+
+// printTemplates lists the keys of the available service & client templates
+func printTemplates(serviceTemplates map[string]ProjTemplate,
+	clientTemplates map[string]ProjTemplate) {
+
+	templatesOut := templateListOutput{
+		ServiceTemplates: sortedTemplateKeys(serviceTemplates),
+		ClientTemplates:  sortedTemplateKeys(clientTemplates),
+	}
+	if outputFormat != OutputText {
+		err := printStructured(&templatesOut)
+		if err != nil {
+			exitWithError(ExitFailure, "Failed to render templates: %v\n", err)
+		}
+		return
+	}
+
+	fmt.Printf("Service templates:\n")
+	for _, key := range templatesOut.ServiceTemplates {
+		fmt.Printf("\t%v\n", key)
+	}
+	fmt.Printf("Client templates:\n")
+	for _, key := range templatesOut.ClientTemplates {
+		fmt.Printf("\t%v\n", key)
+	}
+}
+
 func getUserInputsForNewPkg(serviceTemplates map[string]ProjTemplate) (
 	selectedTmplKey, projectName string) {
 
 	user, err := user.Current()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Unable to determine your username: %v", err)
-		os.Exit(1)
+		exitWithError(ExitFailure, "Unable to determine your username: %v", err)
 	}
 
 	fmt.Printf("Available project templates:\n")
-	var templateKeysSorted []string
-	for key, _ := range serviceTemplates {
-		templateKeysSorted = append(templateKeysSorted, key)
-	}
-
-	sort.Strings(templateKeysSorted)
-	for _, key := range templateKeysSorted {
+	for _, key := range sortedTemplateKeys(serviceTemplates) {
 		fmt.Printf("\t%v\n", key)
 	}
 
@@ -318,7 +1018,7 @@ func getUserInputsForNewPkg(serviceTemplates map[string]ProjTemplate) (
 		if isGoodName {
 			break
 		} else {
-			fmt.Fprintf(os.Stderr, "%v\n", reason)
+			logError("%v", reason)
 		}
 	}
 
@@ -333,9 +1033,8 @@ func replaceTemplateKeywordInFile(filename, existingText, replaceText string,
 		if ignoreIfNotExist && os.IsNotExist(err) {
 			return
 		}
-		fmt.Fprintf(os.Stderr, "Failed to set replace %v with %v in %v: %v",
+		exitWithError(ExitFailure, "Failed to set replace %v with %v in %v: %v",
 			existingText, replaceText, filename, err)
-		os.Exit(1)
 	}
 	fileContent := string(fileContentBytes)
 
@@ -349,8 +1048,7 @@ func replaceTemplateKeywordInFile(filename, existingText, replaceText string,
 
 	err = ioutil.WriteFile(filename, []byte(fileContent), 0644)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to update %v: %v", filename, err)
-		os.Exit(1)
+		exitWithError(ExitFailure, "Failed to update %v: %v", filename, err)
 	}
 }
 
@@ -360,13 +1058,12 @@ func createProjectFromTemplate(serviceTemplates, clientTemplates map[string]Proj
 	ctx := context.Background()
 
 	// copy project from template
-	err := util.RunContainerCommand(ctx, []string{"cp", "-r",
+	err := runBuildContainerCommand(ctx, []string{"cp", "-r",
 		serviceTemplates[selectedTmplKey].srcPath, "./" + projectName},
 		os.Stdout, os.Stderr)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to create project %v: %v", projectName,
+		exitWithError(ExitFailure, "Failed to create project %v: %v", projectName,
 			err)
-		os.Exit(1)
 	}
 
 	// if there's a matching client template, replace site_assets with it
@@ -375,20 +1072,18 @@ func createProjectFromTemplate(serviceTemplates, clientTemplates map[string]Proj
 	clientTmpl, ok := clientTemplates[clientTmplKey]
 	if ok {
 		siteAssetsDir := "./" + projectName + "/" + SiteAssetsSubdir
-		err := util.RunContainerCommand(ctx, []string{"rm", "-rf",
+		err := runBuildContainerCommand(ctx, []string{"rm", "-rf",
 			siteAssetsDir}, os.Stdout, os.Stderr)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to remove %v: %v", siteAssetsDir, err)
-			os.Exit(1)
+			exitWithError(ExitFailure, "Failed to remove %v: %v", siteAssetsDir, err)
 		}
 
 		clientDir := "./" + projectName + "/" + ClientTemplateSubdir
-		err = util.RunContainerCommand(ctx, []string{"cp", "-r",
+		err = runBuildContainerCommand(ctx, []string{"cp", "-r",
 			clientTmpl.srcPath, clientDir}, os.Stdout, os.Stderr)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to copy client assets into %v: %v",
+			exitWithError(ExitFailure, "Failed to copy client assets into %v: %v",
 				siteAssetsDir, err)
-			os.Exit(1)
 		}
 	}
 
@@ -402,9 +1097,8 @@ func createProjectFromTemplate(serviceTemplates, clientTemplates map[string]Proj
 
 	templateKeyword, err := ioutil.ReadFile(templateToken)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to set project name %v: %v", projectName,
+		exitWithError(ExitFailure, "Failed to set project name %v: %v", projectName,
 			err)
-		os.Exit(1)
 	}
 
 	replaceTemplateKeywordInFile(projectFile, string(templateKeyword),
@@ -422,45 +1116,91 @@ func createProjectFromTemplate(serviceTemplates, clientTemplates map[string]Proj
 }
 
 func projCreateMain(args []string) {
+	var overwrite, gitInit, listTemplates bool
+	f := flag.NewFlagSet("bopmatic project create", flag.ExitOnError)
+	setGlobalFlags(f)
+	f.BoolVar(&overwrite, "overwrite", false,
+		"Replace an existing directory with the same name as the new project")
+	f.BoolVar(&gitInit, "git-init", false,
+		"Initialize a git repository with a .gitignore in the new project")
+	f.BoolVar(&listTemplates, "list-templates", false,
+		"List the available project templates and exit without creating a project")
+	err := f.Parse(args)
+	if err != nil {
+		exitWithError(ExitUsage, "%v\n", err)
+	}
+
 	// @todo get project id via sr's CreateProject() primitive
-	haveBuildImg, err := util.HasBopmaticBuildImage()
+	haveBuildImg, err := hasBuildImage()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
-		os.Exit(1)
+		exitWithError(ExitFailure, "%v\n", err)
 	}
 	if !haveBuildImg {
-		fmt.Fprintf(os.Stderr, "Could not find Bopmatic Build Image; please run:\n\n\tbopmatic config\n")
-		os.Exit(1)
+		exitWithError(ExitFailure, "Could not find Bopmatic Build Image; please run:\n\n\tbopmatic config\n")
+	}
+
+	// templates are read from the build image so no api key is needed
+	if listTemplates {
+		printTemplates(fetchTemplates())
+		return
 	}
 
 	sdkOpts, err := getAuthSdkOpts()
 	if err != nil {
-		fmt.Fprintf(os.Stderr,
-			"Failed to get user creds; did you run bompatic config? err: %v\n",
-			err)
-		os.Exit(1)
+		exitWithError(ExitAuth, "%v\n", err)
 	}
 
 	serviceTemplates, clientTemplates := fetchTemplates()
 
 	selectedTmplKey, projectName := getUserInputsForNewPkg(serviceTemplates)
 
+	// never merge a template into an existing directory; the result would
+	// be an ambiguous mix of old and new files
+	targetDir := filepath.Join(".", projectName)
+	_, err = os.Stat(targetDir)
+	if err == nil {
+		if !overwrite {
+			exitWithError(ExitUsage, "%v already exists; choose a different project name or pass --overwrite to replace it\n",
+				targetDir)
+		}
+		err = os.RemoveAll(targetDir)
+		if err != nil {
+			exitWithError(ExitFailure, "Failed to remove existing %v: %v\n",
+				targetDir, err)
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		exitWithError(ExitFailure, "%v\n", err)
+	}
+
+	// don't leave a partially populated project behind if copying fails
+	copied := false
+	atExit(func() {
+		if !copied {
+			_ = os.RemoveAll(targetDir)
+		}
+	})
 	projectDir, projectFile := createProjectFromTemplate(serviceTemplates,
 		clientTemplates, selectedTmplKey, projectName)
+	copied = true
+
+	if gitInit {
+		err = gitInitProject(projectDir)
+		if err != nil {
+			logWarn("Skipping git init: %v", err)
+		}
+	}
 
 	// validate everything worked
 	proj, err := bopsdk.NewProject(projectFile)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Created project %v but it fails to parse: %v",
+		exitWithError(ExitFailure, "Created project %v but it fails to parse: %v",
 			projectDir, err)
-		os.Exit(1)
 	}
 
 	err = proj.Register(sdkOpts...)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Created project %v but it failed to register: %v",
+		exitWithError(ExitServer, "Created project %v but it failed to register: %v",
 			projectDir, err)
-		os.Exit(1)
 	}
 
 	fmt.Printf("Successfully created .%v%v:\n%v", string(os.PathSeparator),
@@ -470,35 +1210,80 @@ func projCreateMain(args []string) {
 		projectDir)
 }
 
+// projectGitIgnore keeps build artifacts and local packages out of a newly
+// created project's git repository
+var projectGitIgnore = "# Bopmatic build artifacts and local packages\n" +
+	"/" + bopsdk.DefaultArtifactDir + "/\n" +
+	"*.tar.xz\n"
+
+// gitInitProject runs 'git init' in projectDir and writes a .gitignore
+// unless the template already provides one
+func gitInitProject(projectDir string) error {
+	_, err := exec.LookPath("git")
+	if err != nil {
+		return fmt.Errorf("git not found in PATH")
+	}
+
+	err = util.RunHostCommand(context.Background(),
+		[]string{"git", "init", "--quiet", projectDir}, os.Stdout, os.Stderr)
+	if err != nil {
+		return fmt.Errorf("git init %v failed: %w", projectDir, err)
+	}
+
+	gitIgnorePath := filepath.Join(projectDir, ".gitignore")
+	_, err = os.Stat(gitIgnorePath)
+	if err == nil {
+		return nil
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	return os.WriteFile(gitIgnorePath, []byte(projectGitIgnore), 0644)
+}
+
 func projDestroyMain(args []string) {
 	sdkOpts, err := getAuthSdkOpts()
 	if err != nil {
-		fmt.Fprintf(os.Stderr,
-			"Failed to get user creds; did you run bompatic config? err: %v\n",
-			err)
-		os.Exit(1)
+		exitWithError(ExitAuth, "%v\n", err)
 	}
 
 	var opts projOpts
-	f := flag.NewFlagSet("bopmatic project describe", flag.ExitOnError)
+	var force bool
+	f := flag.NewFlagSet("bopmatic project destroy", flag.ExitOnError)
 	setProjFlags(f, &opts)
+	setLockFlags(f)
+	f.BoolVar(&force, "force", false,
+		"Destroy the project without asking for confirmation")
+	f.BoolVar(&force, "yes", false, "Alias for --force")
 
 	err = f.Parse(args)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
-		os.Exit(1)
+		exitWithError(ExitUsage, "%v\n", err)
 	}
-	err = setProjIdFromOpts(&opts)
+	err = requireProjIdFromOpts(&opts)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
-		os.Exit(1)
+		exitWithError(ExitUsage, "%v\n", err)
 	}
 
+	if !force {
+		projDesc, err := bopsdk.DescribeProject(opts.projectId, sdkOpts...)
+		if err != nil {
+			exitWithError(ExitServer, "Failed to describe project: %v\n", err)
+		}
+
+		fmt.Printf("This will permanently destroy project %v (projectId:%v) and remove it from production.\n",
+			projDesc.Header.Name, opts.projectId)
+		if !confirmByTypedName("project name", projDesc.Header.Name) {
+			exitWithError(ExitFailure, "Project name did not match; not destroying %v. Use --force to skip this confirmation.\n",
+				opts.projectId)
+		}
+	}
+
+	defer lockProjectOrExit(opts.projectId)()
 	fmt.Printf("Destroying projectId:%v...", opts.projectId)
 	err = bopsdk.UnregisterProject(opts.projectId, sdkOpts...)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to destroy project: %v\n", err)
-		os.Exit(1)
+		exitWithError(ExitServer, "Failed to destroy project: %v\n", err)
 	}
 
 	fmt.Printf("done.\nProject %v was successfully deleted\n",
@@ -508,37 +1293,44 @@ func projDestroyMain(args []string) {
 func projDeactivateMain(args []string) {
 	sdkOpts, err := getAuthSdkOpts()
 	if err != nil {
-		fmt.Fprintf(os.Stderr,
-			"Failed to get user creds; did you run bompatic config? err: %v\n",
-			err)
-		os.Exit(1)
+		exitWithError(ExitAuth, "%v\n", err)
 	}
 
 	var opts projOpts
+	var wait bool
 	f := flag.NewFlagSet("bopmatic project deactivate", flag.ExitOnError)
 	setProjFlags(f, &opts)
+	setLockFlags(f)
+	f.BoolVar(&wait, "wait", false,
+		"Wait for deactivation to complete before returning")
 
 	err = f.Parse(args)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
-		os.Exit(1)
+		exitWithError(ExitUsage, "%v\n", err)
 	}
-	err = setProjIdFromOpts(&opts)
+	err = requireProjIdFromOpts(&opts)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
-		os.Exit(1)
+		exitWithError(ExitUsage, "%v\n", err)
 	}
 
+	defer lockProjectOrExit(opts.projectId)()
 	// @todo implement environment ids
 	fmt.Printf("Deactivating projId:%v...", opts.projectId)
 	deployId, err := bopsdk.DeactivateProject(opts.projectId, "", sdkOpts...)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to deactivate project: %v\n", err)
-		os.Exit(1)
+		exitWithError(ExitServer, "Failed to deactivate project: %v\n", err)
+	}
+
+	if !wait {
+		fmt.Printf("Started\nDeactivating takes about 10 minutes. You can check progress with:\n\t'bopmatic deploy describe --deployid %v'\n",
+			deployId)
+		return
 	}
 
-	fmt.Printf("Started\nDeactivating takes about 10 minutes. You can check progress with:\n\t'bopmatic deploy describe --deployid %v'\n",
-		deployId)
+	fmt.Printf("Started deployId:%v\n", deployId)
+	waitForDeployment(deployId, "deactivation", sdkOpts)
+
+	fmt.Printf("Deactivated projId:%v\n", opts.projectId)
 }
 
 //go:embed projHelp.txt
@@ -549,37 +1341,110 @@ func projHelpMain(args []string) {
 }
 
 func setProjFlags(f *flag.FlagSet, o *projOpts) {
+	setGlobalFlags(f)
 	f.StringVar(&o.projectFilename, "projfile", bopsdk.DefaultProjectFilename,
 		"Bopmatic project filename")
+	f.StringVar(&o.projectDir, "projdir", "",
+		"Bopmatic project directory; --projfile is relative to this")
+	f.BoolVar(&o.noDiscovery, "no-discovery", false,
+		"Don't search parent directories for the project file")
 	f.StringVar(&o.projectId, "projid", "", "Bopmatic project id")
 }
 
 func projListMain(args []string) {
 	sdkOpts, err := getAuthSdkOpts()
 	if err != nil {
-		fmt.Fprintf(os.Stderr,
-			"Failed to get user creds; did you run bompatic config? err: %v\n",
-			err)
-		os.Exit(1)
+		exitWithError(ExitAuth, "%v\n", err)
 	}
 
+	var verbose bool
+	var porcelain bool
 	f := flag.NewFlagSet("bopmatic project list", flag.ExitOnError)
+	setGlobalFlags(f)
+	f.BoolVar(&verbose, "verbose", false,
+		"Include each project's name, state, and deployments")
+	f.BoolVar(&porcelain, "porcelain", false,
+		"Print a stable, header-less, space separated format for scripts")
 
 	err = f.Parse(args)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
-		os.Exit(1)
+		exitWithError(ExitUsage, "%v\n", err)
 	}
+	checkPorcelain(porcelain)
 
 	// @todo add envId
+	// @todo group by account/org once the sdk exposes more than the
+	// caller's own account
 	projects, err := bopsdk.ListProjects(sdkOpts...)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
-		os.Exit(1)
+		exitWithError(ExitServer, "%v\n", err)
+	}
+
+	var projDescList []*pb.ProjectDescription
+	if verbose {
+		projDescList = make([]*pb.ProjectDescription, len(projects))
+		wg := newFanOutGroup()
+		for idx, projId := range projects {
+			wg.Go(func() error {
+				var err error
+				projDescList[idx], err = bopsdk.DescribeProject(projId,
+					sdkOpts...)
+				return err
+			})
+		}
+		err = wg.Wait()
+		if err != nil {
+			exitWithError(ExitServer, "Failed to describe project: %v\n", err)
+		}
+	}
+
+	if outputFormat != OutputText {
+		var listOut any = nonNilStrings(projects)
+		if verbose {
+			projListOut := make([]projListItemOutput, 0, len(projDescList))
+			for _, projDesc := range projDescList {
+				projListOut = append(projListOut, projListItemOutput{
+					Id:               projDesc.Id,
+					Name:             projDesc.Header.Name,
+					State:            projDesc.State.String(),
+					Created:          unixTime2UtcStr(projDesc.CreateTime),
+					ActiveDeployIds:  nonNilStrings(projDesc.ActiveDeployIds),
+					PendingDeployIds: nonNilStrings(projDesc.PendingDeployIds),
+				})
+			}
+			listOut = projListOut
+		}
+		err = printStructured(listOut)
+		if err != nil {
+			exitWithError(ExitFailure, "Failed to render projects: %v\n", err)
+		}
+		return
 	}
 
-	if len(projects) == 0 {
+	if porcelain {
+		// <project id> [<state> <created (epoch msecs)> <name>]
+		for idx, projId := range projects {
+			if verbose {
+				projDesc := projDescList[idx]
+				printPorcelain(projDesc.Id, projDesc.State,
+					projDesc.CreateTime, projDesc.Header.Name)
+			} else {
+				printPorcelain(projId)
+			}
+		}
+	} else if len(projects) == 0 {
 		fmt.Printf("\nNo projects exist; create a new one with 'bopmatic project create'\n")
+	} else if verbose {
+		fmt.Printf("%-24v%-24v%-12v%v\n", "Project Id", "Name", "State",
+			"Created")
+		fmt.Printf("%-24v%-24v%-12v%v\n", "----------", "----", "-----",
+			"-------")
+
+		for _, projDesc := range projDescList {
+			fmt.Printf("%-24v%-24v%-12v%v\n", projDesc.Id,
+				projDesc.Header.Name, projDesc.State,
+				unixTime2UtcStr(projDesc.CreateTime))
+		}
 	} else {
 		fmt.Printf("Project Id\n")
 		fmt.Printf("-----------------------\n")
@@ -590,6 +1455,15 @@ func projListMain(args []string) {
 	}
 }
 
+type projListItemOutput struct {
+	Id               string   `json:"id"`
+	Name             string   `json:"name"`
+	State            string   `json:"state"`
+	Created          string   `json:"created"`
+	ActiveDeployIds  []string `json:"activeDeployIds"`
+	PendingDeployIds []string `json:"pendingDeployIds"`
+}
+
 func projMain(args []string) {
 	exitStatus := 0
 
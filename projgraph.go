@@ -0,0 +1,180 @@
+/* Copyright © 2024 Bopmatic, LLC. All Rights Reserved.
+ *
+ * See LICENSE file at the root of this package for license terms
+ */
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/bopmatic/sdk/golang/pb"
+)
+
+const (
+	GraphDot     = "dot"
+	GraphMermaid = "mermaid"
+)
+
+// projGraph is a project's service -> database/datastore dependency graph
+type projGraph struct {
+	projId      string
+	svcNames    []string
+	dbNames     []string
+	dstoreNames []string
+	// service name -> names of the databases & datastores it references
+	svcDbs     map[string][]string
+	svcDstores map[string][]string
+}
+
+// newProjGraph builds a dependency graph from describe results. Databases &
+// datastores referenced by a service but not themselves described (e.g.
+// because of --resource) are still included.
+func newProjGraph(projId string, svcDescList []*pb.DescribeServiceReply,
+	dbDescList []*pb.DescribeDatabaseReply,
+	dstoreDescList []*pb.DescribeDatastoreReply) *projGraph {
+
+	graph := &projGraph{
+		projId:     projId,
+		svcDbs:     make(map[string][]string),
+		svcDstores: make(map[string][]string),
+	}
+	dbSet := make(map[string]bool)
+	dstoreSet := make(map[string]bool)
+	for _, svcDesc := range svcDescList {
+		svcName := svcDesc.Desc.SvcHeader.ServiceName
+		graph.svcNames = append(graph.svcNames, svcName)
+		graph.svcDbs[svcName] = svcDesc.Desc.DatabaseNames
+		graph.svcDstores[svcName] = svcDesc.Desc.DatastoreNames
+		for _, dbName := range svcDesc.Desc.DatabaseNames {
+			dbSet[dbName] = true
+		}
+		for _, dstoreName := range svcDesc.Desc.DatastoreNames {
+			dstoreSet[dstoreName] = true
+		}
+	}
+	for _, dbDesc := range dbDescList {
+		dbSet[dbDesc.Desc.DatabaseHeader.DatabaseName] = true
+	}
+	for _, dstoreDesc := range dstoreDescList {
+		dstoreSet[dstoreDesc.Desc.DatastoreHeader.DatastoreName] = true
+	}
+	for dbName := range dbSet {
+		graph.dbNames = append(graph.dbNames, dbName)
+	}
+	for dstoreName := range dstoreSet {
+		graph.dstoreNames = append(graph.dstoreNames, dstoreName)
+	}
+	sort.Strings(graph.dbNames)
+	sort.Strings(graph.dstoreNames)
+
+	return graph
+}
+
+// writeDot renders graph in Graphviz DOT format, e.g. for
+// 'dot -Tsvg -o arch.svg'
+func (graph *projGraph) writeDot(w io.Writer) {
+	quote := func(s string) string {
+		return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+	}
+
+	fmt.Fprintf(w, "digraph %v {\n", quote(graph.projId))
+	fmt.Fprintf(w, "  rankdir=LR;\n")
+	for _, svcName := range graph.svcNames {
+		fmt.Fprintf(w, "  %v [label=%v, shape=box];\n",
+			quote("svc:"+svcName), quote(svcName))
+	}
+	for _, dbName := range graph.dbNames {
+		fmt.Fprintf(w, "  %v [label=%v, shape=cylinder];\n",
+			quote("db:"+dbName), quote(dbName))
+	}
+	for _, dstoreName := range graph.dstoreNames {
+		fmt.Fprintf(w, "  %v [label=%v, shape=folder];\n",
+			quote("dstore:"+dstoreName), quote(dstoreName))
+	}
+	for _, svcName := range graph.svcNames {
+		for _, dbName := range graph.svcDbs[svcName] {
+			fmt.Fprintf(w, "  %v -> %v;\n", quote("svc:"+svcName),
+				quote("db:"+dbName))
+		}
+		for _, dstoreName := range graph.svcDstores[svcName] {
+			fmt.Fprintf(w, "  %v -> %v;\n", quote("svc:"+svcName),
+				quote("dstore:"+dstoreName))
+		}
+	}
+	fmt.Fprintf(w, "}\n")
+}
+
+// mermaid node ids may only contain word characters
+var mermaidIdRe = regexp.MustCompile(`\W`)
+
+// writeMermaid renders graph as a Mermaid flowchart, which e.g. GitHub
+// renders inline within a ```mermaid markdown block
+func (graph *projGraph) writeMermaid(w io.Writer) {
+	nodeId := func(kind string, name string) string {
+		return kind + "_" + mermaidIdRe.ReplaceAllString(name, "_")
+	}
+	label := func(s string) string {
+		return `"` + strings.ReplaceAll(s, `"`, "#quot;") + `"`
+	}
+
+	fmt.Fprintf(w, "flowchart LR\n")
+	for _, svcName := range graph.svcNames {
+		fmt.Fprintf(w, "  %v[%v]\n", nodeId("svc", svcName), label(svcName))
+	}
+	for _, dbName := range graph.dbNames {
+		fmt.Fprintf(w, "  %v[(%v)]\n", nodeId("db", dbName), label(dbName))
+	}
+	for _, dstoreName := range graph.dstoreNames {
+		fmt.Fprintf(w, "  %v[/%v/]\n", nodeId("dstore", dstoreName),
+			label(dstoreName))
+	}
+	for _, svcName := range graph.svcNames {
+		for _, dbName := range graph.svcDbs[svcName] {
+			fmt.Fprintf(w, "  %v --> %v\n", nodeId("svc", svcName),
+				nodeId("db", dbName))
+		}
+		for _, dstoreName := range graph.svcDstores[svcName] {
+			fmt.Fprintf(w, "  %v --> %v\n", nodeId("svc", svcName),
+				nodeId("dstore", dstoreName))
+		}
+	}
+}
+
+// writeProjGraph renders graph in graphFormat to outfile, or to stdout when
+// outfile is empty
+func writeProjGraph(graph *projGraph, graphFormat string,
+	outfile string) error {
+
+	var sb strings.Builder
+	switch graphFormat {
+	case GraphDot:
+		graph.writeDot(&sb)
+	case GraphMermaid:
+		graph.writeMermaid(&sb)
+	default:
+		return fmt.Errorf("invalid --graph %v; must be one of %v or %v",
+			graphFormat, GraphDot, GraphMermaid)
+	}
+
+	if outfile == "" {
+		fmt.Print(sb.String())
+		return nil
+	}
+	err := os.MkdirAll(filepath.Dir(outfile), 0755)
+	if err != nil {
+		return fmt.Errorf("Could not create %v: %w", filepath.Dir(outfile), err)
+	}
+	err = os.WriteFile(outfile, []byte(sb.String()), 0644)
+	if err != nil {
+		return fmt.Errorf("Could not write %v: %w", outfile, err)
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %v graph to %v\n", graphFormat, outfile)
+
+	return nil
+}
@@ -0,0 +1,295 @@
+/* Copyright © 2024 Bopmatic, LLC. All Rights Reserved.
+ *
+ * See LICENSE file at the root of this package for license terms
+ */
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	bopsdk "github.com/bopmatic/sdk/golang"
+)
+
+// how long 'bopmatic run' waits for the rpc to respond
+const DefaultRunTimeout = 30 * time.Second
+
+type runOutput struct {
+	Url        string          `json:"url"`
+	Status     int             `json:"status"`
+	DurationMs int64           `json:"durationMs"`
+	Body       json.RawMessage `json:"body,omitempty"`
+	// set instead of Body when the response isn't json
+	RawBody string `json:"rawBody,omitempty"`
+}
+
+// runMain invokes one of a deployed service's rpc methods and prints the
+// response, e.g. as a smoke test right after deploying
+func runMain(args []string) {
+	sdkOpts, err := getAuthSdkOpts()
+	if err != nil {
+		exitWithError(ExitAuth, "%v\n", err)
+	}
+
+	type runOpts struct {
+		common   commonOpts
+		bodyFile string
+		timeout  time.Duration
+		headers  stringListFlag
+		auth     bool
+	}
+
+	var opts runOpts
+
+	f := flag.NewFlagSet("bopmatic run", flag.ExitOnError)
+	setCommonFlags(f, &opts.common)
+	f.StringVar(&opts.bodyFile, "body-file", "",
+		"Read the json request body from this file ('-' for stdin)")
+	f.DurationVar(&opts.timeout, "timeout", DefaultRunTimeout,
+		"How long to wait for the rpc to respond")
+	f.Var(&opts.headers, "header",
+		"Add a 'Name: value' header to the request; may be repeated")
+	f.BoolVar(&opts.auth, "auth", false,
+		"Send your Bopmatic api key as the request's Authorization header")
+
+	err = f.Parse(args)
+	if err != nil {
+		exitWithError(ExitUsage, "%v\n", err)
+	}
+	if opts.common.serviceName == "" {
+		exitWithError(ExitUsage, "Please specify the service to invoke with --svcname\n")
+	}
+	if f.NArg() < 1 || f.NArg() > 2 {
+		exitWithError(ExitUsage, "Usage: bopmatic run --svcname <svcname> <method> [<json body>]\n")
+	}
+	if f.NArg() == 2 && opts.bodyFile != "" {
+		exitWithError(ExitUsage, "Specify the request body either as an argument or with --body-file, not both\n")
+	}
+	if opts.timeout <= 0 {
+		exitWithError(ExitUsage, "--timeout must be positive\n")
+	}
+	method := f.Arg(0)
+	body, err := readRunBody(f.Arg(1), opts.bodyFile)
+	if err != nil {
+		exitWithError(ExitUsage, "%v\n", err)
+	}
+	header, err := parseRunHeaders(opts.headers)
+	if err != nil {
+		exitWithError(ExitUsage, "%v\n", err)
+	}
+	if opts.auth {
+		if header.Get("Authorization") != "" {
+			exitWithError(ExitUsage, "--auth cannot be combined with an Authorization --header\n")
+		}
+		apiKey, err := getRequiredApiKey()
+		if err != nil {
+			exitWithError(ExitAuth, "%v\n", err)
+		}
+		header.Set("Authorization", fmt.Sprintf("ApiKey %v", apiKey))
+	}
+
+	projId := opts.common.projectId
+	if projId == "" {
+		projId, err = resolveProjectId(opts.common.projFile())
+		if err != nil {
+			exitWithError(ExitUsage, "%v\n", err)
+		}
+	}
+
+	rpcUrl, err := resolveRpcUrl(projId, opts.common.serviceName, method,
+		sdkOpts)
+	if err != nil {
+		exitWithError(ExitNotFound, "%v\n", err)
+	}
+
+	out, err := invokeRpc(rpcUrl, body, header, opts.timeout)
+	if err != nil {
+		exitWithError(ExitFailure, "Failed to invoke %v: %v\n", rpcUrl, err)
+	}
+
+	if outputFormat != OutputText {
+		err = printStructured(out)
+		if err != nil {
+			exitWithError(ExitFailure, "Failed to render response: %v\n", err)
+		}
+	} else {
+		fmt.Printf("POST %v\n", out.Url)
+		fmt.Printf("HTTP %v %v (%v)\n", out.Status, http.StatusText(out.Status),
+			time.Duration(out.DurationMs)*time.Millisecond)
+		if out.Body != nil {
+			var indented bytes.Buffer
+			_ = json.Indent(&indented, out.Body, "", "  ")
+			fmt.Printf("%s\n", indented.Bytes())
+		} else if out.RawBody != "" {
+			fmt.Printf("%v\n", out.RawBody)
+		}
+	}
+	if out.Status >= http.StatusBadRequest {
+		exit(ExitFailure)
+	}
+}
+
+// readRunBody returns the request body from bodyArg or bodyFile, defaulting
+// to an empty json object, after checking that it is valid json
+func readRunBody(bodyArg string, bodyFile string) ([]byte, error) {
+	body := []byte(bodyArg)
+	if bodyFile == "-" {
+		var err error
+		body, err = io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("Could not read request body from stdin: %w",
+				err)
+		}
+	} else if bodyFile != "" {
+		var err error
+		body, err = os.ReadFile(bodyFile)
+		if err != nil {
+			return nil, fmt.Errorf("Could not read request body: %w", err)
+		}
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return []byte("{}"), nil
+	}
+	if !json.Valid(body) {
+		return nil, fmt.Errorf("The request body is not valid json")
+	}
+
+	return body, nil
+}
+
+// parseRunHeaders parses each --header 'Name: value'
+func parseRunHeaders(headers []string) (http.Header, error) {
+	header := make(http.Header)
+	for _, h := range headers {
+		name, val, found := strings.Cut(h, ":")
+		name = strings.TrimSpace(name)
+		if !found || name == "" {
+			return nil, fmt.Errorf("Invalid --header %q; expected 'Name: value'",
+				h)
+		}
+		header.Add(name, strings.TrimSpace(val))
+	}
+
+	return header, nil
+}
+
+// resolveRpcUrl finds svcName's rpc endpoint for method. Endpoints which
+// aren't absolute urls are relative to the project's site endpoint; absolute
+// ones must be within the site's domain so that a request, and any
+// credentials it carries, only goes to the project itself.
+func resolveRpcUrl(projId string, svcName string, method string,
+	sdkOpts []bopsdk.DeployOption) (string, error) {
+
+	// @todo specify environment id
+	svcDesc, err := bopsdk.DescribeService(projId, "", svcName, sdkOpts...)
+	if err != nil {
+		return "", fmt.Errorf("Could not describe service %v: %w", svcName, err)
+	}
+
+	endpoint := ""
+	for _, rpcEnd := range svcDesc.Desc.RpcEndpoints {
+		if rpcEnd == method || strings.HasSuffix(rpcEnd, "/"+method) {
+			endpoint = rpcEnd
+			break
+		}
+	}
+	if endpoint == "" {
+		return "", fmt.Errorf("Service %v has no rpc method %v; its endpoints are: %v",
+			svcName, method, strings.Join(svcDesc.Desc.RpcEndpoints, ", "))
+	}
+
+	descSiteReply, err := bopsdk.DescribeSite(projId, "", sdkOpts...)
+	if err != nil {
+		return "", fmt.Errorf("Could not describe site: %w", err)
+	}
+	siteUrl := descSiteReply.SiteEndpoint
+	if siteUrl == "" {
+		return "", fmt.Errorf("Project %v has no site endpoint", projId)
+	}
+	if !strings.Contains(siteUrl, "://") {
+		siteUrl = "https://" + siteUrl
+	}
+	if strings.Contains(endpoint, "://") {
+		err = checkRpcUrlInSite(endpoint, siteUrl)
+		if err != nil {
+			return "", err
+		}
+		return endpoint, nil
+	}
+
+	return strings.TrimRight(siteUrl, "/") + "/" +
+		strings.TrimLeft(endpoint, "/"), nil
+}
+
+// checkRpcUrlInSite returns an error unless rpcUrl's host is siteUrl's host
+// or one of its subdomains
+func checkRpcUrlInSite(rpcUrl string, siteUrl string) error {
+	rpcParsed, err := url.Parse(rpcUrl)
+	if err != nil {
+		return fmt.Errorf("Invalid rpc endpoint %v: %w", rpcUrl, err)
+	}
+	siteParsed, err := url.Parse(siteUrl)
+	if err != nil {
+		return fmt.Errorf("Invalid site endpoint %v: %w", siteUrl, err)
+	}
+	rpcHost := strings.ToLower(rpcParsed.Hostname())
+	siteHost := strings.ToLower(siteParsed.Hostname())
+	if rpcHost == "" || siteHost == "" ||
+		(rpcHost != siteHost && !strings.HasSuffix(rpcHost, "."+siteHost)) {
+		return fmt.Errorf("Refusing to invoke %v which is outside of the project's site %v",
+			rpcUrl, siteHost)
+	}
+
+	return nil
+}
+
+// invokeRpc POSTs body to rpcUrl with header added to the request
+func invokeRpc(rpcUrl string, body []byte, header http.Header,
+	timeout time.Duration) (*runOutput, error) {
+
+	req, err := http.NewRequest(http.MethodPost, rpcUrl, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for name, vals := range header {
+		req.Header[name] = vals
+	}
+	if req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	httpClient := &http.Client{
+		Timeout: timeout,
+	}
+	startTime := time.Now()
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &runOutput{
+		Url:        rpcUrl,
+		Status:     resp.StatusCode,
+		DurationMs: time.Since(startTime).Milliseconds(),
+	}
+	if json.Valid(respBody) {
+		out.Body = respBody
+	} else {
+		out.RawBody = string(respBody)
+	}
+
+	return out, nil
+}
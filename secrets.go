@@ -0,0 +1,101 @@
+/* Copyright © 2024 Bopmatic, LLC. All Rights Reserved.
+ *
+ * See LICENSE file at the root of this package for license terms
+ */
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// how much of a secret is left visible by maskSecret
+const maskedSecretPrefixLen = 4
+
+// set via --mask-secrets; on by default so that e.g. --log-level debug
+// output pasted into an issue doesn't leak credentials
+var maskSecretsEnabled = true
+
+type maskSecretsFlag struct{}
+
+func (m *maskSecretsFlag) String() string {
+	return strconv.FormatBool(maskSecretsEnabled)
+}
+
+func (m *maskSecretsFlag) Set(val string) error {
+	v, err := strconv.ParseBool(val)
+	if err != nil {
+		return err
+	}
+	maskSecretsEnabled = v
+
+	return nil
+}
+
+func (m *maskSecretsFlag) IsBoolFlag() bool {
+	return true
+}
+
+// secrets read by this process (e.g. the api key) which are masked
+// wherever they appear in log and error messages
+var (
+	knownSecretsLock sync.Mutex
+	knownSecrets     []string
+)
+
+// registerSecret arranges for secret to be masked by maskSecrets
+func registerSecret(secret string) {
+	secret = strings.TrimSpace(secret)
+	if len(secret) <= maskedSecretPrefixLen {
+		return
+	}
+
+	knownSecretsLock.Lock()
+	defer knownSecretsLock.Unlock()
+	for _, known := range knownSecrets {
+		if known == secret {
+			return
+		}
+	}
+	knownSecrets = append(knownSecrets, secret)
+}
+
+// the credential of an Authorization header, which may be echoed back within
+// errors even when the secret wasn't registered; anywhere else only
+// registered secrets are masked
+var authHeaderSecretRe = regexp.MustCompile(`(?i)(Authorization:\s*(?:Bearer|ApiKey)\s+)(\S+)`)
+
+// maskSecret replaces all but a short prefix of secret with asterisks; the
+// number of asterisks is fixed so that the secret's length isn't revealed
+func maskSecret(secret string) string {
+	if len(secret) <= maskedSecretPrefixLen {
+		return "********"
+	}
+
+	return secret[:maskedSecretPrefixLen] + "********"
+}
+
+// maskSecrets masks each registered secret and each Authorization header
+// credential within msg unless masking was disabled with --mask-secrets=false
+func maskSecrets(msg string) string {
+	if !maskSecretsEnabled {
+		return msg
+	}
+
+	knownSecretsLock.Lock()
+	for _, secret := range knownSecrets {
+		msg = strings.ReplaceAll(msg, secret, maskSecret(secret))
+	}
+	knownSecretsLock.Unlock()
+
+	return authHeaderSecretRe.ReplaceAllStringFunc(msg, func(match string) string {
+		parts := authHeaderSecretRe.FindStringSubmatch(match)
+		if strings.HasSuffix(parts[2], "********") {
+			// already masked as a registered secret
+			return match
+		}
+		return parts[1] + maskSecret(parts[2])
+	})
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestMaskSecrets(t *testing.T) {
+	savedSecrets := knownSecrets
+	defer func() {
+		knownSecrets = savedSecrets
+		maskSecretsEnabled = true
+	}()
+	knownSecrets = nil
+	registerSecret("regkey0123456789\n")
+
+	tests := []struct {
+		msg      string
+		enabled  bool
+		expected string
+	}{
+		{"request failed for key regkey0123456789: 401", true,
+			"request failed for key regk********: 401"},
+		{"Authorization: ApiKey unreg9876543210", true,
+			"Authorization: ApiKey unre********"},
+		{"authorization:Bearer unreg9876543210 rejected", true,
+			"authorization:Bearer unre******** rejected"},
+		// the registered secret is masked first and not masked again
+		{"Authorization: ApiKey regkey0123456789", true,
+			"Authorization: ApiKey regk********"},
+		{"Authorization: ApiKey regk********", true,
+			"Authorization: ApiKey regk********"},
+		// unregistered values outside an Authorization header are left be
+		{"the Bearer of bad news", true, "the Bearer of bad news"},
+		{"ApiKey unreg9876543210", true, "ApiKey unreg9876543210"},
+		// --mask-secrets=false
+		{"key regkey0123456789; Authorization: ApiKey unreg9876543210",
+			false,
+			"key regkey0123456789; Authorization: ApiKey unreg9876543210"},
+	}
+
+	for _, test := range tests {
+		maskSecretsEnabled = test.enabled
+		masked := maskSecrets(test.msg)
+		if masked != test.expected {
+			t.Errorf("maskSecrets(%q) with masking %v: expected %q, got %q",
+				test.msg, test.enabled, test.expected, masked)
+		}
+	}
+}
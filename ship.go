@@ -0,0 +1,157 @@
+/* Copyright © 2024 Bopmatic, LLC. All Rights Reserved.
+ *
+ * See LICENSE file at the root of this package for license terms
+ */
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	bopsdk "github.com/bopmatic/sdk/golang"
+	"github.com/bopmatic/sdk/golang/pb"
+)
+
+const DefaultShipTimeout = 30 * time.Minute
+
+func isTerminalPkgState(pkgDesc *pb.PackageDescription) bool {
+	switch pkgDesc.State {
+	case pb.PackageState_UPLOADING, pb.PackageState_UPLOADED,
+		pb.PackageState_PKG_VALIDATING, pb.PackageState_PKG_BUILDING:
+		return false
+	}
+
+	return true
+}
+
+func isTerminalDeployState(deployDesc *pb.DeploymentDescription) bool {
+	switch deployDesc.State {
+	case pb.DeploymentState_SUCCESS, pb.DeploymentState_FAILED,
+		pb.DeploymentState_UNKNOWN_DEPLOY_STATE:
+		return true
+	}
+
+	return false
+}
+
+// pkgShipMain builds the project, deploys the resulting package, and waits
+// for the deployment to complete
+func pkgShipMain(args []string) {
+	sdkOpts, err := getAuthSdkOpts()
+	if err != nil {
+		exitWithError(ExitAuth, "%v\n", err)
+	}
+
+	type shipOpts struct {
+		common      commonOpts
+		yes         bool
+		timeout     time.Duration
+		verbose     bool
+		confirmProd bool
+	}
+
+	var opts shipOpts
+
+	f := flag.NewFlagSet("bopmatic package ship", flag.ExitOnError)
+	setCommonFlags(f, &opts.common)
+	setLockFlags(f)
+	setDeployHookFlags(f)
+	f.BoolVar(&opts.yes, "yes", false,
+		"Deploy without prompting for confirmation")
+	f.DurationVar(&opts.timeout, "timeout", DefaultShipTimeout,
+		"Maximum time to wait for the deployment to complete")
+	f.BoolVar(&opts.verbose, "verbose-container", false,
+		"Show the build container's full output rather than only on failure")
+	f.BoolVar(&opts.confirmProd, "confirm-prod", false,
+		"Acknowledge deploying to a protected environment without prompting")
+
+	err = f.Parse(args)
+	if err != nil {
+		exitWithError(ExitUsage, "%v\n", err)
+	}
+	err = confirmProtectedEnvs(nil, opts.confirmProd)
+	if err != nil {
+		exitWithError(ExitUsage, "%v\n", err)
+	}
+
+	fmt.Printf("==> [1/4] Building\n")
+	pkg, err := buildAndPackage(opts.common.projFile(), nil,
+		opts.verbose)
+	if err != nil {
+		exitWithError(ExitFailure, "%v\n", err)
+	}
+
+	if !opts.yes &&
+		!confirm(fmt.Sprintf("Deploy pkgId:%v to production?", pkg.Id)) {
+
+		fmt.Printf("Not deploying; you can deploy later with:\n\t'bopmatic package deploy'\n")
+		return
+	}
+
+	fmt.Printf("\n==> [2/4] Deploying pkgId:%v\n", pkg.Id)
+	defer lockProjectOrExit(pkg.Proj.Desc.Id)()
+	validateNoConflicts(sdkOpts, pkg)
+	// @todo specify envId
+	deployId, err := pkg.Deploy("", sdkOpts...)
+	if err != nil {
+		exitWithError(ExitServer, "%v\n", err)
+	}
+	fmt.Printf("Started deployId:%v\n", deployId)
+
+	// --timeout bounds both waits combined
+	deadline := time.Now().Add(opts.timeout)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	ctx, cancel := context.WithDeadline(ctx, deadline)
+	defer cancel()
+	pollOpts := pollOptions{
+		interval:    DefaultPollInterval,
+		maxInterval: DefaultPollMaxInterval,
+		out:         os.Stdout,
+	}
+	exitOnWaitErr := func(err error) {
+		if errors.Is(err, context.DeadlineExceeded) ||
+			errors.Is(err, errPollTimeout) {
+			exitWithError(ExitFailure, "Timed out after %v; check progress with:\n\t'bopmatic deploy describe --deployid %v'\n",
+				opts.timeout, deployId)
+		} else if errors.Is(err, context.Canceled) {
+			exitWithError(ExitFailure, "Interrupted; deployment %v continues in the background. Check progress with:\n\t'bopmatic deploy describe --deployid %v'\n",
+				deployId, deployId)
+		}
+		exitWithError(ExitServer, "%v\n", err)
+	}
+
+	fmt.Printf("\n==> [3/4] Waiting for pkgId:%v to be built\n", pkg.Id)
+	pkgDesc, err := pollUntil(ctx, pollOpts,
+		func(ctx context.Context) (*pb.PackageDescription, error) {
+			return bopsdk.Describe(pkg.Id, sdkOpts...)
+		}, isTerminalPkgState,
+		func(pkgDesc *pb.PackageDescription) string {
+			return fmt.Sprintf("package %v", pkgDesc.State)
+		})
+	if err != nil {
+		exitOnWaitErr(err)
+	}
+	if pkgDesc.State != pb.PackageState_BUILT {
+		exitWithError(ExitFailure, "Package %v did not build (state %v); see:\n\t'bopmatic package describe --pkgid %v'\n",
+			pkg.Id, pkgDesc.State, pkg.Id)
+	}
+
+	fmt.Printf("\n==> [4/4] Waiting for deployId:%v to complete\n", deployId)
+	remaining := time.Until(deadline)
+	if remaining <= 0 {
+		exitOnWaitErr(context.DeadlineExceeded)
+	}
+	watchDeployment(deployId, "deployment", sdkOpts, remaining,
+		func(deployDesc *pb.DeploymentDescription) string {
+			return fmt.Sprintf("deployment %v", deployDesc.State)
+		})
+
+	fmt.Printf("\nShipped pkgId:%v to production via deployId:%v\n", pkg.Id,
+		deployId)
+}
@@ -0,0 +1,105 @@
+/* Copyright © 2024 Bopmatic, LLC. All Rights Reserved.
+ *
+ * See LICENSE file at the root of this package for license terms
+ */
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	bopsdk "github.com/bopmatic/sdk/golang"
+)
+
+// how long 'bopmatic package deploy --verify' waits for the site to serve
+const DefaultVerifyTimeout = 5 * time.Minute
+
+// siteProbe is the outcome of a single request made of a site endpoint
+type siteProbe struct {
+	status int
+	err    error
+}
+
+// isLive considers any response other than a server error to mean the site
+// is being served; e.g. a 404 from a site without an index page is still
+// live
+func (probe siteProbe) isLive() bool {
+	return probe.err == nil && probe.status < http.StatusInternalServerError
+}
+
+func (probe siteProbe) String() string {
+	if probe.err != nil {
+		return fmt.Sprintf("not yet reachable (%v)", probe.err)
+	} else if !probe.isLive() {
+		return fmt.Sprintf("not yet serving (HTTP %v)", probe.status)
+	}
+
+	return fmt.Sprintf("live (HTTP %v)", probe.status)
+}
+
+// verifySiteEndpoint resolves projId's site endpoint and polls it with
+// HTTP GETs until it responds or timeout expires
+func verifySiteEndpoint(projId string, envId string, timeout time.Duration,
+	sdkOpts []bopsdk.DeployOption) error {
+
+	descSiteReply, err := bopsdk.DescribeSite(projId, envId, sdkOpts...)
+	if err != nil {
+		return fmt.Errorf("Could not describe site: %w", err)
+	}
+	siteUrl := descSiteReply.SiteEndpoint
+	if siteUrl == "" {
+		return fmt.Errorf("Project %v has no site endpoint to verify", projId)
+	}
+	if !strings.Contains(siteUrl, "://") {
+		siteUrl = "https://" + siteUrl
+	}
+
+	fmt.Printf("Verifying %v is serving...\n", siteUrl)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	httpClient := &http.Client{
+		Timeout: time.Second * 30,
+	}
+	probe, err := pollUntil(ctx, pollOptions{
+		interval:    DefaultPollInterval,
+		maxInterval: DefaultPollMaxInterval,
+		timeout:     timeout,
+		out:         os.Stdout,
+	}, func(ctx context.Context) (siteProbe, error) {
+		return probeSite(ctx, httpClient, siteUrl), nil
+	}, siteProbe.isLive, siteProbe.String)
+	if errors.Is(err, errPollTimeout) {
+		return fmt.Errorf("%v is still %v after %v", siteUrl, probe, timeout)
+	} else if errors.Is(err, context.Canceled) {
+		return fmt.Errorf("Interrupted while verifying %v", siteUrl)
+	} else if err != nil {
+		return err
+	}
+	fmt.Printf("%v is live\n", siteUrl)
+
+	return nil
+}
+
+func probeSite(ctx context.Context, httpClient *http.Client,
+	siteUrl string) siteProbe {
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, siteUrl, nil)
+	if err != nil {
+		return siteProbe{err: err}
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return siteProbe{err: err}
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	return siteProbe{status: resp.StatusCode}
+}
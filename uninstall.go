@@ -0,0 +1,124 @@
+/* Copyright © 2024 Bopmatic, LLC. All Rights Reserved.
+ *
+ * See LICENSE file at the root of this package for license terms
+ */
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/docker/docker/api/types/image"
+	dockerClient "github.com/docker/docker/client"
+
+	"github.com/bopmatic/sdk/golang/util"
+)
+
+// selfUninstallMain removes the CLI's config directory, optionally its
+// build images, and either uninstalls the brew formula or tells the user
+// where the binary is so that they can delete it
+func selfUninstallMain(args []string) {
+	var removeImage, yes bool
+
+	f := flag.NewFlagSet("bopmatic self-uninstall", flag.ExitOnError)
+	setGlobalFlags(f)
+	f.BoolVar(&removeImage, "remove-image", false,
+		"Also remove every local Bopmatic Build Image")
+	f.BoolVar(&yes, "yes", false, "Uninstall without prompting for confirmation")
+	err := f.Parse(args)
+	if err != nil {
+		exitWithError(ExitUsage, "%v\n", err)
+	}
+
+	configPath, err := getConfigPath()
+	if err != nil {
+		exitWithError(ExitFailure, "%v\n", err)
+	}
+	binaryPath, err := getCLIBinaryPath()
+	if err != nil {
+		exitWithError(ExitFailure, "%v\n", err)
+	}
+
+	fmt.Printf("This will remove:\n")
+	fmt.Printf("\t%v (your api key and all CLI settings)\n", configPath)
+	if removeImage {
+		fmt.Printf("\tevery local %v docker image\n", util.BopmaticImageRepo)
+	}
+	if isBrewVersion() {
+		fmt.Printf("\tthe bopmatic brew formula (%v)\n", binaryPath)
+	}
+	if !yes && !confirm("Uninstall the Bopmatic CLI?") {
+		fmt.Printf("Not uninstalling\n")
+		return
+	}
+
+	err = os.RemoveAll(configPath)
+	if err != nil {
+		exitWithError(ExitFailure, "Could not remove %v: %v\n", configPath, err)
+	}
+	fmt.Printf("Removed %v\n", configPath)
+
+	if removeImage {
+		err = removeBopmaticImages()
+		if err != nil {
+			exitWithError(ExitFailure, "%v\n", err)
+		}
+	}
+
+	if isBrewVersion() {
+		err = util.RunHostCommand(context.Background(),
+			[]string{"brew", "uninstall", "bopmatic/macos/cli"}, os.Stdout,
+			os.Stderr)
+		if err != nil {
+			exitWithError(ExitFailure, "Failed to uninstall bopmatic via brew: %v\n",
+				err)
+		}
+		fmt.Printf("The Bopmatic CLI has been uninstalled\n")
+		return
+	}
+
+	fmt.Printf("To finish uninstalling, delete the bopmatic binary:\n\trm -f %v %v%v\n",
+		binaryPath, binaryPath, PrevBinarySuffix)
+}
+
+// removeBopmaticImages removes every tag of the Bopmatic Build Image,
+// including pinned and --platform variants
+func removeBopmaticImages() error {
+	cli, err := dockerClient.NewClientWithOpts(dockerClient.FromEnv,
+		dockerClient.WithAPIVersionNegotiation())
+	if err != nil {
+		return fmt.Errorf(util.DockerInstallErrMsg, err)
+	}
+	defer cli.Close()
+
+	ctx := context.Background()
+	images, err := cli.ImageList(ctx, image.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("Failed to list docker images: %w", err)
+	}
+	removed := 0
+	for _, img := range images {
+		for _, repoTag := range img.RepoTags {
+			if !strings.HasPrefix(repoTag, util.BopmaticImageRepo+":") {
+				continue
+			}
+			_, err = cli.ImageRemove(ctx, repoTag, image.RemoveOptions{
+				Force:         true,
+				PruneChildren: true,
+			})
+			if err != nil {
+				return fmt.Errorf("Failed to remove %v: %w", repoTag, err)
+			}
+			fmt.Printf("Removed %v\n", repoTag)
+			removed++
+		}
+	}
+	if removed == 0 {
+		fmt.Printf("No %v images found\n", util.BopmaticImageRepo)
+	}
+
+	return nil
+}
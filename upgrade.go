@@ -8,11 +8,18 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"io/fs"
 	"io/ioutil"
 	"net/http"
 	"os"
+	"os/exec"
+	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -20,35 +27,107 @@ import (
 
 	"github.com/docker/docker/api/types/image"
 	dockerClient "github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
 
 	"github.com/bopmatic/sdk/golang/util"
 )
 
+const (
+	UpgradeChannelStable = "stable"
+	UpgradeChannelBeta   = "beta"
+)
+
+// getUpgradeChannel returns the release channel persisted in the config
+// file
+func getUpgradeChannel() string {
+	cfg, err := loadConfig()
+	if err != nil || cfg.UpgradeChannel == "" {
+		return UpgradeChannelStable
+	}
+
+	return cfg.UpgradeChannel
+}
+
+// setUpgradeChannel validates channel and persists it so that subsequent
+// upgrades and upgrade checks track it
+func setUpgradeChannel(channel string) error {
+	if channel != UpgradeChannelStable && channel != UpgradeChannelBeta {
+		return fmt.Errorf("Invalid --channel %v; must be %v or %v", channel,
+			UpgradeChannelStable, UpgradeChannelBeta)
+	}
+	if channel == getUpgradeChannel() {
+		return nil
+	}
+
+	err := updateConfig(func(cfg *cliConfig) error {
+		if channel == UpgradeChannelStable {
+			cfg.UpgradeChannel = ""
+		} else {
+			cfg.UpgradeChannel = channel
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Bopmatic CLI upgrades now track the %v channel\n", channel)
+
+	return nil
+}
+
 func getLatestVersion() (string, error) {
 	const LatestReleaseUrl = "https://api.github.com/repos/bopmatic/cli/releases/latest"
+	const ReleasesUrl = "https://api.github.com/repos/bopmatic/cli/releases"
 
 	client := http.Client{
 		Timeout: time.Second * 30,
 	}
 
-	resp, err := client.Get(LatestReleaseUrl)
+	releaseUrl := LatestReleaseUrl
+	if getUpgradeChannel() == UpgradeChannelBeta {
+		releaseUrl = ReleasesUrl
+	}
+	resp, err := client.Get(releaseUrl)
 	if err != nil {
 		return "", err
 	}
+	defer resp.Body.Close()
 
 	releaseJsonDoc, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		return "", err
 	}
-	var releaseDoc map[string]any
-	err = json.Unmarshal(releaseJsonDoc, &releaseDoc)
-	if err != nil {
-		return "", err
-	}
 
-	latestRelease, ok := releaseDoc["tag_name"].(string)
-	if !ok {
-		return "", fmt.Errorf("Could not parse %v", LatestReleaseUrl)
+	type releaseDoc struct {
+		TagName    string `json:"tag_name"`
+		Draft      bool   `json:"draft"`
+		Prerelease bool   `json:"prerelease"`
+	}
+	var latestRelease string
+	if releaseUrl == ReleasesUrl {
+		var releaseDocs []releaseDoc
+		err = json.Unmarshal(releaseJsonDoc, &releaseDocs)
+		if err != nil {
+			return "", err
+		}
+		// releases are listed newest first; the newest prerelease is the
+		// beta unless a stable release has since superseded it
+		for _, release := range releaseDocs {
+			if !release.Draft {
+				latestRelease = release.TagName
+				break
+			}
+		}
+	} else {
+		var release releaseDoc
+		err = json.Unmarshal(releaseJsonDoc, &release)
+		if err != nil {
+			return "", err
+		}
+		latestRelease = release.TagName
+	}
+	if latestRelease == "" {
+		return "", fmt.Errorf("Could not parse %v", releaseUrl)
 	}
 
 	if isBrewVersion() {
@@ -57,20 +136,262 @@ func getLatestVersion() (string, error) {
 	return latestRelease, nil
 }
 
+var upgradeSubCommandTab = map[string]func(args []string){
+	"container": upgradeBuildContainer,
+	"cli":       upgradeCLI,
+}
+
+func setUpgradeChannelFlag(f *flag.FlagSet, channel *string) {
+	f.StringVar(channel, "channel", "",
+		"Release channel to upgrade the CLI from; stable or beta. Persisted for future upgrades")
+}
+
 func upgradeMain(args []string) {
-	upgradeBuildContainer(args)
-	upgradeCLI(args)
+	if len(args) > 0 {
+		upgradeSubCommand, ok := upgradeSubCommandTab[args[0]]
+		if ok {
+			upgradeSubCommand(args[1:])
+			return
+		}
+	}
+
+	var channel string
+	var rollback bool
+	f := flag.NewFlagSet("bopmatic upgrade", flag.ExitOnError)
+	setGlobalFlags(f)
+	setUpgradeChannelFlag(f, &channel)
+	f.BoolVar(&rollback, "rollback", false,
+		"Restore the CLI version replaced by the most recent upgrade")
+	err := f.Parse(args)
+	if err != nil {
+		exitWithError(ExitUsage, "%v\n", err)
+	}
+	if rollback {
+		rollbackCLI()
+		return
+	}
+	if channel != "" {
+		err = setUpgradeChannel(channel)
+		if err != nil {
+			exitWithError(ExitUsage, "%v\n", err)
+		}
+	}
+
+	upgradeBuildContainer(f.Args())
+	upgradeCLI(f.Args())
+}
+
+// getBuildImageTag returns the Bopmatic Build Image tag pinned in the config
+// file, or util.BopmaticImageTag when unpinned
+func getBuildImageTag() string {
+	cfg, err := loadConfig()
+	if err != nil || cfg.BuildImageTag == "" {
+		return util.BopmaticImageTag
+	}
+
+	return cfg.BuildImageTag
+}
+
+func hasBuildImage() (bool, error) {
+	return util.HasImage(util.BopmaticImageRepo, getBuildImageTag())
+}
+
+// set by 'bopmatic package build --platform' to the platform variant of the
+// build image; empty otherwise
+var buildImageOverride string
+
+// getBuildImageName returns the Bopmatic Build Image which build commands
+// are run within; see runBuildContainerCommand
+func getBuildImageName() string {
+	if buildImageOverride != "" {
+		return buildImageOverride
+	}
+
+	return util.BopmaticImageRepo + ":" + getBuildImageTag()
+}
+
+// ensureDefaultBuildImage pulls util.BopmaticBuildImageName if it isn't
+// already local. The sdk always packages within it so it is needed even when
+// builds are pinned to another tag.
+func ensureDefaultBuildImage() {
+	haveDefault, err := util.HasBopmaticBuildImage()
+	if err != nil {
+		exitWithError(ExitFailure, "%v\n", err)
+	}
+	if !haveDefault {
+		fmt.Printf("Packaging uses %v; pulling it as well...\n",
+			util.BopmaticBuildImageName)
+		pullBopmaticImage(util.BopmaticImageTag)
+	}
+}
+
+// platforms which --platform accepts for the Bopmatic Build Image
+var buildPlatforms = []string{"linux/amd64", "linux/arm64"}
+
+func validateBuildPlatform(platform string) error {
+	for _, p := range buildPlatforms {
+		if platform == p {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("Unsupported --platform %v; must be one of %v", platform,
+		buildPlatforms)
+}
+
+// applyBuildPlatform runs this invocation's build commands within the
+// platform variant of the build image, tagged <tag>-<arch> (e.g.
+// latest-arm64). The variant is pulled by digest so that the build image's
+// own tag, which the sdk packages within, keeps referring to the default
+// platform.
+func applyBuildPlatform(platform string) error {
+	tag := getBuildImageTag()
+	srcImage := util.BopmaticImageRepo + ":" + tag
+
+	cli, err := dockerClient.NewClientWithOpts(dockerClient.FromEnv,
+		dockerClient.WithAPIVersionNegotiation())
+	if err != nil {
+		return fmt.Errorf(util.DockerInstallErrMsg, err)
+	}
+	defer cli.Close()
+
+	ctx := context.Background()
+	srcInfo, _, err := cli.ImageInspectWithRaw(ctx, srcImage)
+	if err != nil {
+		return fmt.Errorf("Failed to inspect %v: %w", srcImage, err)
+	}
+	if srcInfo.Os+"/"+srcInfo.Architecture == platform {
+		return nil
+	}
+
+	platImage := srcImage + "-" + path.Base(platform)
+	dist, err := cli.DistributionInspect(ctx, srcImage, "")
+	if err != nil {
+		return fmt.Errorf("Failed to look up %v: %w", srcImage, err)
+	}
+	digestRef := util.BopmaticImageRepo + "@" + dist.Descriptor.Digest.String()
+
+	logInfo("Pulling %v for %v...", srcImage, platform)
+	reader, err := cli.ImagePull(ctx, digestRef,
+		image.PullOptions{Platform: platform})
+	if err != nil {
+		return fmt.Errorf("No %v Bopmatic Build Image is available: %w",
+			platform, err)
+	}
+	_, err = io.Copy(io.Discard, reader)
+	reader.Close()
+	if err != nil {
+		return fmt.Errorf("Failed to pull %v for %v: %w", srcImage, platform,
+			err)
+	}
+	err = cli.ImageTag(ctx, digestRef, platImage)
+	if err != nil {
+		return fmt.Errorf("Failed to tag %v build image as %v: %w", platform,
+			platImage, err)
+	}
+	buildImageOverride = platImage
+
+	return nil
+}
+
+// getLocalImageCreated returns when the local repo:tag image was built
+func getLocalImageCreated(cli *dockerClient.Client,
+	tag string) (time.Time, error) {
+
+	imageName := util.BopmaticImageRepo + ":" + tag
+	imageInfo, _, err := cli.ImageInspectWithRaw(context.Background(),
+		imageName)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Failed to inspect %v: %w", imageName,
+			err)
+	}
+
+	return time.Parse(time.RFC3339Nano, imageInfo.Created)
+}
+
+// isPinnedBuildImageStale reports whether the pinned tag refers to an image
+// built before the local util.BopmaticImageTag image. Tags needn't be
+// versions so images are compared by when they were built; when either
+// isn't local the pin is assumed current.
+func isPinnedBuildImageStale(tag string) bool {
+	if tag == util.BopmaticImageTag {
+		return false
+	}
+
+	cli, err := dockerClient.NewClientWithOpts(dockerClient.FromEnv,
+		dockerClient.WithAPIVersionNegotiation())
+	if err != nil {
+		return false
+	}
+	defer cli.Close()
+
+	pinnedCreated, err := getLocalImageCreated(cli, tag)
+	if err != nil {
+		logDebug("could not date pinned build image: %v", err)
+		return false
+	}
+	defaultCreated, err := getLocalImageCreated(cli, util.BopmaticImageTag)
+	if err != nil {
+		logDebug("could not date default build image: %v", err)
+		return false
+	}
+
+	return pinnedCreated.Before(defaultCreated)
+}
+
+func pinBuildImage(tag string) {
+	pullBopmaticImage(tag)
+
+	err := updateConfig(func(cfg *cliConfig) error {
+		if tag == util.BopmaticImageTag {
+			cfg.BuildImageTag = ""
+		} else {
+			cfg.BuildImageTag = tag
+		}
+		return nil
+	})
+	if err != nil {
+		exitWithError(ExitFailure, "%v\n", err)
+	}
+	ensureDefaultBuildImage()
+
+	if tag == util.BopmaticImageTag {
+		fmt.Printf("Bopmatic Build Image now tracks %v\n",
+			util.BopmaticImageTag)
+		return
+	}
+
+	fmt.Printf("Bopmatic Build Image pinned to %v:%v\n",
+		util.BopmaticImageRepo, tag)
+	if isPinnedBuildImageStale(tag) {
+		logWarn("pinned build image tag %v is older than the default (%v)",
+			tag, util.BopmaticImageTag)
+	}
 }
 
 func upgradeCLI(args []string) {
+	var channel string
+	f := flag.NewFlagSet("bopmatic upgrade cli", flag.ExitOnError)
+	setGlobalFlags(f)
+	setUpgradeChannelFlag(f, &channel)
+	err := f.Parse(args)
+	if err != nil {
+		exitWithError(ExitUsage, "%v\n", err)
+	}
+	if channel != "" {
+		err = setUpgradeChannel(channel)
+		if err != nil {
+			exitWithError(ExitUsage, "%v\n", err)
+		}
+	}
+
 	if versionText == DevVersionText {
-		fmt.Fprintf(os.Stderr, "Skipping CLI upgrade on development version\n")
+		logWarn("Skipping CLI upgrade on development version")
 		return
 	}
 	latestVer, err := getLatestVersion()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Could not determine latest version: %v\n", err)
-		os.Exit(1)
+		exitWithError(ExitFailure, "Could not determine latest version: %v\n", err)
 	}
 	if latestVer == versionText {
 		fmt.Printf("Bopmatic CLI %v is already the latest version\n",
@@ -92,6 +413,10 @@ func upgradeCLI(args []string) {
 		latestVer)
 
 	if isBrewVersion() {
+		if getUpgradeChannel() == UpgradeChannelBeta {
+			logWarn("brew only distributes stable releases; the %v channel requires a GitHub install",
+				UpgradeChannelBeta)
+		}
 		upgradeCLIViaBrew()
 	} else {
 		upgradeCLIViaGithub(latestVer)
@@ -99,18 +424,30 @@ func upgradeCLI(args []string) {
 }
 
 func upgradeBuildContainer(args []string) {
-	haveBuildImg, err := util.HasBopmaticBuildImage()
+	var pinTag string
+	f := flag.NewFlagSet("bopmatic upgrade container", flag.ExitOnError)
+	setGlobalFlags(f)
+	f.StringVar(&pinTag, "tag", "",
+		"Pull and pin the Bopmatic Build Image to the specified tag")
+	err := f.Parse(args)
+	if err != nil {
+		exitWithError(ExitUsage, "%v\n", err)
+	}
+	if pinTag != "" {
+		pinBuildImage(pinTag)
+		return
+	}
+
+	tag := getBuildImageTag()
+	haveBuildImg, err := hasBuildImage()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
-		os.Exit(1)
+		exitWithError(ExitFailure, "%v\n", err)
 	}
 	if haveBuildImg {
 		needUpgrade, err :=
-			util.DoesLocalImageNeedUpdate(util.BopmaticImageRepo,
-				util.BopmaticImageTag)
+			util.DoesLocalImageNeedUpdate(util.BopmaticImageRepo, tag)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "%v\n", err)
-			os.Exit(1)
+			exitWithError(ExitFailure, "%v\n", err)
 		}
 		if needUpgrade == false {
 			fmt.Printf("Bopmatic Build container is up to date\n")
@@ -127,7 +464,8 @@ func upgradeBuildContainer(args []string) {
 	shouldDownload = strings.TrimSpace(shouldDownload)
 
 	if strings.ToUpper(shouldDownload)[0] == 'Y' {
-		pullBopmaticImage()
+		pullBopmaticImage(tag)
+		ensureDefaultBuildImage()
 
 		if !haveBuildImg {
 			fmt.Printf("To create a bopmatic project, next run:\n\t'bopmatic new'\n")
@@ -140,14 +478,12 @@ func upgradeCLIViaBrew() {
 	err := util.RunHostCommand(ctx, []string{"brew", "update"}, os.Stdout,
 		os.Stderr)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to update brew formulae: %v\n", err)
-		os.Exit(1)
+		exitWithError(ExitFailure, "Failed to update brew formulae: %v\n", err)
 	}
 	err = util.RunHostCommand(ctx, []string{"brew", "install",
 		"bopmatic/macos/cli"}, os.Stdout, os.Stderr)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to upgrade bopmatic: %v\n", err)
-		os.Exit(1)
+		exitWithError(ExitFailure, "Failed to upgrade bopmatic: %v\n", err)
 	}
 }
 
@@ -160,87 +496,198 @@ func upgradeCLIViaGithub(latestVer string) {
 
 	resp, err := client.Get(fmt.Sprintf(LatestDownloadFmt, latestVer))
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to download version %v: %v\n",
+		exitWithError(ExitFailure, "Failed to download version %v: %v\n",
 			versionText, err)
-		os.Exit(1)
 	}
 
 	tmpFile, err := os.CreateTemp("", "bopmatic-*")
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to create temp file: %v", err)
+		logError("Failed to create temp file: %v", err)
 	}
 	binaryContent, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to download version %v: %v\n",
+		exitWithError(ExitFailure, "Failed to download version %v: %v\n",
 			versionText, err)
-		os.Exit(1)
 	}
 
 	_, err = tmpFile.Write(binaryContent)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to download version %v: %v\n",
+		exitWithError(ExitFailure, "Failed to download version %v: %v\n",
 			versionText, err)
-		os.Exit(1)
 	}
 	err = tmpFile.Chmod(0755)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to download version %v: %v\n",
+		exitWithError(ExitFailure, "Failed to download version %v: %v\n",
 			versionText, err)
-		os.Exit(1)
 	}
 	err = tmpFile.Close()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to download version %v: %v\n",
+		exitWithError(ExitFailure, "Failed to download version %v: %v\n",
 			versionText, err)
-		os.Exit(1)
 	}
-	myBinaryPath, err := os.Executable()
+	myBinaryPath, err := getCLIBinaryPath()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Could not determine path to bopmatic CLI: %v\n",
-			err)
-		os.Exit(1)
-	}
-	myBinaryPath, err = filepath.EvalSymlinks(myBinaryPath)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Could not determine path to bopmatic CLI: %v\n",
-			err)
-		os.Exit(1)
+		exitWithError(ExitFailure, "%v\n", err)
 	}
 
-	myBinaryPathBak := myBinaryPath + ".bak"
-	err = os.Rename(myBinaryPath, myBinaryPathBak)
+	// keep the replaced binary so that 'bopmatic upgrade --rollback' can
+	// restore it; only the single most recent previous version is kept
+	myBinaryPathPrev := myBinaryPath + PrevBinarySuffix
+	_ = os.Remove(myBinaryPathPrev)
+	err = os.Rename(myBinaryPath, myBinaryPathPrev)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Could not replace existing %v; do you need to be root?: %v\n",
+		exitWithError(ExitFailure, "Could not replace existing %v; do you need to be root?: %v\n",
 			myBinaryPath, err)
-		os.Exit(1)
 	}
 	err = os.Rename(tmpFile.Name(), myBinaryPath)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Could not replace existing %v; do you need to be root?: %v\n",
+		_ = os.Rename(myBinaryPathPrev, myBinaryPath)
+		exitWithError(ExitFailure, "Could not replace existing %v; do you need to be root?: %v\n",
 			myBinaryPath, err)
-		_ = os.Rename(myBinaryPathBak, myBinaryPath)
-		os.Exit(1)
 	}
-	_ = os.Remove(myBinaryPathBak)
 
 	fmt.Printf("Upgrade %v to %v complete\n", myBinaryPath, latestVer)
+	fmt.Printf("The previous version was kept as %v; 'bopmatic upgrade --rollback' restores it\n",
+		myBinaryPathPrev)
+}
+
+// suffix of the CLI binary replaced by the most recent upgrade
+const PrevBinarySuffix = ".prev"
+
+// getCLIBinaryPath returns the resolved path of the running CLI binary
+func getCLIBinaryPath() (string, error) {
+	binaryPath, err := os.Executable()
+	if err == nil {
+		binaryPath, err = filepath.EvalSymlinks(binaryPath)
+	}
+	if err != nil {
+		return "", fmt.Errorf("Could not determine path to bopmatic CLI: %w",
+			err)
+	}
+
+	return binaryPath, nil
+}
+
+// rollbackCLI swaps the CLI binary with the one kept by the most recent
+// upgrade; the replaced binary is kept in turn so that the rollback can
+// itself be undone
+func rollbackCLI() {
+	if isBrewVersion() {
+		exitWithError(ExitFailure, "This CLI was installed via brew; please use brew to install a previous version\n")
+	}
+	myBinaryPath, err := getCLIBinaryPath()
+	if err != nil {
+		exitWithError(ExitFailure, "%v\n", err)
+	}
+	myBinaryPathPrev := myBinaryPath + PrevBinarySuffix
+	_, err = os.Stat(myBinaryPathPrev)
+	if errors.Is(err, fs.ErrNotExist) {
+		exitWithError(ExitNotFound, "No previous version to roll back to; %v does not exist\n",
+			myBinaryPathPrev)
+	} else if err != nil {
+		exitWithError(ExitFailure, "%v\n", err)
+	}
+
+	myBinaryPathTmp := myBinaryPath + ".rollback"
+	err = os.Rename(myBinaryPath, myBinaryPathTmp)
+	if err != nil {
+		exitWithError(ExitFailure, "Could not replace existing %v; do you need to be root?: %v\n",
+			myBinaryPath, err)
+	}
+	err = os.Rename(myBinaryPathPrev, myBinaryPath)
+	if err != nil {
+		_ = os.Rename(myBinaryPathTmp, myBinaryPath)
+		exitWithError(ExitFailure, "Could not restore %v; do you need to be root?: %v\n",
+			myBinaryPathPrev, err)
+	}
+	err = os.Rename(myBinaryPathTmp, myBinaryPathPrev)
+	if err != nil {
+		logWarn("Could not keep %v as %v: %v", versionText, myBinaryPathPrev,
+			err)
+		_ = os.Remove(myBinaryPathTmp)
+	}
+
+	restoredVer := "an unknown version"
+	cmd := exec.Command(myBinaryPath, "version")
+	cmd.Env = append(os.Environ(), NoUpgradeCheckEnvVar+"=1")
+	verOut, err := cmd.Output()
+	if err == nil {
+		restoredVer = strings.TrimSpace(string(verOut))
+	} else {
+		logDebug("could not determine restored version: %v", err)
+	}
+	fmt.Printf("Rolled %v back from bopmatic-cli-%v to %v\n", myBinaryPath,
+		versionText, restoredVer)
+}
+
+const (
+	// attempts made to pull the build image before giving up; docker keeps
+	// completed layers so each retry resumes rather than restarts
+	imagePullMaxAttempts = 5
+	imagePullRetryDelay  = 5 * time.Second
+)
+
+// messages of image pull failures which retrying won't fix
+var fatalImagePullErrs = []string{
+	"no space left on device",
+	"unauthorized",
+	"denied",
+	"manifest unknown",
+	"not found",
 }
 
-func pullBopmaticImage() {
+func isRetryableImagePullErr(err error) bool {
+	if errdefs.IsUnauthorized(err) || errdefs.IsForbidden(err) ||
+		errdefs.IsNotFound(err) || errdefs.IsInvalidParameter(err) {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	for _, fatalMsg := range fatalImagePullErrs {
+		if strings.Contains(msg, fatalMsg) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func pullBopmaticImage(tag string) {
+	imageName := util.BopmaticImageRepo + ":" + tag
+
 	cli, err := dockerClient.NewClientWithOpts(dockerClient.FromEnv,
 
 		dockerClient.WithAPIVersionNegotiation())
 	if err != nil {
 		err := fmt.Errorf(util.DockerInstallErrMsg, err)
-		fmt.Fprintf(os.Stderr, "%v\n", err)
-		os.Exit(1)
+		exitWithError(ExitFailure, "%v\n", err)
 	}
+	defer cli.Close()
+
+	for attempt := 1; ; attempt++ {
+		err = pullImage(cli, imageName)
+		if err == nil {
+			break
+		}
+		if !isRetryableImagePullErr(err) || attempt == imagePullMaxAttempts {
+			exitWithError(ExitFailure, "Failed to pull image: %v\n", err)
+		}
 
-	reader, err := cli.ImagePull(context.Background(),
-		util.BopmaticBuildImageName, image.PullOptions{})
+		retryDelay := imagePullRetryDelay * time.Duration(attempt)
+		logWarn("Pull of %v interrupted (%v); resuming in %v (attempt %v of %v)",
+			imageName, err, retryDelay, attempt+1, imagePullMaxAttempts)
+		time.Sleep(retryDelay)
+	}
+
+	fmt.Printf("Successfully pulled %v\n", imageName)
+}
+
+// pullImage makes a single attempt at pulling imageName, reporting progress
+// as it goes
+func pullImage(cli *dockerClient.Client, imageName string) error {
+	reader, err := cli.ImagePull(context.Background(), imageName,
+		image.PullOptions{})
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to pull image: %v", err)
-		os.Exit(1)
+		return err
 	}
 
 	defer reader.Close()
@@ -255,15 +702,20 @@ func pullBopmaticImage() {
 		Status string         `json:"status"`
 		Id     string         `json:"id"`
 		Detail ProgressDetail `json:"progressDetail"`
+		// set instead of Status when the pull fails part way through
+		Error string `json:"error"`
 	}
 
-	var dockerStatus DockerStatus
 	progressScanner := bufio.NewScanner(reader)
 	for progressScanner.Scan() {
+		var dockerStatus DockerStatus
 		err = json.Unmarshal(progressScanner.Bytes(), &dockerStatus)
 		if err != nil {
 			continue
 		}
+		if dockerStatus.Error != "" {
+			return errors.New(dockerStatus.Error)
+		}
 
 		var progressPct uint64
 		progressPct = 100
@@ -276,13 +728,7 @@ func pullBopmaticImage() {
 			dockerStatus.Id, progressPct)
 	}
 
-	err = progressScanner.Err()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to pull image: %v", err)
-		os.Exit(1)
-	}
-
-	fmt.Printf("Successfully pulled %v\n", util.BopmaticBuildImageName)
+	return progressScanner.Err()
 }
 
 //go:embed version.txt
@@ -290,10 +736,6 @@ var versionText string
 
 const DevVersionText = "v0.devbuild"
 
-func versionMain(args []string) {
-	fmt.Printf("bopmatic-cli-%v\n", versionText)
-}
-
 func isBrewVersion() bool {
 	if versionText[len(versionText)-1] == BrewVersionSuffix[0] {
 		return true
@@ -302,6 +744,42 @@ func isBrewVersion() bool {
 	return false
 }
 
+const NoUpgradeCheckEnvVar = "BOPMATIC_NO_UPGRADE_CHECK"
+
+// set via --no-upgrade-check
+var noUpgradeCheck = false
+
+type noUpgradeCheckFlag struct{}
+
+func (n *noUpgradeCheckFlag) String() string {
+	return strconv.FormatBool(noUpgradeCheck)
+}
+
+func (n *noUpgradeCheckFlag) Set(val string) error {
+	v, err := strconv.ParseBool(val)
+	if err != nil {
+		return err
+	}
+	noUpgradeCheck = v
+
+	return nil
+}
+
+func (n *noUpgradeCheckFlag) IsBoolFlag() bool {
+	return true
+}
+
+// skipUpgradeCheck reports whether the startup upgrade checks, which cost a
+// network round trip and docker introspection, should be skipped
+func skipUpgradeCheck() bool {
+	if noUpgradeCheck {
+		return true
+	}
+	skip, err := strconv.ParseBool(os.Getenv(NoUpgradeCheckEnvVar))
+
+	return err == nil && skip
+}
+
 func checkAndPrintUpgradeCLIWarning() bool {
 	if versionText == DevVersionText {
 		return false
@@ -314,25 +792,36 @@ func checkAndPrintUpgradeCLIWarning() bool {
 		return false
 	}
 
-	fmt.Fprintf(os.Stderr, "*WARN*: A new version of the Bopmatic CLI is available (%v). Please upgrade via 'bopmatic upgrade'.\n",
+	logWarn("A new version of the Bopmatic CLI is available (%v). Please upgrade via 'bopmatic upgrade'.",
 		latestVer)
 
 	return true
 }
 
 func checkAndPrintUpgradeContainerWarning() bool {
-	haveBuildImg, err := util.HasBopmaticBuildImage()
+	haveBuildImg, err := hasBuildImage()
 	if err != nil || !haveBuildImg {
 		return false
 	}
 
+	tag := getBuildImageTag()
+	if tag != util.BopmaticImageTag {
+		if !isPinnedBuildImageStale(tag) {
+			return false
+		}
+
+		logWarn("The Bopmatic Build container is pinned to %v which is older than %v. Unpin via 'bopmatic upgrade container --tag %v'.",
+			tag, util.BopmaticImageTag, util.BopmaticImageTag)
+		return true
+	}
+
 	needUpgrade, err := util.DoesLocalImageNeedUpdate(util.BopmaticImageRepo,
-		util.BopmaticImageTag)
+		tag)
 	if err != nil || needUpgrade == false {
 		return false
 	}
 
-	fmt.Fprintf(os.Stderr, "*WARN*: A new version of the Bopmatic Build container is available. Please upgrade via 'bopmatic upgrade'.\n")
+	logWarn("A new version of the Bopmatic Build container is available. Please upgrade via 'bopmatic upgrade'.")
 
 	return true
 }
@@ -0,0 +1,142 @@
+/* Copyright © 2024 Bopmatic, LLC. All Rights Reserved.
+ *
+ * See LICENSE file at the root of this package for license terms
+ */
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	bopsdk "github.com/bopmatic/sdk/golang"
+)
+
+const (
+	watchPollInterval = 500 * time.Millisecond
+	watchDebounce     = time.Second
+)
+
+// directories which never trigger a rebuild; DefaultArtifactDir holds the
+// package tarballs produced by the build itself
+var watchExcludedDirs = map[string]bool{
+	".git":                    true,
+	bopsdk.DefaultArtifactDir: true,
+}
+
+type fileSnapshot map[string]time.Time
+
+func snapshotProjectFiles(root string) (fileSnapshot, error) {
+	snapshot := make(fileSnapshot)
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry,
+		err error) error {
+
+		if err != nil {
+			// files may disappear mid-walk while an editor saves
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			if path != root && watchExcludedDirs[d.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		snapshot[path] = info.ModTime()
+
+		return nil
+	})
+
+	return snapshot, err
+}
+
+func (snapshot fileSnapshot) equals(other fileSnapshot) bool {
+	if len(snapshot) != len(other) {
+		return false
+	}
+	for path, modTime := range snapshot {
+		otherModTime, ok := other[path]
+		if !ok || !modTime.Equal(otherModTime) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// waitForProjectChange blocks until the files under root differ from
+// baseline and then remain unchanged for watchDebounce so that a burst of
+// saves results in a single rebuild
+func waitForProjectChange(root string, baseline fileSnapshot) error {
+	for {
+		time.Sleep(watchPollInterval)
+		current, err := snapshotProjectFiles(root)
+		if err != nil {
+			return err
+		}
+		if current.equals(baseline) {
+			continue
+		}
+
+		for {
+			time.Sleep(watchDebounce)
+			settled, err := snapshotProjectFiles(root)
+			if err != nil {
+				return err
+			}
+			if settled.equals(current) {
+				return nil
+			}
+			current = settled
+		}
+	}
+}
+
+// watchAndBuild rebuilds the project each time its sources change until
+// interrupted; build failures are reported but do not stop the watch
+func watchAndBuild(projectFilename string, targets []string,
+	verboseContainer bool) {
+	absProjectFilename, err := filepath.Abs(projectFilename)
+	if err != nil {
+		exitWithError(ExitFailure, "%v\n", err)
+	}
+	root := filepath.Dir(absProjectFilename)
+
+	for buildNum := 1; ; buildNum++ {
+		if buildNum > 1 {
+			fmt.Printf("\n========== rebuild #%v (%v) ==========\n\n",
+				buildNum, time.Now().Format(time.TimeOnly))
+		}
+
+		_, err = buildAndPackage(absProjectFilename, targets, verboseContainer)
+		if err != nil {
+			logError("%v", err)
+		}
+
+		// snapshot after building so that files generated by the build
+		// don't immediately trigger another one
+		baseline, err := snapshotProjectFiles(root)
+		if err != nil {
+			exitWithError(ExitFailure, "Failed to watch %v: %v\n", root, err)
+		}
+		fmt.Printf("Watching %v for changes; press Ctrl-C to stop\n", root)
+
+		err = waitForProjectChange(root, baseline)
+		if err != nil {
+			exitWithError(ExitFailure, "Failed to watch %v: %v\n", root, err)
+		}
+	}
+}